@@ -0,0 +1,338 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"sea9.org/go/c9ryptool/pkg/agent"
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/encrypts/sym"
+)
+
+const PWD_INTERACTIVE = "{[INTERACTIVE]}"
+
+const TIMEOUT = 15 * time.Minute // default lifetime of the keys
+
+const CMD_HELP = 0
+const CMD_VERSION = 1
+const CMD_START = 2
+const CMD_ADD = 3
+const CMD_LIST = 4
+const CMD_REMOVE = 5
+const CMD_STOP = 6
+
+var ENVIVARS = []string{
+	"C9_VERBOSE",
+	"C9_ENCRYPTION",
+	agent.SOCKET,
+}
+
+func usage() string {
+	return "Usage:\n c9agent\n" +
+		"  [version | help]\n\n" +
+		"  [start]\n" +
+		"   {-t DUR | --timeout=DUR}\n\n" +
+		"  [add]\n" +
+		"   {-n NAME | --name=NAME}\n" +
+		"   {-a ALGR | --algorithm=ALGR}\n" +
+		"   {-k FILE | --key=FILE}\n" +
+		"   {-p | --password}\n" +
+		"   {--password=PASS}\n" +
+		"   {--salt=LEN}\n" +
+		"   {--encode-key=ENC}\n" +
+		"   {-t DUR | --timeout=DUR}\n\n" +
+		"  [list]\n\n" +
+		"  [remove]\n" +
+		"   {-n NAME | --name=NAME}\n\n" +
+		"  [stop]\n\n" +
+		"  all commands\n" +
+		"   {-s SOCK | --socket=SOCK}\n" +
+		"   {-v | --verbose}"
+}
+
+func help() string {
+	return fmt.Sprintf("Usage: c9agent [commands] {options}\n"+
+		" # misc.\n"+
+		" . version - display current version of 'c9agent'\n"+
+		" . help    - display this message\n\n"+
+		" # agent\n"+
+		" . start   - start the key agent in the foreground, keeping the keys in memory only\n"+
+		"   * options:\n"+
+		"    -t DUR, --timeout=DUR\n"+
+		"       default lifetime of the keys added, e.g. '30m', '0' means never expire, default: %v\n"+
+		" . add     - add a key, or a key-generating password, to the agent\n"+
+		"   * options:\n"+
+		"    -n NAME, --name=NAME\n"+
+		"       name of the key, default: '%v'\n"+
+		"    -a ALGR, --algorithm=ALGR\n"+
+		"       encryption algorithm of the key, default: '%v'\n"+
+		"    -k FILE, --key=FILE\n"+
+		"       path of the file containing the encryption key\n"+
+		"    -p, --password\n"+
+		"       indicate a password, for encryption key generation, is input interactively\n"+
+		"    --password=PASS\n"+
+		"       input the key-generating password via the command line\n"+
+		"    --salt=LEN\n"+
+		"       length of salt to use for generating keys from password, default: %v\n"+
+		"    --encode-key=ENC\n"+
+		"       encoding scheme of the symmetric key file\n"+
+		"    -t DUR, --timeout=DUR\n"+
+		"       lifetime of the key, default: the default lifetime of the agent\n"+
+		" . list    - list the keys kept by the agent\n"+
+		" . remove  - remove a key from the agent\n"+
+		"   * options:\n"+
+		"    -n NAME, --name=NAME\n"+
+		"       name of the key, omitting means removing all keys\n"+
+		" . stop    - stop the agent, wiping all keys\n\n"+
+		" # common options\n"+
+		"    -s SOCK, --socket=SOCK\n"+
+		"       path of the agent socket, default: '%v'\n"+
+		"    -v, --verbose\n"+
+		"       display detail operation messages during processing\n\n"+
+		" # environment variables\n"+
+		"    %v",
+		TIMEOUT,
+		agent.DEFAULT,
+		encrypts.Default(),
+		sym.SALTLEN,
+		agent.SocketPath(),
+		strings.Join(ENVIVARS, "\n    "),
+	)
+}
+
+// parse parse command line arguments to populate a Config object
+func parse(args []string) (cfg *cfgs.Config, err error) {
+	if len(args) < 2 {
+		err = fmt.Errorf("[CONF] Command missing")
+		return
+	}
+
+	cfg = cfgs.New([]string{
+		"help",    // 0
+		"version", // 1
+		"start",   // 2
+		"add",     // 3
+		"list",    // 4
+		"remove",  // 5
+		"stop",    // 6
+	})
+	cfg.Algr = encrypts.Default()
+	cfg.SaltLen = sym.SALTLEN
+	cfg.Timeout = -1
+
+	idx, _, err := cfg.CommandMatch(args[1])
+	if err != nil {
+		err = fmt.Errorf("[CONF] %v", err)
+		return
+	} else if idx < 0 {
+		err = fmt.Errorf("[CONF] Invalid command '%v'", args[1])
+		return
+	}
+
+	for _, enm := range ENVIVARS {
+		env := os.Getenv(enm)
+		if env != "" {
+			switch enm {
+			case "C9_VERBOSE":
+				cfg.Verbose, err = strconv.ParseBool(env)
+				if err != nil {
+					err = fmt.Errorf("[CONF] Invalid verbose value in '%v'", enm)
+					return
+				}
+			case "C9_ENCRYPTION":
+				cfg.Algr = env
+			case agent.SOCKET:
+				cfg.Socket = env
+			}
+		}
+	}
+
+	var val int
+	for i := 2; i < len(args); i++ {
+		switch {
+		case args[i] == "-v" || args[i] == "--verbose":
+			cfg.Verbose = true
+		case args[i] == "-s":
+			i++
+			if i >= len(args) {
+				err = fmt.Errorf("[CONF] Missing socket path argument")
+				return
+			} else {
+				cfg.Socket = args[i]
+			}
+		case strings.HasPrefix(args[i], "--socket="):
+			if len(args[i]) <= 9 {
+				err = fmt.Errorf("[CONF] Missing socket path")
+				return
+			} else {
+				cfg.Socket = args[i][9:]
+			}
+		case args[i] == "-t":
+			i++
+			if i >= len(args) {
+				err = fmt.Errorf("[CONF] Missing timeout argument")
+				return
+			} else if cfg.Timeout, err = parseTimeout(args[i]); err != nil {
+				return
+			}
+		case strings.HasPrefix(args[i], "--timeout="):
+			if len(args[i]) <= 10 {
+				err = fmt.Errorf("[CONF] Missing timeout")
+				return
+			} else if cfg.Timeout, err = parseTimeout(args[i][10:]); err != nil {
+				return
+			}
+		case args[i] == "-n":
+			i++
+			if i >= len(args) {
+				err = fmt.Errorf("[CONF] Missing key name argument")
+				return
+			} else {
+				cfg.Agent = args[i]
+			}
+		case strings.HasPrefix(args[i], "--name="):
+			if len(args[i]) <= 7 {
+				err = fmt.Errorf("[CONF] Missing key name")
+				return
+			} else {
+				cfg.Agent = args[i][7:]
+			}
+		case args[i] == "-a":
+			i++
+			if i >= len(args) {
+				err = fmt.Errorf("[CONF] Missing algorithm argument")
+				return
+			} else {
+				cfg.Algr = args[i]
+			}
+		case strings.HasPrefix(args[i], "--algorithm="):
+			if len(args[i]) <= 12 {
+				err = fmt.Errorf("[CONF] Missing algorithm")
+				return
+			} else {
+				cfg.Algr = args[i][12:]
+			}
+		case args[i] == "-k":
+			i++
+			if i >= len(args) {
+				err = fmt.Errorf("[CONF] Missing key filename argument")
+				return
+			} else {
+				cfg.Key = args[i]
+			}
+		case strings.HasPrefix(args[i], "--key="):
+			if len(args[i]) <= 6 {
+				err = fmt.Errorf("[CONF] Missing key filename")
+				return
+			} else {
+				cfg.Key = args[i][6:]
+			}
+		case args[i] == "-p" || args[i] == "--password":
+			cfg.Passwd = PWD_INTERACTIVE
+		case strings.HasPrefix(args[i], "--password="):
+			if len(args[i]) <= 11 {
+				err = fmt.Errorf("[CONF] Missing password")
+				return
+			} else {
+				cfg.Passwd = args[i][11:]
+			}
+		case strings.HasPrefix(args[i], "--salt="):
+			if len(args[i]) <= 7 {
+				err = fmt.Errorf("[CONF] Missing salt length")
+				return
+			} else {
+				val, err = strconv.Atoi(args[i][7:])
+				if err != nil {
+					err = fmt.Errorf("[CONF] Invalid salt length '%v'", args[i][7:])
+					return
+				}
+				cfg.SaltLen = val
+			}
+		case strings.HasPrefix(args[i], "--encode-key="):
+			if len(args[i]) <= 13 {
+				err = fmt.Errorf("[CONF] Missing key encoding")
+				return
+			} else {
+				cfg.Enck = args[i][13:]
+			}
+		default:
+			err = fmt.Errorf("[CONF] Invalid option '%v'", args[i])
+			return
+		}
+	}
+
+	if cfg.Socket == "" {
+		cfg.Socket = agent.SocketPath()
+	}
+	return
+}
+
+// parseTimeout parse durations such as '30m', '0' means never expire
+func parseTimeout(val string) (dur time.Duration, err error) {
+	if val == "0" {
+		return 0, nil
+	}
+	if dur, err = time.ParseDuration(val); err != nil || dur < 0 {
+		err = fmt.Errorf("[CONF] Invalid timeout '%v'", val)
+	}
+	return
+}
+
+func validate(cfg *cfgs.Config) (err error) {
+	errs := make([]error, 0)
+
+	switch cfg.Cmd() {
+	case CMD_START:
+		if cfg.Agent != "" || cfg.Key != "" || cfg.Passwd != "" || cfg.Enck != "" {
+			errs = append(errs, fmt.Errorf("options '-n', '-k', '-p' and '--encode-key' not applicable to 'start'"))
+		}
+	case CMD_ADD:
+		var typ bool
+		if typ, err = encrypts.Validate(cfg.Algr, 0); err != nil {
+			errs = append(errs, err)
+		}
+		if cfg.Key != "" && cfg.Passwd != "" {
+			errs = append(errs, fmt.Errorf("incompatable options '-k' and '-p'"))
+		} else if cfg.Key == "" && cfg.Passwd == "" {
+			errs = append(errs, fmt.Errorf("encryption key missing"))
+		} else if cfg.Key != "" {
+			if _, err = os.Stat(cfg.Key); errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("key file '%v' does not exist", cfg.Key))
+			} else if err != nil {
+				err = fmt.Errorf("[VLDT] %v", err)
+				return
+			}
+		} else if !typ {
+			errs = append(errs, fmt.Errorf("cannot generate keys from password for asymmetric algorithms"))
+		}
+		if cfg.Enck != "" {
+			if err = encodes.Validate(cfg.Enck); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	default:
+		if cfg.Key != "" || cfg.Passwd != "" || cfg.Enck != "" || cfg.Timeout >= 0 {
+			errs = append(errs, fmt.Errorf("options '-k', '-p', '--encode-key' and '-t' only applicable to 'add' and 'start'"))
+		}
+		if cfg.Agent != "" && cfg.Cmd() != CMD_REMOVE {
+			errs = append(errs, fmt.Errorf("option '-n' only applicable to 'add' and 'remove'"))
+		}
+	}
+
+	err = nil
+	if len(errs) > 0 {
+		var buf strings.Builder
+		fmt.Fprintf(&buf, "[\n - %v", errs[0])
+		for _, err := range errs[1:] {
+			fmt.Fprintf(&buf, "\n - %v", err)
+		}
+		err = fmt.Errorf("[VLDT]%v\n]", buf.String())
+	}
+	return
+}
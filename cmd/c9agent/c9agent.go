@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"sea9.org/go/c9ryptool/pkg/agent"
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+const LOG_FRM_MILLI = "2006-01-02T15:04:05.000"
+
+func desc() string {
+	return fmt.Sprintf("c9agent (version %v)", cfgs.Version())
+}
+
+func main() {
+	cfg, err := parse(os.Args)
+	if err != nil {
+		log.Fatalf("[MAIN]%v\n%v\n%v\n", err, desc(), usage())
+	}
+
+	switch cfg.Cmd() {
+	case CMD_HELP:
+		fmt.Printf("%v\n%v\n", desc(), help())
+		return
+	case CMD_VERSION:
+		fmt.Println(desc())
+		return
+	}
+
+	err = validate(cfg)
+	if err != nil {
+		log.Fatalf("[MAIN]%v", err)
+	}
+
+	clnt := agent.NewClient(cfg.Socket)
+	switch cfg.Cmd() {
+	case CMD_START:
+		err = start(cfg)
+	case CMD_ADD:
+		err = add(cfg, clnt)
+	case CMD_LIST:
+		var keys []agent.KeyInfo
+		if keys, err = clnt.List(); err == nil {
+			for i, k := range keys {
+				exp, pwd := "never expire", ""
+				if !k.Expires.IsZero() {
+					exp = fmt.Sprintf("expires in %v", time.Until(k.Expires).Round(time.Second))
+				}
+				if k.Password {
+					pwd = " (password)"
+				}
+				fmt.Printf(" %2v %v %v%v, %v\n", i+1, k.Name, k.Algorithm, pwd, exp)
+			}
+		}
+	case CMD_REMOVE:
+		err = clnt.Remove(cfg.Agent)
+	case CMD_STOP:
+		err = clnt.Stop()
+	}
+	if err != nil {
+		log.Fatalf("[MAIN]%v", err)
+	}
+}
+
+// start run the agent in the foreground until stopped or interrupted
+func start(cfg *cfgs.Config) (err error) {
+	timeout := cfg.Timeout
+	if timeout < 0 {
+		timeout = TIMEOUT
+	}
+	lsnr, err := agent.Listen(cfg.Socket)
+	if err != nil {
+		return
+	}
+	defer os.Remove(cfg.Socket)
+
+	agt := agent.New(timeout)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		agt.Stop()
+	}()
+
+	fmt.Printf("%v=%v; export %v;\n", agent.SOCKET, cfg.Socket, agent.SOCKET)
+	if cfg.Verbose {
+		fmt.Printf("%v [%v] started, default key lifetime: %v\n", time.Now().Format(LOG_FRM_MILLI), desc(), timeout)
+	}
+	err = agt.Serve(lsnr)
+	if cfg.Verbose {
+		fmt.Printf("%v [%v] stopped\n", time.Now().Format(LOG_FRM_MILLI), desc())
+	}
+	return
+}
+
+// add add the key, or the key-generating password, to the agent
+func add(cfg *cfgs.Config, clnt *agent.Client) (err error) {
+	var key []byte
+	pwd := cfg.Passwd
+	if cfg.Passwd == PWD_INTERACTIVE {
+		if pwd, err = utils.Prompt("", "Enter password: "); err != nil {
+			return
+		}
+	} else if cfg.Key != "" {
+		algr := encrypts.Get(encrypts.Parse(cfg.Algr))
+		if eck := encodes.Get(encodes.Parse(cfg.Enck)); cfg.Enck != "" && algr.Type() {
+			key, err = utils.Read(cfg.Key, cfg.Buffer, eck)
+		} else {
+			key, err = utils.Read(cfg.Key, cfg.Buffer)
+		}
+		if err != nil {
+			return fmt.Errorf("[KEY]%v", err)
+		}
+	}
+
+	timeout := cfg.Timeout
+	if timeout < 0 {
+		timeout = 0 // use the agent default
+	} else if timeout == 0 {
+		timeout = -1 // never expire
+	}
+	err = clnt.Add(cfg.Agent, encrypts.Parse(cfg.Algr), key, pwd, cfg.SaltLen, timeout)
+	if err == nil && cfg.Verbose {
+		fmt.Printf("%v added key '%v' to the agent\n", desc(), cfg.Agent)
+	}
+	return
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+
+	"sea9.org/go/c9ryptool/pkg/archives"
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+// compressor get the compressor of the given name, using the given compression level if specified
+func compressor(name string, level int) (cmp encodes.Compressor, err error) {
+	name, _ = encodes.ParseCompressor(name)
+	cmp = encodes.GetCompressor(name)
+	if cmp == nil {
+		err = fmt.Errorf(" unsupported compression algorithm '%v'", name)
+		return
+	}
+	if level != cfgs.LEVEL_DEFAULT {
+		cmp, err = cmp.WithLevel(level)
+	}
+	return
+}
+
+// listCompressors list the supported compression algorithms and their capabilities
+func listCompressors() {
+	fmt.Println(desc())
+	for i, n := range encodes.ListCompressors() {
+		c := encodes.GetCompressor(n)
+		cap := "decompress"
+		if c.CanCompress() {
+			cap = "compress/decompress"
+		}
+		if min, max := c.Levels(); min != max {
+			fmt.Printf(" %2v %-5v %-19v level %v to %v (default %v)\n", i+1, n, cap, min, max, c.Level())
+		} else {
+			fmt.Printf(" %2v %-5v %v\n", i+1, n, cap)
+		}
+	}
+}
+
+func archive(
+	cfg *cfgs.Config,
+	cmp encodes.Compressor,
+) (err error) {
+	inp := utils.Stdin()
+	if cfg.Input != "" {
+		var f *os.File
+		f, err = os.Open(cfg.Input)
+		if err != nil {
+			err = fmt.Errorf("[READ] %v", err)
+			return
+		}
+		defer f.Close()
+		inp = f
+	}
+	rdr := bufio.NewReaderSize(inp, cfg.Buffer)
+	if rdr.Size() != cfg.Buffer {
+		if cfg.Verbose {
+			fmt.Printf("Read buffer size %v mismatching with the specified size %v, changing buffer size...\n", rdr.Size(), cfg.Buffer)
+		}
+		cfg.Buffer = rdr.Size()
+		rdr = bufio.NewReaderSize(inp, cfg.Buffer)
+	}
+
+	wtr := bufio.NewWriter(os.Stdout)
+	if cfg.Output != "" {
+		var out *os.File
+		out, err = os.Create(cfg.Output)
+		if err != nil {
+			err = fmt.Errorf("[WRITE] %v", err)
+			return
+		}
+		wtr = bufio.NewWriter(out)
+		defer out.Close()
+	}
+
+	if cfg.Extract {
+		err = cmp.Decompress(rdr, wtr)
+		if err != nil {
+			err = fmt.Errorf("[DECOMPRESS] %v", err)
+			return
+		}
+	} else {
+		err = cmp.Compress(rdr, wtr)
+		if err != nil {
+			err = fmt.Errorf("[COMPRESS] %v", err)
+			return
+		}
+	}
+	err = wtr.Flush()
+	return
+}
+
+// listArchivers list the supported archive formats
+func listArchivers() {
+	fmt.Println(" archive formats:")
+	for i, n := range archives.List() {
+		fmt.Printf(" %2v %v\n", i+1, n)
+	}
+}
+
+// pack archive the input directory, then compress and encrypt the archive if 'cmp' and 'alg' are given respectively
+func pack(
+	cfg *cfgs.Config,
+	arc archives.Archiver,
+	cmp encodes.Compressor,
+	alg encrypts.Algorithm,
+	eck encodes.Encoding,
+) (err error) {
+	var buf bytes.Buffer
+	var salt []byte
+
+	err = arc.Pack(cfg.Input, &buf)
+	if err != nil {
+		err = fmt.Errorf("[PACK]%v", err)
+		return
+	}
+	result := buf.Bytes()
+
+	if cmp != nil {
+		result, err = encodes.Compress(cmp, result)
+		if err != nil {
+			err = fmt.Errorf("[PACK][ZIP]%v", err)
+			return
+		}
+	}
+
+	if alg != nil {
+		var rst encrypts.EncryptResult
+		salt, err = populateKey(cfg, alg, eck, nil, false)
+		if err != nil {
+			err = fmt.Errorf("[PACK]%v", err)
+			return
+		}
+		rst, err = encrypts.V2(alg).Encrypt(encrypts.EncryptRequest{Plaintext: result})
+		if err != nil {
+			err = fmt.Errorf("[PACK][ECY]%v", err)
+			return
+		}
+		result = rst.Output
+		if salt != nil {
+			result = append(result, salt...)
+		}
+	}
+
+	err = utils.Write(cfg.Output, result)
+	if err != nil {
+		err = fmt.Errorf("[PACK][OUT]%v", err)
+	}
+	return
+}
+
+// unpack decrypt and decompress the input if 'alg' and 'cmp' are given respectively, then extract the archive
+// to the output directory
+func unpack(
+	cfg *cfgs.Config,
+	arc archives.Archiver,
+	cmp encodes.Compressor,
+	alg encrypts.Algorithm,
+	eck encodes.Encoding,
+) (err error) {
+	var input, salt []byte
+
+	input, err = utils.Read(cfg.Input, cfg.Buffer)
+	if err != nil {
+		err = fmt.Errorf("[UNPACK][INP]%v", err)
+		return
+	}
+
+	if alg != nil {
+		var rst encrypts.DecryptResult
+		salt, err = populateKey(cfg, alg, eck, input, true)
+		if err != nil {
+			err = fmt.Errorf("[UNPACK]%v", err)
+			return
+		}
+		rst, err = encrypts.V2(alg).Decrypt(encrypts.DecryptRequest{Ciphertext: input[:len(input)-len(salt)]})
+		if err != nil {
+			err = fmt.Errorf("[UNPACK][DCY]%v", err)
+			return
+		}
+		input = rst.Plaintext
+	}
+
+	if cmp != nil {
+		input, err = encodes.Decompress(cmp, input)
+		if err != nil {
+			err = fmt.Errorf("[UNPACK][ZIP]%v", err)
+			return
+		}
+	}
+
+	err = arc.Unpack(bytes.NewReader(input), cfg.Output)
+	if err != nil {
+		err = fmt.Errorf("[UNPACK]%v", err)
+	}
+	return
+}
@@ -4,19 +4,34 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 
+	"sea9.org/go/c9ryptool/pkg/agent"
+	"sea9.org/go/c9ryptool/pkg/archives"
 	"sea9.org/go/c9ryptool/pkg/cfgs"
 	"sea9.org/go/c9ryptool/pkg/encodes"
 	"sea9.org/go/c9ryptool/pkg/encrypts"
 	"sea9.org/go/c9ryptool/pkg/encrypts/sym"
 	"sea9.org/go/c9ryptool/pkg/hashes"
+	"sea9.org/go/c9ryptool/pkg/keystore"
+	"sea9.org/go/c9ryptool/pkg/kms"
+	"sea9.org/go/c9ryptool/pkg/server"
+	"sea9.org/go/c9ryptool/pkg/utils"
 )
 
 const FORMAT_NONE = "none"
 const FORMAT_YAML = "yaml"
 const FORMAT_JSON = "json"
+const FORMAT_DOTENV = "dotenv"
+const FORMAT_INI = "ini"
+const FORMAT_PROPERTIES = "properties"
+const FORMAT_TOML = "toml"
+const FORMAT_K8S_SECRET = "k8s-secret"
+const FORMAT_CSV = "csv"
+const FORMAT_JSONL = "jsonl"
 const PWD_INTERACTIVE = "{[INTERACTIVE]}"
 
 const CMD_HELP = 0
@@ -28,6 +43,11 @@ const CMD_DECODE = 5
 const CMD_HASHING = 6
 const CMD_DISPLAY = 7
 const CMD_ARCHIVE = 8
+const CMD_SERVE = 9
+const CMD_REKEY = 10
+const CMD_EXEC = 11
+
+const LISTEN = "127.0.0.1:8089" // default address of the 'serve' command
 
 var ENVIVARS = []string{
 	"C9_BUFFER",
@@ -44,7 +64,11 @@ func usage() string {
 		"  [encrypt | decrypt]\n" +
 		"   {-l | --list}\n" +
 		"   {-a ALGR | --algorithm=ALGR}\n" +
-		"   {-k FILE | --key=FILE}\n" +
+		"   {-k FILE | --key=FILE | -k @NAME}\n" +
+		"   {--keystore=FILE}\n" +
+		"   {--identity=FILE}\n" +
+		"   {--kms=URI}\n" +
+		"   {--agent | --agent=NAME}\n" +
 		"   {-g | --generate}\n" +
 		"   {-p | --password}\n" +
 		"   {--password=PASS}\n" +
@@ -62,12 +86,84 @@ func usage() string {
 		"   {--encode-aad=ENC}\n" +
 		"   {--encode-out=ENC}\n" +
 		"   {--encode-key=ENC}\n" +
-		"   {-z ALGR | --compress=ALGR}\n\n" +
-		"  [encode | decode | archive]\n" +
+		"   {-z ALGR | --compress=ALGR}\n" +
+		"   {--level=LVL}\n" +
+		"   {--in-place}\n" +
+		"   {--backup}\n" +
+		"   {--include=GLOB}\n" +
+		"   {--exclude=GLOB}\n" +
+		"   {--workers=NUM}\n" +
+		"   {--path=EXPR}\n" +
+		"   {--encrypted-regex=RE}\n" +
+		"   {--columns=COLS}\n" +
+		"   {--header|--no-header}\n" +
+		"   {--sops}\n\n" +
+		"  [rekey]\n" +
+		"   {-a ALGR | --algorithm=ALGR}\n" +
+		"   {-k FILE | --key=FILE | -k @NAME}\n" +
+		"   {-p | --password}\n" +
+		"   {--password=PASS}\n" +
+		"   {--new-algorithm=ALGR}\n" +
+		"   {--new-key=FILE | --new-key=@NAME}\n" +
+		"   {-g | --generate}\n" +
+		"   {--new-password}\n" +
+		"   {--new-password=PASS}\n" +
+		"   {--keystore=FILE}\n" +
+		"   {--identity=FILE}\n" +
+		"   {--salt=LEN}\n" +
+		"   {-f FORMAT | --format=FORMAT}\n" +
+		"   {-i FILE | --in=FILE}\n" +
+		"   {-o FILE | --out=FILE}\n" +
+		"   {-n ENC | --encoding=ENC}\n" +
+		"   {--encode-in=ENC}\n" +
+		"   {--encode-out=ENC}\n" +
+		"   {--encode-key=ENC}\n" +
+		"   {--in-place}\n" +
+		"   {--backup}\n" +
+		"   {--include=GLOB}\n" +
+		"   {--exclude=GLOB}\n" +
+		"   {--workers=NUM}\n" +
+		"   {--path=EXPR}\n" +
+		"   {--encrypted-regex=RE}\n\n" +
+		"  [exec]\n" +
+		"   {-a ALGR | --algorithm=ALGR}\n" +
+		"   {-k FILE | --key=FILE | -k @NAME}\n" +
+		"   {-p | --password}\n" +
+		"   {--password=PASS}\n" +
+		"   {--keystore=FILE}\n" +
+		"   {--identity=FILE}\n" +
+		"   {--salt=LEN}\n" +
+		"   {-f FORMAT | --format=FORMAT}\n" +
+		"   [-i FILE | --in=FILE]\n" +
+		"   {-n ENC | --encoding=ENC}\n" +
+		"   {--iv=IV}\n" +
+		"   {--tag=TAG}\n" +
+		"   {--aad=AAD}\n" +
+		"   {--prefix=PREFIX}\n" +
+		"   {--separator=SEP}\n" +
+		"   {--path=EXPR}\n" +
+		"   {--encrypted-regex=RE}\n" +
+		"   [-- COMMAND {ARGS}]\n\n" +
+		"  [encode | decode]\n" +
 		"   {-l | --list}\n" +
 		"   {-i FILE | --in=FILE}\n" +
 		"   {-o FILE | --out=FILE}\n" +
 		"   {-n ENC | --encoding=ENC}\n\n" +
+		"  [archive]\n" +
+		"   {-l | --list}\n" +
+		"   {-f FORMAT | --format=FORMAT}\n" +
+		"   {-i FILE | --in=FILE}\n" +
+		"   {-o FILE | --out=FILE}\n" +
+		"   {-n ALGR | --encoding=ALGR}\n" +
+		"   {-x | --extract}\n" +
+		"   {--level=LVL}\n" +
+		"   {-a ALGR | --algorithm=ALGR}\n" +
+		"   {-k FILE | --key=FILE}\n" +
+		"   {-g | --generate}\n" +
+		"   {-p | --password}\n" +
+		"   {--password=PASS}\n" +
+		"   {--salt=LEN}\n" +
+		"   {--encode-key=ENC}\n\n" +
 		"  [hash]\n" +
 		"   {-l | --list}\n" +
 		"   {-i FILE | --in=FILE}\n" +
@@ -76,8 +172,17 @@ func usage() string {
 		"  [display]\n" +
 		"   {-i FILE | --in=FILE}\n" +
 		"   {-n ENC | --encoding=ENC}\n\n" +
+		"  [serve]\n" +
+		"   {--listen=ADDR}\n" +
+		"   {--keystore=FILE}\n" +
+		"   {--tls-cert=FILE}\n" +
+		"   {--tls-key=FILE}\n" +
+		"   {--client-ca=FILE}\n" +
+		"   {--max-size=SIZE}\n" +
+		"   {--identity=FILE}\n\n" +
 		"  all commands\n" +
 		"   {-b SIZE | --buffer=SIZE}\n" +
+		"   {--interactive | --no-sentinel}\n" +
 		"   {-v | --verbose}"
 }
 
@@ -94,8 +199,21 @@ func help() string {
 		"       list the supported algorithms or encoding schemes\n"+
 		"    -a ALGR, --algorithm=ALGR\n"+
 		"       encryption algorithm to use, default: '%v'\n"+
-		"    -k FILE, --key=FILE\n"+
-		"       path of the file containing the encryption key\n"+
+		"    -k FILE, --key=FILE, -k @NAME\n"+
+		"       path of the file containing the encryption key, or '@NAME' to use the key NAME in the keystore,\n"+
+		"       the algorithm is that of the key in the keystore\n"+
+		"    --keystore=FILE\n"+
+		"       path of the keystore, default: env var '%v', or '%v'\n"+
+		"    --identity=FILE\n"+
+		"       private key of the recipient unlocking the keystore, otherwise the master password is read\n"+
+		"       from env var '%v', or input interactively\n"+
+		"    --kms=URI\n"+
+		"       key management service wrapping a new data key, which is stored with the ciphertext:\n"+
+		"        1. 'file:///path/to/kek' - key encryption key in a local file\n"+
+		"        2. 'vault+https://host:port/mount/key' - Vault transit engine, token from env var '%v'\n"+
+		"    --agent, --agent=NAME\n"+
+		"       use the key of the given name, default '%v', kept by 'c9agent' listening on the socket\n"+
+		"       in env var '%v'; the algorithm and salt length are those of the key in the agent\n"+
 		"    -g, --generate\n"+
 		"       generate a new encrytpion key\n"+
 		"    -p, --password\n"+
@@ -108,9 +226,30 @@ func help() string {
 		"       format of the input file, default is none:\n"+
 		"        1. 'none' - no format, the entire input is treated as a stream of bytes\n"+
 		"        2. 'yaml' - encrypt/decrypt field values in the given YAML file while preserving the file structure\n"+
-		"        3. 'json' - to be added\n"+
+		"           and the value types, encrypted values are written as 'ENC[ALGR,data:...,type:TYPE]'; comments,\n"+
+		"           anchors, multiple documents and top level lists are kept; the values are bound to their key\n"+
+		"           paths, and a MAC of the document is added, so swapped, removed or copied values are detected\n"+
+		"        3. 'json' - same as 'yaml' for the given JSON file, keeping the key order, written indented by tabs;\n"+
+		"           the top level must be an object\n"+
+		"        4. 'dotenv', 'ini', 'properties' - encrypt/decrypt the values in the given '.env', INI or Java\n"+
+		"           '.properties' file, keeping the key order, comments, quoting and escaping; the key paths are\n"+
+		"           the keys, or the sections and the keys of INI files\n"+
+		"        5. 'toml' - encrypt/decrypt the leaf values in the given TOML file, keeping the tables, arrays of\n"+
+		"           tables, key order and comments; encrypted values are quoted 'ENC[ALGR,data:...,type:TYPE]'\n"+
+		"           restored to the original type; the items of arrays are keyed by their indices\n"+
+		"        6. 'k8s-secret' - encryption turns a Kubernetes Secret manifest into a git-safe document, i.e.\n"+
+		"           the base64 'data' and 'stringData' decoded into plain 'data' values encrypted as 'yaml' values\n"+
+		"           (only the values under 'data' by default); decryption outputs a 'v1/Secret' manifest with the\n"+
+		"           values encoded in 'base64', other documents are flattened into the 'data' keys of a Secret\n"+
+		"           named after the input file\n"+
+		"        7. 'csv' - encrypt/decrypt the cells of the columns given by '--columns' in the given CSV file,\n"+
+		"           streamed row by row; each cell is bound to its column index, so rows may be sorted or filtered\n"+
+		"        8. 'jsonl' - encrypt/decrypt the values in the given JSON Lines (NDJSON) file, one JSON object per\n"+
+		"           line streamed record by record, keeping the key order and formatting; encrypted values are\n"+
+		"           quoted 'ENC[ALGR,data:...,type:TYPE]' restored to the original type; errors give the line number\n"+
 		"    -i FILE, --in=FILE\n"+
-		"       path of the input file, omitting means input from stdin\n"+
+		"       path of the input file, omitting means input from stdin; if a directory is given, all files\n"+
+		"       in it are encrypted to (or decrypted from) the output directory, along with a manifest\n"+
 		"    -o FILE, --out=FILE\n"+
 		"       path of the output file, omitting means output to stdout\n"+
 		"    --iv=IV\n"+
@@ -119,6 +258,8 @@ func help() string {
 		"        2. decryption - read from the begining of the ciphertext after any decoding\n"+
 		"    --tag=TAG\n"+
 		"       path of the file containing the message authentication tag\n"+
+		"       '--iv' and '--tag' are not supported by formats 'yaml', 'json', 'dotenv', 'ini', 'properties',\n"+
+		"       'toml', 'k8s-secret', 'csv' and 'jsonl', which generate a nonce for each value\n"+
 		"    --aad=AAD\n"+
 		"       path of the file containing the additional authenticated data\n"+
 		"    -n ENC, --encoding=ENC\n"+
@@ -136,11 +277,86 @@ func help() string {
 		"    --encode-key=ENC\n"+
 		"       encoding scheme of the symmetric key (when option -k / --key is specified)\n"+
 		"    -z ALGR, --compress=ALGR\n"+
-		"       compression algorithm for encryption (compression of input) and decryption (decompression of output)\n\n"+
+		"       compression algorithm for encryption (compression of input) and decryption (decompression of output)\n"+
+		"    --level=LVL\n"+
+		"       compression level to use, default: the default level of the compression algorithm\n"+
+		"    --in-place\n"+
+		"       replace the input file with the output atomically, keeping the file mode\n"+
+		"    --backup\n"+
+		"       keep the original input file as FILE.bak when '--in-place' is specified\n"+
+		"    --include=GLOB, --exclude=GLOB\n"+
+		"       patterns of the files to include/exclude when the input is a directory, can be repeated\n"+
+		"    --workers=NUM\n"+
+		"       number of files to process in parallel when the input is a directory, default: # of CPUs\n"+
+		"    --path=EXPR\n"+
+		"       path expression of the values to encrypt in field-level formats, e.g. '$.db.password', '**.secret*'\n"+
+		"       or '.hosts[0]', can be repeated; prefix with '!' to exclude, e.g. '!$.db.user'; the same selectors\n"+
+		"       must be given for decryption\n"+
+		"    --encrypted-regex=RE\n"+
+		"       regular expression of the key names of the values to encrypt in field-level formats, e.g.\n"+
+		"       '^(password|token)$'; all values are encrypted if neither '--path' nor '--encrypted-regex' is given\n"+
+		"    --columns=COLS\n"+
+		"       comma separated names or 0-based indices of the columns to encrypt in CSV files, e.g.\n"+
+		"       '--columns=email,ssn' or '--columns=2', can be repeated; the cells are bound to the indices of\n"+
+		"       the columns, and the same columns must be given for decryption\n"+
+		"    --header, --no-header\n"+
+		"       the first row of CSV files is the header kept in clear (default), or is data without any header;\n"+
+		"       columns can be given by name with the header only\n"+
+		"    --sops\n"+
+		"       encrypt a YAML or JSON document with a top level map in the SOPS format, readable by 'sops -d' if\n"+
+		"       the data key is wrapped by '--kms' using Vault; values with keys ending with '_unencrypted', or not\n"+
+		"       matching '--encrypted-regex', are kept in clear; SOPS documents are detected and decrypted\n"+
+		"       automatically\n\n"+
+		" # rekey - re-encrypt the output of 'encrypt' using a new key, entirely in memory, the plaintext never\n"+
+		"           touches the disk; field-level formats keep the document structure\n"+
+		"   * options:\n"+
+		"    -a ALGR, -k FILE, -k @NAME, -p, --password=PASS, --salt=LEN\n"+
+		"       the old algorithm, and key or password, same as the 'decrypt' command\n"+
+		"    --new-algorithm=ALGR\n"+
+		"       algorithm of the new key, default: the old algorithm\n"+
+		"    --new-key=FILE, --new-key=@NAME\n"+
+		"       path of the file containing the new key, or '@NAME' to use the active key NAME in the keystore\n"+
+		"    -g, --generate\n"+
+		"       generate the new key, written to the file given by '--new-key'\n"+
+		"    --new-password\n"+
+		"       indicate the password of the new key is input interactively\n"+
+		"    --new-password=PASS\n"+
+		"       input the password of the new key via the command line\n"+
+		"    -f FORMAT, --format=FORMAT\n"+
+		"       format of the input file, 'none', 'yaml' or 'json', default is none; compression is kept as is;\n"+
+		"       the other formats are not supported, use 'decrypt' and 'encrypt' instead\n"+
+		"    -i FILE, --in=FILE\n"+
+		"       path of the input file, omitting means input from stdin; if a directory is given, the files in it\n"+
+		"       are re-encrypted to the output directory, or in-place; all files of a directory encrypted with a\n"+
+		"       manifest are re-encrypted, including the manifest; with '--in-place', the files are replaced only\n"+
+		"       after all of them are re-encrypted\n"+
+		"    -n ENC, --encoding=ENC\n"+
+		"       encoding scheme of both the input and the output, default: none, or '%v' for field-level formats\n"+
+		"    --keystore=FILE, --identity=FILE, -o FILE, --out=FILE, --encode-in=ENC, --encode-out=ENC,\n"+
+		"    --encode-key=ENC, --in-place, --backup, --include=GLOB, --exclude=GLOB, --workers=NUM,\n"+
+		"    --path=EXPR, --encrypted-regex=RE\n"+
+		"       same as the 'encrypt' command\n\n"+
+		" # exec - decrypt the YAML input in memory, and run COMMAND with the values as environment variables,\n"+
+		"          signals are forwarded to COMMAND, and its exit code is returned\n"+
+		"   * options:\n"+
+		"    -f FORMAT, --format=FORMAT\n"+
+		"       format of the input file, only 'yaml' is supported, default: 'yaml'\n"+
+		"    -i FILE, --in=FILE\n"+
+		"       path of the encrypted input file\n"+
+		"    --prefix=PREFIX\n"+
+		"       prefix of the environment variable names, default: none\n"+
+		"    --separator=SEP\n"+
+		"       separator of the nested keys in the environment variable names, default: '%v'; the keys are\n"+
+		"       upper-cased, characters other than letters, digits and '_' are replaced by '_', and the items of\n"+
+		"       lists are keyed by their indices, e.g. 'db: {hosts: [a]}' becomes 'DB_HOSTS_0=a'\n"+
+		"    -- COMMAND {ARGS}\n"+
+		"       the command line of the child process, following all other options\n"+
+		"    -a ALGR, -k FILE, -k @NAME, -p, --password=PASS, --keystore=FILE, --identity=FILE, --salt=LEN,\n"+
+		"    -n ENC, --aad=AAD, --path=EXPR, --encrypted-regex=RE\n"+
+		"       same as the 'decrypt' command\n\n"+
 		" # encoding\n"+
 		" . encode  - convert the given input into the specified encoding\n"+
 		" . decode  - convert the given input back from the specified encoding\n"+
-		" . archive - compress/decompress the given input base on the selected compression algorithm\n"+
 		"   * options:\n"+
 		"    -l, --list\n"+
 		"       list the supported algorithms or encoding schemes\n"+
@@ -149,7 +365,28 @@ func help() string {
 		"    -o FILE, --out=FILE\n"+
 		"       path of the output file, omitting means output to stdout\n"+
 		"    -n ENC, --encoding=ENC\n"+
-		"       encoding scheme / compression algorithm to use; encoding default: '%v'\n\n"+
+		"       encoding scheme to use, default: '%v'\n\n"+
+		" # archive - compress/decompress the given input base on the selected compression algorithm,\n"+
+		"             or pack/unpack a directory into/from an archive file\n"+
+		"   * options:\n"+
+		"    -l, --list\n"+
+		"       list the supported compression algorithms, their capabilities, and the archive formats\n"+
+		"    -f FORMAT, --format=FORMAT\n"+
+		"       archive format ('tar' or 'zip'), default: 'tar' if the input is a directory\n"+
+		"    -i FILE, --in=FILE\n"+
+		"       path of the input file (or directory to archive), omitting means input from stdin\n"+
+		"    -o FILE, --out=FILE\n"+
+		"       path of the output file (or directory to extract to), omitting means output to stdout\n"+
+		"    -n ALGR, --encoding=ALGR\n"+
+		"       compression algorithm to use, optional for the 'tar' archive format\n"+
+		"    -x, --extract\n"+
+		"       decompress the given input (or unpack the given archive) instead of compressing it\n"+
+		"    --level=LVL\n"+
+		"       compression level to use, default: the default level of the compression algorithm\n"+
+		"    -a ALGR, --algorithm=ALGR\n"+
+		"       encrypt the archive with the given algorithm, default: '%v' if a key or password is given\n"+
+		"    -k FILE, --key=FILE / -g, --generate / -p, --password / --password=PASS / --salt=LEN / --encode-key=ENC\n"+
+		"       same as the 'encrypt' command, archive encryption only available when packing directories\n\n"+
 		" # hash - hash input using the specified algorithm\n"+
 		"   * options:\n"+
 		"    -l, --list\n"+
@@ -166,20 +403,53 @@ func help() string {
 		"       path of the input file, omitting means input from stdin\n"+
 		"    -n ENC, --encoding=ENC\n"+
 		"       encoding scheme to use, default: do not decode\n\n"+
+		" # serve - serve encrypt, decrypt, hash, encode, sign and mac as JSON/HTTP endpoints:\n"+
+		"             POST /v1/encrypt, /v1/decrypt, /v1/hash, /v1/encode, /v1/decode, /v1/sign, /v1/verify,\n"+
+		"             /v1/mac; GET /healthz\n"+
+		"   * options:\n"+
+		"    --listen=ADDR\n"+
+		"       address to listen on, default: '%v'\n"+
+		"    --keystore=FILE\n"+
+		"       path of the keystore, keys are referenced by their names in the requests, default: env var '%v'\n"+
+		"    --tls-cert=FILE, --tls-key=FILE\n"+
+		"       PEM encoded certificate and private key of the server, serve HTTPS if given\n"+
+		"    --client-ca=FILE\n"+
+		"       PEM encoded CA certificates, require and verify client certificates (mTLS) if given\n"+
+		"    --max-size=SIZE\n"+
+		"       maximum size of a request body in # of bytes, default: %v\n"+
+		"    --identity=FILE\n"+
+		"       same as the 'encrypt' command\n\n"+
 		" # common options:\n"+
 		"    -b SIZE, --buffer=SIZE\n"+
 		"       size of the read buffer in # of bytes, default: %vKB\n"+
+		"    --interactive\n"+
+		"       always end stdin input with a line containing a single period (.), even if stdin is not a terminal\n"+
+		"    --no-sentinel\n"+
+		"       always read stdin until EOF (<ctrl-d>), even if stdin is a terminal\n"+
 		"    -v, --verbose\n"+
 		"       display detail operation messages during processing\n\n"+
 		" NOTE 1: a prompt will appear for typing in the password when password-\n"+
 		"         generated key is used\n\n"+
 		" NOTE 2: type a period (.) then press <enter> in a new line to finish\n"+
-		"         when inputting interactively from stdin",
+		"         when inputting interactively from a terminal, piped input is read\n"+
+		"         until EOF",
 		encrypts.Default(),
+		keystore.KEYSTORE,
+		keystore.DefaultPath(),
+		keystore.PASSWORD,
+		kms.VAULT_TOKEN,
+		agent.DEFAULT,
+		agent.SOCKET,
 		sym.SALTLEN,
 		encodes.Default(),
 		encodes.Default(),
+		SEPARATOR,
+		encodes.Default(),
+		encrypts.Default(),
 		hashes.Default(),
+		LISTEN,
+		keystore.KEYSTORE,
+		server.MAX_REQUEST,
 		cfgs.BUFFER/1024,
 	)
 }
@@ -196,11 +466,15 @@ func parse(args []string) (cfg *cfgs.Config, err error) {
 		case CMD_ENCRYPT:
 			cfg.Enco = val
 			cfg.Enck = val
-		case CMD_DECRYPT:
+		case CMD_DECRYPT, CMD_EXEC:
 			cfg.Encd = val
 			cfg.Encv = val
 			cfg.Enct = val
 			cfg.Enck = val
+		case CMD_REKEY:
+			cfg.Encd = val
+			cfg.Enco = val
+			cfg.Enck = val
 		default:
 			cfg.Encd = val
 		}
@@ -216,6 +490,9 @@ func parse(args []string) (cfg *cfgs.Config, err error) {
 		"hash",    // 6
 		"display", // 7
 		"archive", // 8
+		"serve",   // 9
+		"rekey",   // 10
+		"exec",    // 11
 	})
 	cfg.SaltLen = sym.SALTLEN
 
@@ -265,6 +542,27 @@ func parse(args []string) (cfg *cfgs.Config, err error) {
 		switch {
 		case args[i] == "-v" || args[i] == "--verbose":
 			cfg.Verbose = true
+		case args[i] == "--":
+			cfg.Exec = args[i+1:]
+			i = len(args)
+		case strings.HasPrefix(args[i], "--prefix="):
+			if len(args[i]) <= 9 {
+				err = fmt.Errorf("[CONF] Missing environment variable prefix")
+				return
+			} else {
+				cfg.Prefix = args[i][9:]
+			}
+		case strings.HasPrefix(args[i], "--separator="):
+			if len(args[i]) <= 12 {
+				err = fmt.Errorf("[CONF] Missing environment variable separator")
+				return
+			} else {
+				cfg.Sep = args[i][12:]
+			}
+		case args[i] == "--interactive":
+			cfg.Sentinel = utils.SENTINEL_ON
+		case args[i] == "--no-sentinel":
+			cfg.Sentinel = utils.SENTINEL_OFF
 		case args[i] == "-l" || args[i] == "--list":
 			cfg.SetList()
 			i = len(args)
@@ -319,6 +617,22 @@ func parse(args []string) (cfg *cfgs.Config, err error) {
 			} else {
 				cfg.Key = args[i][6:]
 			}
+		case args[i] == "--agent":
+			cfg.Agent = agent.DEFAULT
+		case strings.HasPrefix(args[i], "--agent="):
+			if len(args[i]) <= 8 {
+				err = fmt.Errorf("[CONF] Missing key name")
+				return
+			} else {
+				cfg.Agent = args[i][8:]
+			}
+		case strings.HasPrefix(args[i], "--kms="):
+			if len(args[i]) <= 6 {
+				err = fmt.Errorf("[CONF] Missing KMS URI")
+				return
+			} else {
+				cfg.Kms = args[i][6:]
+			}
 		case strings.HasPrefix(args[i], "--iv="):
 			if len(args[i]) <= 5 {
 				err = fmt.Errorf("[CONF] Missing IV value")
@@ -361,6 +675,29 @@ func parse(args []string) (cfg *cfgs.Config, err error) {
 			} else {
 				cfg.Passwd = args[i][11:]
 			}
+		case strings.HasPrefix(args[i], "--new-algorithm="):
+			if len(args[i]) <= 16 {
+				err = fmt.Errorf("[CONF] Missing new algorithm")
+				return
+			} else {
+				cfg.NewAlgr = args[i][16:]
+			}
+		case strings.HasPrefix(args[i], "--new-key="):
+			if len(args[i]) <= 10 {
+				err = fmt.Errorf("[CONF] Missing new key filename")
+				return
+			} else {
+				cfg.NewKey = args[i][10:]
+			}
+		case args[i] == "--new-password":
+			cfg.NewPwd = PWD_INTERACTIVE
+		case strings.HasPrefix(args[i], "--new-password="):
+			if len(args[i]) <= 15 {
+				err = fmt.Errorf("[CONF] Missing new password value")
+				return
+			} else {
+				cfg.NewPwd = args[i][15:]
+			}
 		case args[i] == "-n":
 			i++
 			if i >= len(args) {
@@ -493,6 +830,130 @@ func parse(args []string) (cfg *cfgs.Config, err error) {
 			} else {
 				cfg.Zip = args[i][11:]
 			}
+		case args[i] == "--in-place":
+			cfg.InPlace = true
+		case args[i] == "--backup":
+			cfg.Backup = true
+		case strings.HasPrefix(args[i], "--include="):
+			if len(args[i]) <= 10 {
+				err = fmt.Errorf("[CONF] Missing include pattern")
+				return
+			} else {
+				cfg.Include = append(cfg.Include, args[i][10:])
+			}
+		case strings.HasPrefix(args[i], "--exclude="):
+			if len(args[i]) <= 10 {
+				err = fmt.Errorf("[CONF] Missing exclude pattern")
+				return
+			} else {
+				cfg.Exclude = append(cfg.Exclude, args[i][10:])
+			}
+		case strings.HasPrefix(args[i], "--path="):
+			if len(args[i]) <= 7 {
+				err = fmt.Errorf("[CONF] Missing path expression")
+				return
+			} else {
+				cfg.Paths = append(cfg.Paths, args[i][7:])
+			}
+		case strings.HasPrefix(args[i], "--encrypted-regex="):
+			if len(args[i]) <= 18 {
+				err = fmt.Errorf("[CONF] Missing regular expression")
+				return
+			} else {
+				cfg.Regex = args[i][18:]
+			}
+		case strings.HasPrefix(args[i], "--columns="):
+			if len(args[i]) <= 10 {
+				err = fmt.Errorf("[CONF] Missing columns")
+				return
+			} else {
+				cfg.Columns = append(cfg.Columns, strings.Split(args[i][10:], ",")...)
+			}
+		case args[i] == "--header":
+			cfg.NoHeader = false
+		case args[i] == "--no-header":
+			cfg.NoHeader = true
+		case args[i] == "--sops":
+			cfg.Sops = true
+		case strings.HasPrefix(args[i], "--workers="):
+			if len(args[i]) <= 10 {
+				err = fmt.Errorf("[CONF] Missing number of workers")
+				return
+			} else {
+				num, err = strconv.Atoi(args[i][10:])
+				if err != nil || num < 1 {
+					err = fmt.Errorf("[CONF] Invalid number of workers '%v'", args[i][10:])
+					return
+				}
+				cfg.Workers = num
+			}
+		case strings.HasPrefix(args[i], "--level="):
+			if len(args[i]) <= 8 {
+				err = fmt.Errorf("[CONF] Missing compression level")
+				return
+			} else {
+				num, err = strconv.Atoi(args[i][8:])
+				if err != nil {
+					err = fmt.Errorf("[CONF] Invalid compression level '%v'", args[i][8:])
+					return
+				}
+				cfg.Level = num
+			}
+		case args[i] == "-x" || args[i] == "--extract":
+			cfg.Extract = true
+		case strings.HasPrefix(args[i], "--listen="):
+			if len(args[i]) <= 9 {
+				err = fmt.Errorf("[CONF] Missing listen address")
+				return
+			} else {
+				cfg.Listen = args[i][9:]
+			}
+		case strings.HasPrefix(args[i], "--keystore="):
+			if len(args[i]) <= 11 {
+				err = fmt.Errorf("[CONF] Missing keystore filename")
+				return
+			} else {
+				cfg.Keystore = args[i][11:]
+			}
+		case strings.HasPrefix(args[i], "--identity="):
+			if len(args[i]) <= 11 {
+				err = fmt.Errorf("[CONF] Missing identity filename")
+				return
+			} else {
+				cfg.Identity = args[i][11:]
+			}
+		case strings.HasPrefix(args[i], "--tls-cert="):
+			if len(args[i]) <= 11 {
+				err = fmt.Errorf("[CONF] Missing TLS certificate filename")
+				return
+			} else {
+				cfg.TlsCert = args[i][11:]
+			}
+		case strings.HasPrefix(args[i], "--tls-key="):
+			if len(args[i]) <= 10 {
+				err = fmt.Errorf("[CONF] Missing TLS key filename")
+				return
+			} else {
+				cfg.TlsKey = args[i][10:]
+			}
+		case strings.HasPrefix(args[i], "--client-ca="):
+			if len(args[i]) <= 12 {
+				err = fmt.Errorf("[CONF] Missing client CA filename")
+				return
+			} else {
+				cfg.ClientCA = args[i][12:]
+			}
+		case strings.HasPrefix(args[i], "--max-size="):
+			if len(args[i]) <= 11 {
+				err = fmt.Errorf("[CONF] Missing maximum request size")
+				return
+			} else {
+				cfg.MaxSize, err = strconv.ParseInt(args[i][11:], 10, 64)
+				if err != nil || cfg.MaxSize < 1 {
+					err = fmt.Errorf("[CONF] Invalid maximum request size '%v'", args[i][11:])
+					return
+				}
+			}
 		default:
 			err = fmt.Errorf("[CONF] Invalid option '%v'", args[i])
 			return
@@ -515,16 +976,55 @@ func parse(args []string) (cfg *cfgs.Config, err error) {
 		if cfg.Encd == "" && cfg.Format != "" && cfg.Format != FORMAT_NONE {
 			cfg.Encd = encodes.Default()
 		}
+	case CMD_EXEC:
+		if cfg.Algr == "" {
+			cfg.Algr = encrypts.Default()
+		}
+		if cfg.Format == "" {
+			cfg.Format = FORMAT_YAML
+		}
+		if cfg.Encd == "" {
+			cfg.Encd = encodes.Default()
+		}
+		if cfg.Sep == "" {
+			cfg.Sep = SEPARATOR
+		}
+	case CMD_REKEY:
+		if cfg.Algr == "" {
+			cfg.Algr = encrypts.Default()
+		}
+		if cfg.Format != "" && cfg.Format != FORMAT_NONE {
+			if cfg.Encd == "" {
+				cfg.Encd = encodes.Default()
+			}
+			if cfg.Enco == "" {
+				cfg.Enco = encodes.Default()
+			}
+		}
 	case CMD_ENCODE:
 		fallthrough
 	case CMD_DECODE:
 		if cfg.Encd == "" {
 			cfg.Encd = encodes.Default()
 		}
+	case CMD_ARCHIVE:
+		if cfg.Algr == "" && (cfg.Key != "" || cfg.Passwd != "" || cfg.Genkey) {
+			cfg.Algr = encrypts.Default()
+		}
 	case CMD_HASHING:
 		if cfg.Hash == "" {
 			cfg.Hash = hashes.Default()
 		}
+	case CMD_SERVE:
+		if cfg.Keystore == "" {
+			cfg.Keystore = os.Getenv(keystore.KEYSTORE)
+		}
+		if cfg.Listen == "" {
+			cfg.Listen = LISTEN
+		}
+		if cfg.MaxSize == 0 {
+			cfg.MaxSize = server.MAX_REQUEST
+		}
 	}
 
 	return
@@ -557,6 +1057,40 @@ func validate(cfg *cfgs.Config) (err error) {
 		}
 	}
 
+	if (cfg.InPlace || cfg.Backup) && cfg.Cmd() != CMD_ENCRYPT && cfg.Cmd() != CMD_DECRYPT && cfg.Cmd() != CMD_REKEY {
+		errs = append(errs, fmt.Errorf("options '--in-place' and '--backup' only applicable to 'encrypt', 'decrypt' and 'rekey'"))
+	}
+	if (len(cfg.Columns) > 0 || cfg.NoHeader) && cfg.Cmd() != CMD_ENCRYPT && cfg.Cmd() != CMD_DECRYPT {
+		errs = append(errs, fmt.Errorf("options '--columns' and '--no-header' only applicable to 'encrypt' and 'decrypt'"))
+	}
+	if (len(cfg.Exec) > 0 || cfg.Prefix != "" || cfg.Sep != "") && cfg.Cmd() != CMD_EXEC {
+		errs = append(errs, fmt.Errorf("options '--prefix', '--separator' and '--' only applicable to 'exec'"))
+	}
+	if (cfg.NewAlgr != "" || cfg.NewKey != "" || cfg.NewPwd != "") && cfg.Cmd() != CMD_REKEY {
+		errs = append(errs, fmt.Errorf("options '--new-algorithm', '--new-key' and '--new-password' only applicable to 'rekey'"))
+	}
+	if cfg.Sops && cfg.Cmd() != CMD_ENCRYPT {
+		errs = append(errs, fmt.Errorf("option '--sops' only applicable to 'encrypt'"))
+	}
+	if (cfg.Kms != "" || cfg.Agent != "") && cfg.Cmd() != CMD_ENCRYPT && cfg.Cmd() != CMD_DECRYPT {
+		errs = append(errs, fmt.Errorf("options '--kms' and '--agent' only applicable to 'encrypt' and 'decrypt'"))
+	}
+
+	if cfg.Level != cfgs.LEVEL_DEFAULT {
+		name := cfg.Zip
+		if cfg.Cmd() == CMD_ARCHIVE {
+			name = cfg.Encd
+		} else if cfg.Cmd() != CMD_ENCRYPT || cfg.Zip == "" {
+			errs = append(errs, fmt.Errorf("compression level only applicable to 'archive', or 'encrypt' with '-z'"))
+		}
+		name, _ = encodes.ParseCompressor(name)
+		if c := encodes.GetCompressor(name); c != nil {
+			if _, err = c.WithLevel(cfg.Level); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
 	zipChecked := false
 	switch cfg.Cmd() {
 	case CMD_ENCRYPT:
@@ -575,10 +1109,8 @@ func validate(cfg *cfgs.Config) (err error) {
 				return
 			}
 			zipChecked = true
-			if t, err := encodes.Validate(cfg.Zip, -1); err != nil {
+			if err = encodes.ValidateCompressor(cfg.Zip, true); err != nil {
 				errs = append(errs, err)
-			} else if t <= 0 {
-				errs = append(errs, fmt.Errorf("expects compression for encryption"))
 			}
 		}
 		fallthrough
@@ -592,112 +1124,290 @@ func validate(cfg *cfgs.Config) (err error) {
 				err = fmt.Errorf("[VLDT] incompatable options '-z' and '-f'")
 				return
 			}
-			if t, err := encodes.Validate(cfg.Zip, -1); err != nil {
+			if err = encodes.ValidateCompressor(cfg.Zip, false); err != nil {
 				errs = append(errs, err)
-			} else if t >= 0 {
-				errs = append(errs, fmt.Errorf("expects decompression for decryption"))
 			}
 		}
 
-		if cfg.Passwd != "" && cfg.Genkey {
-			err = fmt.Errorf("[VLDT] incompatable options '-g' and '-p'") // > c9ryptool e|d -p -g -i README.md
-			return
+		if cfg.InPlace {
+			if cfg.Input == "" || isDir(cfg.Input) {
+				errs = append(errs, fmt.Errorf("option '--in-place' requires an input file"))
+			}
+			if cfg.Output != "" {
+				errs = append(errs, fmt.Errorf("incompatable options '--in-place' and '-o'"))
+			}
+		} else if cfg.Backup {
+			errs = append(errs, fmt.Errorf("option '--backup' requires '--in-place'"))
 		}
 
-		if cfg.Key != "" {
-			if cfg.Passwd != "" {
-				err = fmt.Errorf("[VLDT] incompatable options '-k' and '-p'")
-				return
+		if isDir(cfg.Input) {
+			if cfg.Output == "" {
+				errs = append(errs, fmt.Errorf("output directory missing"))
+			}
+			if cfg.Format != "" && cfg.Format != FORMAT_NONE {
+				errs = append(errs, fmt.Errorf("option '-f' not supported when the input is a directory"))
 			}
-			if _, err = os.Stat(cfg.Key); errors.Is(err, os.ErrNotExist) {
-				if !cfg.Genkey {
-					errs = append(errs, fmt.Errorf("key file '%v' does not exist", cfg.Key))
+			if cfg.Iv != "" || cfg.Tag != "" || cfg.Aad != "" || cfg.Kms != "" || cfg.Agent != "" {
+				errs = append(errs, fmt.Errorf("options '--iv', '--tag', '--aad', '--kms' and '--agent' not supported when the input is a directory"))
+			}
+			if cfg.Cmd() == CMD_DECRYPT {
+				if _, e := os.Stat(filepath.Join(cfg.Input, MANIFEST)); e != nil {
+					errs = append(errs, fmt.Errorf("manifest '%v' not found in '%v'", MANIFEST, cfg.Input))
 				}
-			} else if err != nil {
-				err = fmt.Errorf("[VLDT] %v", err)
-				return
-			} else if cfg.Genkey {
-				errs = append(errs, fmt.Errorf("key file '%v' already exists", cfg.Key))
 			}
-		} else if cfg.Passwd == "" {
-			errs = append(errs, fmt.Errorf("encryption key missing")) // > go run ./cmd/c9ryptool e|d {-g} -i README.md
-		}
-
-		if cfg.Cmd() == CMD_DECRYPT && cfg.Genkey {
-			errs = append(errs, fmt.Errorf("cannot generate new key for decryption")) // > c9ryptool d -g {-k key.txt} -i README.md
+			for _, p := range append(cfg.Include, cfg.Exclude...) {
+				if err = utils.ValidateGlob(p); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		} else if len(cfg.Include) > 0 || len(cfg.Exclude) > 0 || cfg.Workers > 0 {
+			errs = append(errs, fmt.Errorf("options '--include', '--exclude' and '--workers' only apply when the input is a directory"))
 		}
 
 		var typ int
-		if cfg.Passwd != "" || cfg.Iv != "" || cfg.Tag != "" || cfg.Aad != "" {
-			// must be symmetric algorithm if:
-			// 1. encryption key is generated from a passphrase
-			// 2. IV is given
-			typ = 1
-		}
-
-		if _, err = encrypts.Validate(cfg.Algr, typ); err != nil {
-			errs = append(errs, err)
+		var kerrs []error
+		typ, kerrs, err = validateKey(cfg, cfg.Cmd() == CMD_DECRYPT)
+		if err != nil {
+			return
 		}
+		errs = append(errs, kerrs...)
 
 		if cfg.Encd != "" {
-			if _, err = encodes.Validate(cfg.Encd, 1); err != nil {
+			if err = encodes.Validate(cfg.Encd); err != nil {
 				errs = append(errs, err)
 			}
 		}
 		if cfg.Enco != "" {
-			if _, err = encodes.Validate(cfg.Enco, 1); err != nil {
+			if err = encodes.Validate(cfg.Enco); err != nil {
 				errs = append(errs, err)
 			}
 		}
 		if typ > 0 {
 			if cfg.Encv != "" {
-				if _, err = encodes.Validate(cfg.Encv, 1); err != nil {
+				if err = encodes.Validate(cfg.Encv); err != nil {
 					errs = append(errs, err)
 				}
 			}
 			if cfg.Enct != "" {
-				if _, err = encodes.Validate(cfg.Enct, 1); err != nil {
+				if err = encodes.Validate(cfg.Enct); err != nil {
 					errs = append(errs, err)
 				}
 			}
 			if cfg.Enca != "" {
-				if _, err = encodes.Validate(cfg.Enca, 1); err != nil {
+				if err = encodes.Validate(cfg.Enca); err != nil {
 					errs = append(errs, err)
 				}
 			}
 			if cfg.Enck != "" {
-				if _, err = encodes.Validate(cfg.Enck, 1); err != nil {
+				if err = encodes.Validate(cfg.Enck); err != nil {
 					errs = append(errs, err)
 				}
 			}
 		}
 
 		if cfg.Format != "" {
-			if cfg.Format != FORMAT_NONE && cfg.Format != FORMAT_YAML && cfg.Format != FORMAT_JSON {
+			if cfg.Format != FORMAT_NONE && cfg.Format != FORMAT_YAML && cfg.Format != FORMAT_JSON && cfg.Format != FORMAT_K8S_SECRET && cfg.Format != FORMAT_CSV && cfg.Format != FORMAT_JSONL && !isLines(cfg.Format) {
 				err = fmt.Errorf("[VLDT] unsupported file format '%v'", cfg.Format)
+			} else if cfg.Format != FORMAT_NONE && cfg.Agent != "" {
+				errs = append(errs, fmt.Errorf("option '--agent' only supported with format '%v'", FORMAT_NONE))
+			} else if cfg.Format != FORMAT_NONE && cfg.Kms != "" && ((cfg.Format != FORMAT_YAML && cfg.Format != FORMAT_JSON) || (cfg.Cmd() == CMD_ENCRYPT && !cfg.Sops)) {
+				errs = append(errs, fmt.Errorf("option '--kms' only supported with format '%v', or SOPS documents", FORMAT_NONE))
+			}
+		}
+		if cfg.Sops {
+			if cfg.Format != FORMAT_YAML && cfg.Format != FORMAT_JSON {
+				errs = append(errs, fmt.Errorf("option '--sops' only supported with formats '%v' and '%v'", FORMAT_YAML, FORMAT_JSON))
+			}
+			if len(cfg.Paths) > 0 || cfg.Iv != "" || cfg.Tag != "" || cfg.Aad != "" {
+				errs = append(errs, fmt.Errorf("options '--path', '--iv', '--tag' and '--aad' not supported with '--sops'"))
+			}
+		}
+		errs = append(errs, validatePaths(cfg)...)
+		if (cfg.Format == FORMAT_CSV || cfg.Format == FORMAT_JSONL) && cfg.InPlace {
+			errs = append(errs, fmt.Errorf("option '--in-place' not supported by format '%v', which is streamed", cfg.Format))
+		}
+		if (isNodes(cfg.Format) && !cfg.Sops || cfg.Format == FORMAT_K8S_SECRET || cfg.Format == FORMAT_CSV || cfg.Format == FORMAT_JSONL || isLines(cfg.Format)) && (cfg.Iv != "" || cfg.Tag != "") {
+			errs = append(errs, fmt.Errorf("options '--iv' and '--tag' not supported by format '%v', a nonce is generated for each value", cfg.Format))
+		}
+		if cfg.Format == FORMAT_CSV {
+			if len(cfg.Columns) <= 0 {
+				errs = append(errs, fmt.Errorf("option '--columns' required by format '%v'", FORMAT_CSV))
+			}
+		} else if len(cfg.Columns) > 0 || cfg.NoHeader {
+			errs = append(errs, fmt.Errorf("options '--columns' and '--no-header' only apply to format '%v'", FORMAT_CSV))
+		}
+		for _, c := range cfg.Columns {
+			if c = strings.TrimSpace(c); c == "" {
+				errs = append(errs, fmt.Errorf("empty column in '--columns'"))
+			} else if _, e := strconv.Atoi(c); e != nil && cfg.NoHeader {
+				errs = append(errs, fmt.Errorf("column '%v' given by name, which requires the header, remove '--no-header'", c))
 			}
 		}
 
+	case CMD_REKEY:
+		if cfg.IsList() {
+			errs = append(errs, fmt.Errorf("option '-l' not applicable to 'rekey', use 'encrypt -l'"))
+			break
+		}
+		if cfg.Format != "" && cfg.Format != FORMAT_NONE && !isNodes(cfg.Format) {
+			errs = append(errs, fmt.Errorf("format '%v' not supported by 'rekey', use 'decrypt' and 'encrypt' instead", cfg.Format))
+		}
+		if cfg.Iv != "" || cfg.Tag != "" || cfg.Aad != "" || cfg.Kms != "" || cfg.Agent != "" || cfg.Zip != "" {
+			errs = append(errs, fmt.Errorf("options '--iv', '--tag', '--aad', '--kms', '--agent' and '-z' not supported by 'rekey'"))
+		}
+
+		if isDir(cfg.Input) {
+			if cfg.Output == "" && !cfg.InPlace {
+				errs = append(errs, fmt.Errorf("output directory missing, or use '--in-place'"))
+			}
+			if (len(cfg.Include) > 0 || len(cfg.Exclude) > 0) && hasManifest(cfg.Input) && !isNodes(cfg.Format) {
+				errs = append(errs, fmt.Errorf("options '--include' and '--exclude' not supported for directories encrypted with a manifest"))
+			}
+			for _, p := range append(cfg.Include, cfg.Exclude...) {
+				if err = utils.ValidateGlob(p); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		} else {
+			if cfg.InPlace && cfg.Input == "" {
+				errs = append(errs, fmt.Errorf("option '--in-place' requires an input file"))
+			}
+			if len(cfg.Include) > 0 || len(cfg.Exclude) > 0 || cfg.Workers > 0 {
+				errs = append(errs, fmt.Errorf("options '--include', '--exclude' and '--workers' only apply when the input is a directory"))
+			}
+		}
+		if cfg.InPlace && cfg.Output != "" {
+			errs = append(errs, fmt.Errorf("incompatable options '--in-place' and '-o'"))
+		} else if cfg.Backup && !cfg.InPlace {
+			errs = append(errs, fmt.Errorf("option '--backup' requires '--in-place'"))
+		}
+
+		ocfg, ncfg := rekeyConfigs(cfg)
+		var kerrs []error
+		if _, kerrs, err = validateKey(ocfg, true); err != nil {
+			return
+		}
+		errs = append(errs, kerrs...)
+		if ncfg.Key == "" && ncfg.Passwd == "" {
+			errs = append(errs, fmt.Errorf("new key missing, use '--new-key' or '--new-password'"))
+		} else if _, kerrs, err = validateKey(ncfg, false); err != nil {
+			return
+		} else {
+			errs = append(errs, kerrs...)
+		}
+		for _, enc := range []string{cfg.Encd, cfg.Enco, cfg.Enck} {
+			if enc != "" {
+				if err = encodes.Validate(enc); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+		errs = append(errs, validatePaths(cfg)...)
+
+	case CMD_EXEC:
+		if cfg.IsList() {
+			errs = append(errs, fmt.Errorf("option '-l' not applicable to 'exec', use 'decrypt -l'"))
+			break
+		}
+		if len(cfg.Exec) <= 0 {
+			errs = append(errs, fmt.Errorf("command missing, use '-- COMMAND {ARGS}'"))
+		}
+		if cfg.Format != FORMAT_YAML {
+			errs = append(errs, fmt.Errorf("format '%v' not supported by 'exec'", cfg.Format))
+		}
+		if cfg.Input == "" {
+			errs = append(errs, fmt.Errorf("input file missing, stdin is passed to the command"))
+		} else if isDir(cfg.Input) {
+			errs = append(errs, fmt.Errorf("input '%v' is a directory", cfg.Input))
+		}
+		if cfg.Output != "" || cfg.InPlace || cfg.Kms != "" || cfg.Agent != "" || cfg.Zip != "" || cfg.Iv != "" || cfg.Tag != "" {
+			errs = append(errs, fmt.Errorf("options '-o', '--in-place', '--kms', '--agent', '-z', '--iv' and '--tag' not applicable to 'exec'"))
+		}
+		var kerrs []error
+		if _, kerrs, err = validateKey(cfg, true); err != nil {
+			return
+		}
+		errs = append(errs, kerrs...)
+		for _, enc := range []string{cfg.Encd, cfg.Encv, cfg.Enct, cfg.Enca, cfg.Enck} {
+			if enc != "" {
+				if err = encodes.Validate(enc); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+		errs = append(errs, validatePaths(cfg)...)
+
 	case CMD_ENCODE:
 		fallthrough
 	case CMD_DECODE:
 		if cfg.IsList() {
 			break
 		}
-		if _, err = encodes.Validate(cfg.Encd, 1); err != nil {
+		if err = encodes.Validate(cfg.Encd); err != nil {
 			errs = append(errs, err)
 		}
 	case CMD_ARCHIVE:
 		if cfg.IsList() {
 			break
 		}
-		if _, err = encodes.Validate(cfg.Encd, -1); err != nil {
+		if cfg.Encd != "" {
+			if _, dec := encodes.ParseCompressor(cfg.Encd); dec {
+				cfg.Extract = true
+			}
+		}
+		if cfg.Format == "" && !cfg.Extract && cfg.Input != "" {
+			if info, e := os.Stat(cfg.Input); e == nil && info.IsDir() {
+				cfg.Format = archives.Default()
+			}
+		}
+
+		if cfg.Format == "" {
+			if err = encodes.ValidateCompressor(cfg.Encd, !cfg.Extract); err != nil {
+				errs = append(errs, err)
+			}
+			if cfg.Algr != "" || cfg.Key != "" || cfg.Passwd != "" || cfg.Genkey {
+				errs = append(errs, fmt.Errorf("encryption only supported when archiving directories"))
+			}
+			break
+		}
+
+		if err = archives.Validate(cfg.Format); err != nil {
 			errs = append(errs, err)
+		} else if archives.Parse(cfg.Format) == "zip" && cfg.Encd != "" {
+			errs = append(errs, fmt.Errorf("zip archives are already compressed, cannot use '%v'", cfg.Encd))
+		}
+		if cfg.Encd != "" {
+			if err = encodes.ValidateCompressor(cfg.Encd, !cfg.Extract); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if cfg.Extract {
+			if cfg.Output == "" {
+				errs = append(errs, fmt.Errorf("output directory missing"))
+			}
+		} else if cfg.Input == "" {
+			errs = append(errs, fmt.Errorf("input directory missing"))
+		} else if info, e := os.Stat(cfg.Input); e == nil && !info.IsDir() {
+			errs = append(errs, fmt.Errorf("input '%v' is not a directory", cfg.Input))
+		}
+
+		if cfg.Algr != "" || cfg.Key != "" || cfg.Passwd != "" || cfg.Genkey {
+			var kerrs []error
+			_, kerrs, err = validateKey(cfg, cfg.Extract)
+			if err != nil {
+				return
+			}
+			errs = append(errs, kerrs...)
+			if cfg.Enck != "" {
+				if err = encodes.Validate(cfg.Enck); err != nil {
+					errs = append(errs, err)
+				}
+			}
 		}
 
 	case CMD_DISPLAY:
 		if cfg.Encd != "" {
-			_, err = encodes.Validate(cfg.Encd, 1)
+			err = encodes.Validate(cfg.Encd)
 		}
 
 	case CMD_HASHING:
@@ -707,6 +1417,37 @@ func validate(cfg *cfgs.Config) (err error) {
 		if err = hashes.Validate(cfg.Hash); err != nil {
 			errs = append(errs, err)
 		}
+
+	case CMD_SERVE:
+		if cfg.Input != "" || cfg.Output != "" || cfg.Key != "" || cfg.Passwd != "" || cfg.Genkey {
+			errs = append(errs, fmt.Errorf("options '-i', '-o', '-k', '-p' and '-g' not applicable to 'serve', use '--keystore'"))
+		}
+		if (cfg.TlsCert == "") != (cfg.TlsKey == "") {
+			errs = append(errs, fmt.Errorf("options '--tls-cert' and '--tls-key' must be given together"))
+		} else if cfg.ClientCA != "" && cfg.TlsCert == "" {
+			errs = append(errs, fmt.Errorf("option '--client-ca' requires '--tls-cert' and '--tls-key'"))
+		}
+		if cfg.Identity != "" && cfg.Keystore == "" {
+			errs = append(errs, fmt.Errorf("option '--identity' requires '--keystore'"))
+		}
+		for _, f := range []string{cfg.Keystore, cfg.Identity, cfg.TlsCert, cfg.TlsKey, cfg.ClientCA} {
+			if f == "" {
+				continue
+			}
+			if _, err = os.Stat(f); errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("file '%v' does not exist", f))
+			} else if err != nil {
+				err = fmt.Errorf("[VLDT] %v", err)
+				return
+			}
+		}
+	}
+	if cfg.Cmd() != CMD_SERVE && (cfg.Listen != "" || cfg.TlsCert != "" || cfg.TlsKey != "" || cfg.ClientCA != "" || cfg.MaxSize != 0) {
+		errs = append(errs, fmt.Errorf("options '--listen', '--tls-cert', '--tls-key', '--client-ca' and '--max-size' only applicable to 'serve'"))
+	}
+	_, ok := keyName(cfg.Key)
+	if _, nok := keyName(cfg.NewKey); cfg.Cmd() != CMD_SERVE && !ok && !nok && (cfg.Keystore != "" || cfg.Identity != "") {
+		errs = append(errs, fmt.Errorf("options '--keystore' and '--identity' only applicable to 'serve', or with '-k @NAME'"))
 	}
 
 	if len(errs) > 0 {
@@ -719,3 +1460,104 @@ func validate(cfg *cfgs.Config) (err error) {
 	}
 	return
 }
+
+// validatePaths validate the options selecting the values to encrypt in field-level formats.
+func validatePaths(cfg *cfgs.Config) (errs []error) {
+	if len(cfg.Paths) <= 0 && cfg.Regex == "" {
+		return
+	}
+	if cfg.Format != FORMAT_YAML && cfg.Format != FORMAT_JSON && cfg.Format != FORMAT_K8S_SECRET && cfg.Format != FORMAT_JSONL && !isLines(cfg.Format) {
+		errs = append(errs, fmt.Errorf("options '--path' and '--encrypted-regex' only apply to field-level formats"))
+	}
+	for _, p := range cfg.Paths {
+		if _, err := utils.ParsePath(strings.TrimPrefix(p, "!")); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cfg.Regex != "" {
+		if _, err := regexp.Compile(cfg.Regex); err != nil {
+			errs = append(errs, fmt.Errorf("invalid regular expression '%v'", cfg.Regex))
+		}
+	}
+	return
+}
+
+// validateKey validate the options related to encryption keys.
+// returns typ: 1 - symmetric algorithm is expected; 0 - don't care
+func validateKey(cfg *cfgs.Config, isDecrypt bool) (typ int, errs []error, err error) {
+	errs = make([]error, 0)
+
+	if cfg.Passwd != "" && cfg.Genkey {
+		err = fmt.Errorf("[VLDT] incompatable options '-g' and '-p'") // > c9ryptool e|d -p -g -i README.md
+		return
+	}
+
+	if cfg.Agent != "" {
+		if cfg.Key != "" || cfg.Passwd != "" || cfg.Genkey || cfg.Kms != "" {
+			err = fmt.Errorf("[VLDT] option '--agent' is incompatable with '-k', '-p', '-g' and '--kms'")
+			return
+		}
+		if cfg.Iv != "" || cfg.Tag != "" {
+			errs = append(errs, fmt.Errorf("options '--iv' and '--tag' not supported with '--agent'"))
+		}
+		return // the algorithm is that of the key in the agent
+	} else if cfg.Kms != "" {
+		if cfg.Key != "" || cfg.Passwd != "" || cfg.Genkey {
+			err = fmt.Errorf("[VLDT] option '--kms' is incompatable with '-k', '-p' and '-g'")
+			return
+		}
+		if _, e := kms.Open(cfg.Kms); e != nil {
+			errs = append(errs, e)
+		}
+	} else if name, ok := keyName(cfg.Key); ok {
+		if cfg.Passwd != "" || cfg.Genkey {
+			err = fmt.Errorf("[VLDT] option '-k @NAME' is incompatable with '-p' and '-g'")
+			return
+		}
+		if name == "" {
+			errs = append(errs, fmt.Errorf("key name missing in '-k @NAME'"))
+		}
+		for _, f := range []string{cfg.Keystore, cfg.Identity} {
+			if _, e := os.Stat(f); f != "" && e != nil {
+				errs = append(errs, fmt.Errorf("file '%v' does not exist", f))
+			}
+		}
+		return // the algorithm is that of the key in the keystore
+	} else if cfg.Key != "" {
+		if cfg.Passwd != "" {
+			err = fmt.Errorf("[VLDT] incompatable options '-k' and '-p'")
+			return
+		}
+		if _, err = os.Stat(cfg.Key); errors.Is(err, os.ErrNotExist) {
+			err = nil
+			if !cfg.Genkey {
+				errs = append(errs, fmt.Errorf("key file '%v' does not exist", cfg.Key))
+			}
+		} else if err != nil {
+			err = fmt.Errorf("[VLDT] %v", err)
+			return
+		} else if cfg.Genkey {
+			errs = append(errs, fmt.Errorf("key file '%v' already exists", cfg.Key))
+		}
+	} else if cfg.Passwd == "" {
+		errs = append(errs, fmt.Errorf("encryption key missing")) // > go run ./cmd/c9ryptool e|d {-g} -i README.md
+	}
+
+	if isDecrypt && cfg.Genkey {
+		errs = append(errs, fmt.Errorf("cannot generate new key for decryption")) // > c9ryptool d -g {-k key.txt} -i README.md
+	}
+
+	if cfg.Passwd != "" || cfg.Kms != "" || cfg.Iv != "" || cfg.Tag != "" || cfg.Aad != "" {
+		// must be symmetric algorithm if:
+		// 1. encryption key is generated from a passphrase
+		// 2. data key is wrapped by a KMS
+		// 3. IV is given
+		typ = 1
+	}
+
+	var e error
+	if _, e = encrypts.Validate(cfg.Algr, typ); e != nil {
+		errs = append(errs, e)
+	}
+	return
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+// MANIFEST name of the manifest file written to the root of the encrypted directory
+const MANIFEST = ".c9manifest"
+
+// manifest list of the files in an encrypted directory. The manifest itself is encrypted using the
+// same key as the files, since it contains the hashes of the plaintexts.
+type manifest struct {
+	Algorithm string          `json:"algorithm"`
+	Hashing   string          `json:"hashing"`
+	Compress  string          `json:"compress,omitempty"`
+	Files     []manifestEntry `json:"files"`
+}
+
+type manifestEntry struct {
+	Path string      `json:"path"` // slash-separated path relative to the directory root
+	Size int         `json:"size"` // plaintext size in bytes
+	Mode fs.FileMode `json:"mode"`
+	Hash string      `json:"hash"` // sha256 of the plaintext, hex encoded
+}
+
+// isDir 'true' if 'path' is an existing directory
+func isDir(path string) bool {
+	if path == "" {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// selected check the slash-separated relative path 'rel' against the include/exclude patterns
+func selected(cfg *cfgs.Config, rel string) bool {
+	for _, p := range cfg.Exclude {
+		if utils.MatchGlob(p, rel) {
+			return false
+		}
+	}
+	if len(cfg.Include) <= 0 {
+		return true
+	}
+	for _, p := range cfg.Include {
+		if utils.MatchGlob(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// runWorkers call 'work' for index 0 to 'count'-1 using a pool of workers, returns the errors encountered
+func runWorkers(workers, count int, work func(int) error) (errs []error) {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	errs = make([]error, 0)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := work(i); err != nil {
+					lock.Lock()
+					errs = append(errs, err)
+					lock.Unlock()
+				}
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+	return
+}
+
+// batchError combine the errors of the individual files
+func batchError(errs []error, total int) error {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, " %v of %v files failed [", len(errs), total)
+	for _, err := range errs {
+		fmt.Fprintf(&buf, "\n - %v", err)
+	}
+	return fmt.Errorf("%v\n]", buf.String())
+}
+
+// encryptDir encrypt the files in the input directory to the output directory, the key is populated once
+// and shared by all the workers, since the algorithms do not modify their states when encrypting.
+func encryptDir(
+	cfg *cfgs.Config,
+	alg encrypts.Algorithm,
+	eci, eco, eck encodes.Encoding,
+	zip encodes.Compressor,
+) (err error) {
+	var salt []byte
+	mnft := manifest{
+		Algorithm: alg.Name(),
+		Hashing:   "sha256",
+		Files:     make([]manifestEntry, 0),
+	}
+	if zip != nil {
+		mnft.Compress = zip.Name()
+	}
+
+	err = filepath.WalkDir(cfg.Input, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(cfg.Input, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if d.IsDir() {
+			for _, p := range cfg.Exclude {
+				if utils.MatchGlob(p, rel) {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || rel == MANIFEST || !selected(cfg, rel) {
+			if cfg.Verbose {
+				fmt.Printf("Skipping '%v'\n", rel)
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		mnft.Files = append(mnft.Files, manifestEntry{Path: rel, Mode: info.Mode().Perm()})
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("[ECY][DIR]%v", err)
+		return
+	}
+
+	salt, err = populateKey(cfg, alg, eck, nil, false)
+	if err != nil {
+		err = fmt.Errorf("[ECY]%v", err)
+		return
+	}
+
+	errs := runWorkers(cfg.Workers, len(mnft.Files), func(i int) (err error) {
+		ent := &mnft.Files[i]
+		var input []byte
+		var rst encrypts.EncryptResult
+
+		input, err = utils.Read(filepath.Join(cfg.Input, filepath.FromSlash(ent.Path)), cfg.Buffer, eci)
+		if err != nil {
+			return fmt.Errorf("%v: [INP]%v", ent.Path, err)
+		}
+		hsh := sha256.Sum256(input)
+		ent.Size, ent.Hash = len(input), hex.EncodeToString(hsh[:])
+
+		if zip != nil {
+			input, err = encodes.Compress(zip, input)
+			if err != nil {
+				return fmt.Errorf("%v: [ZIP]%v", ent.Path, err)
+			}
+		}
+
+		rst, err = encrypts.V2(alg).Encrypt(encrypts.EncryptRequest{Plaintext: input})
+		if err != nil {
+			return fmt.Errorf("%v: %v", ent.Path, err)
+		}
+		if cfg.Verbose {
+			fmt.Printf("Encrypted '%v' (%v bytes)\n", ent.Path, ent.Size)
+		}
+		return writeFile(cfg.Output, ent.Path, append(rst.Output, salt...), ent.Mode, eco)
+	})
+	if len(errs) > 0 {
+		err = fmt.Errorf("[ECY]%v", batchError(errs, len(mnft.Files)))
+		return
+	}
+
+	var dat []byte
+	var rst encrypts.EncryptResult
+	dat, err = json.MarshalIndent(mnft, "", "  ")
+	if err != nil {
+		err = fmt.Errorf("[ECY][MNFT]%v", err)
+		return
+	}
+	rst, err = encrypts.V2(alg).Encrypt(encrypts.EncryptRequest{Plaintext: dat})
+	if err != nil {
+		err = fmt.Errorf("[ECY][MNFT]%v", err)
+		return
+	}
+	err = writeFile(cfg.Output, MANIFEST, append(rst.Output, salt...), 0600, eco)
+	if err != nil {
+		err = fmt.Errorf("[ECY][MNFT]%v", err)
+	}
+	return
+}
+
+// decryptDir decrypt the files listed in the manifest of the input directory to the output directory, the
+// decrypted files are verified against the sizes and hashes recorded in the manifest.
+func decryptDir(
+	cfg *cfgs.Config,
+	alg encrypts.Algorithm,
+	eci, eco, eck encodes.Encoding,
+	unzip encodes.Compressor,
+) (err error) {
+	var input, salt []byte
+	var rst encrypts.DecryptResult
+	var mnft manifest
+
+	input, err = utils.Read(filepath.Join(cfg.Input, MANIFEST), cfg.Buffer, eci)
+	if err != nil {
+		err = fmt.Errorf("[DCY][MNFT]%v", err)
+		return
+	}
+	salt, err = populateKey(cfg, alg, eck, input, true)
+	if err != nil {
+		err = fmt.Errorf("[DCY]%v", err)
+		return
+	}
+	rst, err = encrypts.V2(alg).Decrypt(encrypts.DecryptRequest{Ciphertext: input[:len(input)-len(salt)]})
+	if err != nil {
+		err = fmt.Errorf("[DCY][MNFT]%v", err)
+		return
+	}
+	err = json.Unmarshal(rst.Plaintext, &mnft)
+	if err != nil {
+		err = fmt.Errorf("[DCY][MNFT]%v", err)
+		return
+	}
+	if mnft.Hashing != "sha256" {
+		err = fmt.Errorf("[DCY][MNFT] unsupported hashing algorithm '%v'", mnft.Hashing)
+		return
+	}
+	if unzip == nil && mnft.Compress != "" {
+		unzip, err = compressor(mnft.Compress, cfgs.LEVEL_DEFAULT)
+		if err != nil {
+			err = fmt.Errorf("[DCY][MNFT]%v", err)
+			return
+		}
+	}
+
+	files := make([]manifestEntry, 0, len(mnft.Files))
+	for _, ent := range mnft.Files {
+		if !filepath.IsLocal(filepath.FromSlash(ent.Path)) {
+			err = fmt.Errorf("[DCY][MNFT] invalid path '%v'", ent.Path)
+			return
+		}
+		if selected(cfg, ent.Path) {
+			files = append(files, ent)
+		}
+	}
+
+	errs := runWorkers(cfg.Workers, len(files), func(i int) (err error) {
+		ent := files[i]
+		var input []byte
+		var rst encrypts.DecryptResult
+
+		input, err = utils.Read(filepath.Join(cfg.Input, filepath.FromSlash(ent.Path)), cfg.Buffer, eci)
+		if err != nil {
+			return fmt.Errorf("%v: [INP]%v", ent.Path, err)
+		}
+		if salt != nil {
+			if !bytes.HasSuffix(input, salt) {
+				return fmt.Errorf("%v: salt mismatched with the manifest", ent.Path)
+			}
+			input = input[:len(input)-len(salt)]
+		}
+
+		rst, err = encrypts.V2(alg).Decrypt(encrypts.DecryptRequest{Ciphertext: input})
+		if err != nil {
+			return fmt.Errorf("%v: %v", ent.Path, err)
+		}
+		result := rst.Plaintext
+		if unzip != nil {
+			result, err = encodes.Decompress(unzip, result)
+			if err != nil {
+				return fmt.Errorf("%v: [ZIP]%v", ent.Path, err)
+			}
+		}
+
+		hsh := sha256.Sum256(result)
+		if len(result) != ent.Size {
+			return fmt.Errorf("%v: size mismatched, expected %v, got %v", ent.Path, ent.Size, len(result))
+		} else if hex.EncodeToString(hsh[:]) != ent.Hash {
+			return fmt.Errorf("%v: hash mismatched", ent.Path)
+		}
+		if cfg.Verbose {
+			fmt.Printf("Decrypted '%v' (%v bytes)\n", ent.Path, ent.Size)
+		}
+		return writeFile(cfg.Output, ent.Path, result, ent.Mode, eco)
+	})
+	if len(errs) > 0 {
+		err = fmt.Errorf("[DCY]%v", batchError(errs, len(files)))
+	}
+	return
+}
+
+// writeFile write 'dat' to the slash-separated path 'rel' under 'dir', creating any missing parent directories
+func writeFile(dir, rel string, dat []byte, mode fs.FileMode, eco encodes.Encoding) (err error) {
+	path := filepath.Join(dir, filepath.FromSlash(rel))
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("%v: [OUT] %v", rel, err)
+	}
+	if err = utils.Write(path, dat, eco); err != nil {
+		return fmt.Errorf("%v: [OUT]%v", rel, err)
+	}
+	if err = os.Chmod(path, mode); err != nil {
+		return fmt.Errorf("%v: [OUT] %v", rel, err)
+	}
+	return
+}
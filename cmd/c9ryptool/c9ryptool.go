@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
+	"sea9.org/go/c9ryptool/pkg/archives"
 	"sea9.org/go/c9ryptool/pkg/cfgs"
 	"sea9.org/go/c9ryptool/pkg/encodes"
 	"sea9.org/go/c9ryptool/pkg/encrypts"
 	"sea9.org/go/c9ryptool/pkg/hashes"
+	"sea9.org/go/c9ryptool/pkg/utils"
 )
 
 const LOG_FRM_MILLI = "2006-01-02T15:04:05.000"
@@ -18,11 +21,20 @@ func desc() string {
 	return fmt.Sprintf("c9rypTool (version %v)", cfgs.Version())
 }
 
+// aliases format the aliases of an algorithm or scheme for listing
+func aliases(list []string) string {
+	if len(list) <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%v)", strings.Join(list, ", "))
+}
+
 func main() {
 	cfg, err := parse(os.Args)
 	if err != nil {
 		log.Fatalf("[MAIN]%v\n%v\n%v\n", err, desc(), usage())
 	}
+	utils.SetSentinel(cfg.Sentinel)
 
 	switch cfg.Cmd() {
 	case CMD_HELP:
@@ -43,14 +55,17 @@ func main() {
 			for i, n := range encrypts.List(0) {
 				a := encrypts.Get(n)
 				if a.Type() {
-					fmt.Printf(" %2v sym  %v\n", i+1, n)
+					fmt.Printf(" %2v sym  %v%v\n", i+1, n, aliases(encrypts.Aliases(n)))
 				} else {
-					fmt.Printf(" %2v asym %v\n", i+1, n)
+					fmt.Printf(" %2v asym %v%v\n", i+1, n, aliases(encrypts.Aliases(n)))
 				}
 			}
 			return
 		}
 
+		if err = useKeystore(cfg, cfg.Cmd() == CMD_DECRYPT); err != nil {
+			log.Fatalf("[MAIN]%v", err)
+		}
 		algr := encrypts.Get(encrypts.Parse(cfg.Algr))
 		if algr == nil {
 			log.Fatalf("[MAIN] unsupported algorithm '%v'", cfg.Algr)
@@ -62,10 +77,16 @@ func main() {
 		enca := encodes.Get(encodes.Parse(cfg.Enca))
 		enco := encodes.Get(encodes.Parse(cfg.Enco))
 		enck := encodes.Get(encodes.Parse(cfg.Enck))
-		zip := encodes.Get(encodes.Parse(cfg.Zip))
+		var zip encodes.Compressor
+		if cfg.Zip != "" {
+			zip, err = compressor(cfg.Zip, cfg.Level)
+			if err != nil {
+				log.Fatalf("[MAIN]%v", err)
+			}
+		}
 
 		switch cfg.Format {
-		case FORMAT_YAML:
+		case FORMAT_YAML, FORMAT_JSON:
 			if cfg.Cmd() == CMD_ENCRYPT {
 				if enco == nil {
 					log.Fatalf("[MAIN] unsupported output encoding '%v'", cfg.Enco)
@@ -77,10 +98,62 @@ func main() {
 				}
 				err = yamlDecrypt(cfg, algr, enci, enck, encv, enct, enca)
 			}
-		case FORMAT_JSON:
-			// TODO HERE!!! add json value encryption!
-		default:
+		case FORMAT_CSV:
 			if cfg.Cmd() == CMD_ENCRYPT {
+				if enco == nil {
+					log.Fatalf("[MAIN] unsupported output encoding '%v'", cfg.Enco)
+				}
+				err = csvEncrypt(cfg, algr, enco, enck, encv, enca)
+			} else {
+				if enci == nil {
+					log.Fatalf("[MAIN] unsupported input encoding '%v'", cfg.Encd)
+				}
+				err = csvDecrypt(cfg, algr, enci, enck, encv, enct, enca)
+			}
+		case FORMAT_JSONL:
+			if cfg.Cmd() == CMD_ENCRYPT {
+				if enco == nil {
+					log.Fatalf("[MAIN] unsupported output encoding '%v'", cfg.Enco)
+				}
+				err = jsonlEncrypt(cfg, algr, enco, enck, encv, enca)
+			} else {
+				if enci == nil {
+					log.Fatalf("[MAIN] unsupported input encoding '%v'", cfg.Encd)
+				}
+				err = jsonlDecrypt(cfg, algr, enci, enck, encv, enct, enca)
+			}
+		case FORMAT_K8S_SECRET:
+			if cfg.Cmd() == CMD_ENCRYPT {
+				if enco == nil {
+					log.Fatalf("[MAIN] unsupported output encoding '%v'", cfg.Enco)
+				}
+				err = k8sEncrypt(cfg, algr, enco, enck, encv, enca)
+			} else {
+				if enci == nil {
+					log.Fatalf("[MAIN] unsupported input encoding '%v'", cfg.Encd)
+				}
+				err = k8sDecrypt(cfg, algr, enci, enck, encv, enct, enca)
+			}
+		case FORMAT_DOTENV, FORMAT_INI, FORMAT_PROPERTIES, FORMAT_TOML:
+			if cfg.Cmd() == CMD_ENCRYPT {
+				if enco == nil {
+					log.Fatalf("[MAIN] unsupported output encoding '%v'", cfg.Enco)
+				}
+				err = linesEncrypt(cfg, algr, enco, enck, encv, enca)
+			} else {
+				if enci == nil {
+					log.Fatalf("[MAIN] unsupported input encoding '%v'", cfg.Encd)
+				}
+				err = linesDecrypt(cfg, algr, enci, enck, encv, enct, enca)
+			}
+		default:
+			if isDir(cfg.Input) {
+				if cfg.Cmd() == CMD_ENCRYPT {
+					err = encryptDir(cfg, algr, enci, enco, enck, zip)
+				} else {
+					err = decryptDir(cfg, algr, enci, enco, enck, zip)
+				}
+			} else if cfg.Cmd() == CMD_ENCRYPT {
 				err = encrypt(cfg, algr, enci, enco, enck, encv, enca, zip)
 			} else {
 				err = decrypt(cfg, algr, enci, enco, enck, encv, enct, enca, zip)
@@ -90,25 +163,98 @@ func main() {
 			fmt.Printf("\n%v [%v] finished:\n%v\n", time.Now().Format(LOG_FRM_MILLI), desc(), cfg)
 		}
 
+	case CMD_REKEY:
+		err = validate(cfg)
+		if err != nil {
+			log.Fatalf("[MAIN]%v", err)
+		}
+
+		enci := encodes.Get(encodes.Parse(cfg.Encd))
+		enco := encodes.Get(encodes.Parse(cfg.Enco))
+		enck := encodes.Get(encodes.Parse(cfg.Enck))
+		if isNodes(cfg.Format) && (enci == nil || enco == nil) {
+			log.Fatalf("[MAIN] unsupported encoding '%v' / '%v'", cfg.Encd, cfg.Enco)
+		}
+		err = rekey(cfg, enci, enco, enck)
+		if cfg.Verbose {
+			fmt.Printf("\n%v [%v] finished:\n%v\n", time.Now().Format(LOG_FRM_MILLI), desc(), cfg)
+		}
+
+	case CMD_EXEC:
+		err = validate(cfg)
+		if err != nil {
+			log.Fatalf("[MAIN]%v", err)
+		}
+
+		if err = useKeystore(cfg, true); err != nil {
+			log.Fatalf("[MAIN]%v", err)
+		}
+		algr := encrypts.Get(encrypts.Parse(cfg.Algr))
+		if algr == nil {
+			log.Fatalf("[MAIN] unsupported algorithm '%v'", cfg.Algr)
+		}
+		enci := encodes.Get(encodes.Parse(cfg.Encd))
+		if enci == nil {
+			log.Fatalf("[MAIN] unsupported input encoding '%v'", cfg.Encd)
+		}
+		var code int
+		code, err = execute(cfg, algr, enci,
+			encodes.Get(encodes.Parse(cfg.Enck)),
+			encodes.Get(encodes.Parse(cfg.Encv)),
+			encodes.Get(encodes.Parse(cfg.Enct)),
+			encodes.Get(encodes.Parse(cfg.Enca)),
+		)
+		if err == nil {
+			os.Exit(code)
+		}
+
 	case CMD_ARCHIVE:
+		err = validate(cfg)
+		if err != nil {
+			log.Fatalf("[MAIN]%v", err)
+		}
+
 		if cfg.IsList() {
-			i := 1
-			fmt.Println(desc())
-			for _, n := range encodes.List() {
-				c := encodes.Get(n)
-				t := c.Type()
-				if t == 0 {
-					continue
-				} else if t > 0 {
-					fmt.Printf(" %2v compress   %v\n", i, n)
-				} else {
-					fmt.Printf(" %2v decompress %v\n", i, n)
+			listCompressors()
+			listArchivers()
+			return
+		}
+
+		var cmp encodes.Compressor
+		if cfg.Format == "" || cfg.Encd != "" {
+			cmp, err = compressor(cfg.Encd, cfg.Level)
+			if err != nil {
+				log.Fatalf("[MAIN]%v", err)
+			}
+		}
+		if cfg.Format == "" {
+			err = archive(cfg, cmp)
+		} else {
+			arc := archives.Get(archives.Parse(cfg.Format))
+			if arc == nil {
+				log.Fatalf("[MAIN] unsupported archive format '%v'", cfg.Format)
+			}
+			if err = useKeystore(cfg, cfg.Extract); err != nil {
+				log.Fatalf("[MAIN]%v", err)
+			}
+			var algr encrypts.Algorithm
+			if cfg.Algr != "" {
+				algr = encrypts.Get(encrypts.Parse(cfg.Algr))
+				if algr == nil {
+					log.Fatalf("[MAIN] unsupported algorithm '%v'", cfg.Algr)
 				}
-				i++
 			}
-			return
+			enck := encodes.Get(encodes.Parse(cfg.Enck))
+			if cfg.Extract {
+				err = unpack(cfg, arc, cmp, algr, enck)
+			} else {
+				err = pack(cfg, arc, cmp, algr, enck)
+			}
 		}
-		fallthrough
+		if cfg.Verbose {
+			fmt.Printf("\n%v [%v] finished:\n%v\n", time.Now().Format(LOG_FRM_MILLI), desc(), cfg)
+		}
+
 	case CMD_ENCODE:
 		fallthrough
 	case CMD_DECODE:
@@ -118,17 +264,9 @@ func main() {
 		}
 
 		if cfg.IsList() {
-			i := 1
 			fmt.Println(desc())
-			for _, n := range encodes.List() {
-				c := encodes.Get(n)
-				t := c.Type()
-				if t == 0 {
-					fmt.Printf(" %2v %v\n", i, n)
-					i++
-				} else {
-					continue
-				}
+			for i, n := range encodes.List() {
+				fmt.Printf(" %2v %v%v\n", i+1, n, aliases(encodes.Aliases(n)))
 			}
 			return
 		}
@@ -138,8 +276,6 @@ func main() {
 			log.Fatalf("[MAIN] unsupported encoding '%v'", cfg.Encd)
 		}
 		switch cfg.Cmd() {
-		case CMD_ARCHIVE: // don't care encode or decode for archiving
-			fallthrough
 		case CMD_ENCODE:
 			err = encode(cfg, encd)
 		case CMD_DECODE:
@@ -173,7 +309,7 @@ func main() {
 		if cfg.IsList() {
 			fmt.Println(desc())
 			for i, n := range hashes.List() {
-				fmt.Printf(" %2v %v\n", i+1, n)
+				fmt.Printf(" %2v %v%v\n", i+1, n, aliases(hashes.Aliases(n)))
 			}
 			return
 		}
@@ -187,6 +323,16 @@ func main() {
 			fmt.Printf("\n%v [%v] finished:\n%v\n", time.Now().Format(LOG_FRM_MILLI), desc(), cfg)
 		}
 
+	case CMD_SERVE:
+		err = validate(cfg)
+		if err != nil {
+			log.Fatalf("[MAIN]%v", err)
+		}
+		err = serve(cfg)
+		if cfg.Verbose {
+			fmt.Printf("\n%v [%v] finished:\n%v\n", time.Now().Format(LOG_FRM_MILLI), desc(), cfg)
+		}
+
 	default:
 		err = fmt.Errorf(" unsupported command '%v'", cfg.Cmd())
 	}
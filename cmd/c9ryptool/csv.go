@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+// csvEncrypt encrypt the cells of the columns given by '--columns' in the CSV input, streamed row by row. Each cell
+// is bound to the index of its column as AAD, whether the column is given by name or by index, so the rows may be
+// sorted or filtered but the cells cannot be moved to other columns. A CSV file has no place for any file-level
+// metadata, so no MAC of the entire file is kept, and the salt is kept in each cell, e.g.
+// 'ENC[ALGR,data:...,type:str,salt:...]'. Empty cells are kept empty.
+func csvEncrypt(
+	cfg *cfgs.Config,
+	alg encrypts.Algorithm,
+	eco, eck, ecv, eca encodes.Encoding,
+) (err error) {
+	var salt, aad []byte
+
+	if salt, err = populateKey(cfg, alg, eck, nil, false); err != nil {
+		return fmt.Errorf("[CSV][ECY]%v", err)
+	}
+	if cfg.Aad != "" {
+		if aad, err = utils.Read(cfg.Aad, cfg.Buffer, eca); err != nil {
+			return fmt.Errorf("[CSV][ECY][AAD]%v", err)
+		}
+	}
+
+	av2 := encrypts.V2(alg)
+	err = csvStream(cfg, func(col string, cell *string) error {
+		rst, err := av2.Encrypt(encrypts.EncryptRequest{Plaintext: []byte(*cell), AAD: fieldAAD(av2, aad, 0, []string{col})})
+		if err != nil {
+			return err
+		}
+		*cell = saltedValue(encValue(alg, eco.EncodeToString(rst.Output), "str"), salt, eco)
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("[CSV][ECY]%v", err)
+	}
+	return
+}
+
+// csvDecrypt decrypt the cells of the columns given by '--columns' in the CSV input encrypted by csvEncrypt(). The
+// key is generated once for each salt found in the cells if a password is given.
+func csvDecrypt(
+	cfg *cfgs.Config,
+	alg encrypts.Algorithm,
+	eci, eck, ecv, ect, eca encodes.Encoding,
+) (err error) {
+	var aad []byte
+
+	keys, err := newSaltedKeys(cfg, alg, eci, eck)
+	if err != nil {
+		return fmt.Errorf("[CSV][DCY]%v", err)
+	}
+	if cfg.Aad != "" {
+		if aad, err = utils.Read(cfg.Aad, cfg.Buffer, eca); err != nil {
+			return fmt.Errorf("[CSV][DCY][AAD]%v", err)
+		}
+	}
+
+	av2 := encrypts.V2(alg)
+	err = csvStream(cfg, func(col string, cell *string) error {
+		label, data, _, wrapped := parseEncValue(*cell)
+		if !wrapped {
+			return fmt.Errorf("[ALG] value not encrypted")
+		} else if label != encLabel(alg) {
+			return fmt.Errorf("[ALG] value encrypted by '%v', not '%v'", label, encLabel(alg))
+		}
+
+		if err := keys.populate(*cell); err != nil {
+			return err
+		}
+
+		enc, err := eci.DecodeString(data)
+		if err != nil {
+			return err
+		}
+		dec, err := av2.Decrypt(encrypts.DecryptRequest{Ciphertext: enc, AAD: fieldAAD(av2, aad, 0, []string{col})})
+		if err != nil {
+			return err
+		}
+		*cell = string(dec.Plaintext)
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("[CSV][DCY]%v", err)
+	}
+	return
+}
+
+// csvStream read the CSV input row by row, call 'action' with the column index and each non-empty cell of the
+// selected columns, and write the rows to the output. The first row is the header, kept as is, unless '--no-header'.
+func csvStream(cfg *cfgs.Config, action func(string, *string) error) (err error) {
+	inp, out, done, err := openStreams(cfg)
+	defer done()
+	if err != nil {
+		return
+	}
+
+	hasHeader := !cfg.NoHeader
+
+	rdr := csv.NewReader(inp)
+	rdr.FieldsPerRecord, rdr.ReuseRecord = -1, true
+	wtr := csv.NewWriter(out)
+	cols, names := make([]int, 0), make([]string, 0)
+	for row := 0; ; row++ {
+		rec, err := rdr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("[UNM] %v", err)
+		}
+
+		if row == 0 {
+			if cols, names, err = csvColumns(cfg.Columns, rec, hasHeader); err != nil {
+				return err
+			}
+		}
+		if row > 0 || !hasHeader {
+			for i, c := range cols {
+				if c >= len(rec) || rec[c] == "" {
+					continue
+				}
+				if err = action(strconv.Itoa(c), &rec[c]); err != nil {
+					line, _ := rdr.FieldPos(c)
+					return fmt.Errorf("[NAV] line %v, column '%v': %v", line, names[i], err)
+				}
+			}
+		}
+		if err = wtr.Write(rec); err != nil {
+			return fmt.Errorf("[OUT] %v", err)
+		}
+	}
+	wtr.Flush()
+	if err = wtr.Error(); err != nil {
+		err = fmt.Errorf("[OUT] %v", err)
+	}
+	return
+}
+
+// csvColumns the indices and the names of the columns, which are the headers if 'hasHeader', or the indices. The
+// names are for reporting errors only.
+func csvColumns(columns, header []string, hasHeader bool) (cols []int, names []string, err error) {
+	for _, c := range columns {
+		c = strings.TrimSpace(c)
+		idx, err := strconv.Atoi(c)
+		if err != nil {
+			if idx = -1; hasHeader {
+				for i, h := range header {
+					if strings.TrimSpace(h) == c {
+						idx = i
+						break
+					}
+				}
+			}
+			if idx < 0 {
+				return nil, nil, fmt.Errorf("[COL] column '%v' not found in the header", c)
+			}
+		} else if idx < 0 {
+			return nil, nil, fmt.Errorf("[COL] invalid column index %v", idx)
+		}
+		if hasHeader && idx < len(header) {
+			names = append(names, strings.TrimSpace(header[idx]))
+		} else {
+			names = append(names, strconv.Itoa(idx))
+		}
+		cols = append(cols, idx)
+	}
+	return
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/encrypts/sym"
+)
+
+const cSVTEST = "id,email,ssn\n1,a@b.c,123-45-6789\n2,,987-65-4321\n"
+
+// csvTestRun encrypt 'input' by the columns 'enc', then decrypt it by the columns 'dec', returning the rows of
+// the encrypted and the decrypted outputs
+func csvTestRun(t *testing.T, cfg *cfgs.Config, input string, enc, dec []string) (encd, decd [][]string, err error) {
+	name, b64 := "AES-256-GCM", encodes.Get("base64")
+	dir := t.TempDir()
+	cfg.Input, cfg.Output = filepath.Join(dir, "clr.csv"), filepath.Join(dir, "enc.csv")
+	if err = os.WriteFile(cfg.Input, []byte(input), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Passwd == "" {
+		cfg.Key, cfg.Genkey = filepath.Join(dir, "csv.key"), true
+	}
+	cfg.Columns = enc
+	if err = csvEncrypt(cfg, encrypts.New(name), b64, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if encd, err = csvTestRows(cfg.Output); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Genkey, cfg.Columns = false, dec
+	cfg.Input, cfg.Output = cfg.Output, filepath.Join(dir, "dec.csv")
+	if err = csvDecrypt(cfg, encrypts.New(name), b64, nil, nil, nil, nil); err != nil {
+		return
+	}
+	decd, err = csvTestRows(cfg.Output)
+	return
+}
+
+// csvTestRows the rows of a CSV file
+func csvTestRows(path string) ([][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return csv.NewReader(f).ReadAll()
+}
+
+func TestCsvColumns(t *testing.T) {
+	header := []string{"id", " email ", "ssn"}
+	tests := []struct {
+		columns   []string
+		hasHeader bool
+		cols      []int
+		names     []string
+	}{
+		{[]string{"email", " 2"}, true, []int{1, 2}, []string{"email", "ssn"}},
+		{[]string{"1", "5"}, true, []int{1, 5}, []string{"email", "5"}},
+		{[]string{"1"}, false, []int{1}, []string{"1"}},
+	}
+	for _, tt := range tests {
+		cols, names, err := csvColumns(tt.columns, header, tt.hasHeader)
+		if err != nil || !reflect.DeepEqual(cols, tt.cols) || !reflect.DeepEqual(names, tt.names) {
+			t.Fatalf("TestCsvColumns() %v unexpected columns %v %v %v", tt.columns, cols, names, err)
+		}
+	}
+
+	for _, tt := range []struct {
+		columns   []string
+		hasHeader bool
+	}{
+		{[]string{"phone"}, true},
+		{[]string{"email"}, false},
+		{[]string{"-1"}, true},
+	} {
+		if _, _, err := csvColumns(tt.columns, header, tt.hasHeader); err == nil {
+			t.Fatalf("TestCsvColumns() %v expecting error", tt.columns)
+		} else {
+			fmt.Printf("TestCsvColumns() %v %v\n", tt.columns, err)
+		}
+	}
+	fmt.Println("TestCsvColumns() test okay")
+}
+
+func TestCsvStream(t *testing.T) {
+	rows, _ := csv.NewReader(strings.NewReader(cSVTEST)).ReadAll()
+
+	// the header is kept, and the columns given by name or by index are the same
+	encd, decd, err := csvTestRun(t, &cfgs.Config{Buffer: cfgs.BUFFER}, cSVTEST, []string{"email", "ssn"}, []string{"1", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decd, rows) {
+		t.Fatalf("TestCsvStream() unexpected decrypted rows %v", decd)
+	}
+	if !reflect.DeepEqual(encd[0], rows[0]) || encd[1][0] != "1" || !strings.HasPrefix(encd[1][1], "ENC[") || encd[2][1] != "" {
+		t.Fatalf("TestCsvStream() unexpected encrypted rows %v", encd)
+	}
+
+	// the first row is data without any header
+	input := strings.Join(strings.Split(cSVTEST, "\n")[1:], "\n")
+	encd, decd, err = csvTestRun(t, &cfgs.Config{NoHeader: true, Buffer: cfgs.BUFFER}, input, []string{"2"}, []string{"2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decd, rows[1:]) || !strings.HasPrefix(encd[0][2], "ENC[") {
+		t.Fatalf("TestCsvStream() unexpected rows without header %v %v", encd, decd)
+	}
+
+	// the salt is kept in each cell
+	cfg := &cfgs.Config{Passwd: pWDTEST, SaltLen: sym.SALTLEN, Buffer: cfgs.BUFFER}
+	encd, decd, err = csvTestRun(t, cfg, cSVTEST, []string{"ssn"}, []string{"ssn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decd, rows) || !strings.Contains(encd[1][2], ",salt:") {
+		t.Fatalf("TestCsvStream() unexpected rows with password %v %v", encd, decd)
+	}
+	fmt.Println("TestCsvStream() test okay")
+}
+
+func TestCsvAad(t *testing.T) {
+	// the order of the columns does not matter
+	_, _, err := csvTestRun(t, &cfgs.Config{Buffer: cfgs.BUFFER}, cSVTEST, []string{"email", "ssn"}, []string{"2", "1"})
+	if err != nil {
+		t.Fatalf("TestCsvAad() columns given in other order: %v", err)
+	}
+	cfg := &cfgs.Config{Buffer: cfgs.BUFFER}
+	encd, _, err := csvTestRun(t, cfg, cSVTEST, []string{"email", "ssn"}, []string{"email", "ssn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// cells moved to other columns
+	encd[1][1], encd[1][2] = encd[1][2], encd[1][1]
+	f, err := os.Create(cfg.Input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wtr := csv.NewWriter(f)
+	wtr.WriteAll(encd)
+	f.Close()
+	err = csvDecrypt(cfg, encrypts.New("AES-256-GCM"), encodes.Get("base64"), nil, nil, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "[NAV] line 2") {
+		t.Fatalf("TestCsvAad() expecting error decrypting cells moved to other columns, got %v", err)
+	}
+	fmt.Printf("TestCsvAad() test okay: %v\n", err)
+}
@@ -100,14 +100,16 @@ func display(
 ) (err error) {
 	buf := make([]byte, 0)
 
-	inp := os.Stdin
+	inp := utils.Stdin()
 	if cfg.Input != "" {
-		inp, err = os.Open(cfg.Input)
+		var f *os.File
+		f, err = os.Open(cfg.Input)
 		if err != nil {
 			err = fmt.Errorf("[READ] %v", err)
 			return
 		}
-		defer inp.Close()
+		defer f.Close()
+		inp = f
 	}
 	rdr := bufio.NewReaderSize(inp, cfg.Buffer)
 	if rdr.Size() != cfg.Buffer {
@@ -7,20 +7,23 @@ import (
 
 	"sea9.org/go/c9ryptool/pkg/cfgs"
 	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/utils"
 )
 
 func encode(
 	cfg *cfgs.Config,
 	ecd encodes.Encoding,
 ) (err error) {
-	inp := os.Stdin
+	inp := utils.Stdin()
 	if cfg.Input != "" {
-		inp, err = os.Open(cfg.Input)
+		var f *os.File
+		f, err = os.Open(cfg.Input)
 		if err != nil {
 			err = fmt.Errorf("[READ] %v", err)
 			return
 		}
-		defer inp.Close()
+		defer f.Close()
+		inp = f
 	}
 	rdr := bufio.NewReaderSize(inp, cfg.Buffer)
 	if rdr.Size() != cfg.Buffer {
@@ -55,14 +58,16 @@ func decode(
 	cfg *cfgs.Config,
 	ecd encodes.Encoding,
 ) (err error) {
-	inp := os.Stdin
+	inp := utils.Stdin()
 	if cfg.Input != "" {
-		inp, err = os.Open(cfg.Input)
+		var f *os.File
+		f, err = os.Open(cfg.Input)
 		if err != nil {
 			err = fmt.Errorf("[READ] %v", err)
 			return
 		}
-		defer inp.Close()
+		defer f.Close()
+		inp = f
 	}
 	rdr := bufio.NewReaderSize(inp, cfg.Buffer)
 	if rdr.Size() != cfg.Buffer {
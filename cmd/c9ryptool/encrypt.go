@@ -2,83 +2,47 @@ package main
 
 import (
 	"fmt"
-	"time"
 
+	"sea9.org/go/c9ryptool/pkg/agent"
 	"sea9.org/go/c9ryptool/pkg/cfgs"
 	"sea9.org/go/c9ryptool/pkg/encodes"
 	"sea9.org/go/c9ryptool/pkg/encrypts"
-	"sea9.org/go/c9ryptool/pkg/encrypts/sym"
 	"sea9.org/go/c9ryptool/pkg/utils"
 )
 
 func encrypt(
 	cfg *cfgs.Config,
 	alg encrypts.Algorithm,
-	eci, eco, eck, ecv, eca, zip encodes.Encoding,
+	eci, eco, eck, ecv, eca encodes.Encoding,
+	zip encodes.Compressor,
 ) (err error) {
-	var results [][]byte
-	var key, input, result, salt, iv, aad []byte
+	var rst encrypts.EncryptResult
+	var input, result, salt, hdr, iv, aad []byte
 
-	input, err = utils.Read(cfg.Input, cfg.Buffer, eci, zip) // decode, then zip before encrypt
+	input, err = utils.Read(cfg.Input, cfg.Buffer, eci)
 	if err != nil {
 		err = fmt.Errorf("[ECY][INP]%v", err)
 		return
 	}
 
-	if cfg.Passwd != "" {
-		pwd := cfg.Passwd
-		if cfg.Passwd == PWD_INTERACTIVE {
-			hdr := ""
-			if cfg.Verbose {
-				hdr = fmt.Sprintf("%v [%v]", time.Now().Format(LOG_FRM_MILLI), desc())
-			}
-			pwd, err = utils.Prompt(hdr, "Enter password: ")
-		}
-		salt, err = sym.PopulateKeyFromPassword(
-			pwd,
-			nil,
-			alg.KeyLength(), cfg.SaltLen,
-			alg.PopulateKey,
-		)
-		if err != nil {
-			err = fmt.Errorf("[ECY][PWD]%v", err)
-			return
-		}
-	} else if cfg.Genkey {
-		err = alg.PopulateKey(nil)
-		if err != nil {
-			err = fmt.Errorf("[ECY][GEN]%v", err)
-			return
-		}
-		if eck == nil || !alg.Type() { // since asymmetric keys uses PEM encoding
-			err = utils.Write(cfg.Key, alg.GetKey())
-		} else {
-			err = utils.Write(cfg.Key, alg.GetKey(), eck)
-		}
-		if err != nil {
-			return
-		}
-	} else {
-		if eck == nil || !alg.Type() {
-			key, err = utils.Read(cfg.Key, cfg.Buffer)
-			if err != nil {
-				err = fmt.Errorf("[ECY][KEY]%v", err)
-				return
-			}
-		} else {
-			key, err = utils.Read(cfg.Key, cfg.Buffer, eck)
-			if err != nil {
-				err = fmt.Errorf("[ECY][KEY]%v", err)
-				return
-			}
-		}
-		err = alg.PopulateKey(key)
+	if zip != nil { // decode, then zip before encrypt
+		input, err = encodes.Compress(zip, input)
 		if err != nil {
-			err = fmt.Errorf("[ECY][POP]%v", err)
+			err = fmt.Errorf("[ECY][ZIP]%v", err)
 			return
 		}
 	}
 
+	if cfg.Kms != "" {
+		hdr, err = sealKey(cfg, alg)
+	} else if cfg.Agent == "" { // the key is kept by the agent otherwise
+		salt, err = populateKey(cfg, alg, eck, nil, false)
+	}
+	if err != nil {
+		err = fmt.Errorf("[ECY]%v", err)
+		return
+	}
+
 	if cfg.Iv != "" {
 		iv, err = utils.Read(cfg.Iv, cfg.Buffer, ecv)
 		if err != nil {
@@ -95,20 +59,23 @@ func encrypt(
 		}
 	}
 
-	results, err = alg.Encrypt(input, iv, aad)
+	if cfg.Agent != "" {
+		result, err = agent.NewClient(cfg.Socket).Encrypt(cfg.Agent, input, aad)
+	} else if rst, err = encrypts.V2(alg).Encrypt(encrypts.EncryptRequest{Plaintext: input, Nonce: iv, AAD: aad}); err == nil {
+		result = rst.Output
+	}
 	if err != nil {
 		err = fmt.Errorf("[ECY]%v", err)
 		return
-	} else if len(results) < 1 || results[0] == nil {
-		err = fmt.Errorf("[ECY] result missing")
-		return
 	}
 
-	result = results[0]
 	if salt != nil {
 		result = append(result, salt...)
 	}
-	err = utils.Write(cfg.Output, result, eco)
+	if hdr != nil {
+		result = append(hdr, result...)
+	}
+	err = writeOutput(cfg, result, eco)
 	if err != nil {
 		err = fmt.Errorf("[ECY][OUT]%v", err)
 	}
@@ -118,10 +85,11 @@ func encrypt(
 func decrypt(
 	cfg *cfgs.Config,
 	alg encrypts.Algorithm,
-	eci, eco, eck, ecv, ect, eca, unzip encodes.Encoding,
+	eci, eco, eck, ecv, ect, eca encodes.Encoding,
+	unzip encodes.Compressor,
 ) (err error) {
-	var results [][]byte
-	var key, input, result, salt, iv, tag, aad []byte
+	var rst encrypts.DecryptResult
+	var input, result, salt, iv, tag, aad []byte
 
 	input, err = utils.Read(cfg.Input, cfg.Buffer, eci)
 	if err != nil {
@@ -129,47 +97,14 @@ func decrypt(
 		return
 	}
 
-	if cfg.Passwd != "" {
-		pwd := cfg.Passwd
-		if cfg.Passwd == PWD_INTERACTIVE {
-			hdr := ""
-			if cfg.Verbose {
-				hdr = fmt.Sprintf("%v [%v]", time.Now().Format(LOG_FRM_MILLI), desc())
-			}
-			pwd, err = utils.Prompt(hdr, "Enter password: ")
-		}
-		salt, err = sym.PopulateKeyFromPassword(
-			pwd,
-			input,
-			alg.KeyLength(), cfg.SaltLen,
-			alg.PopulateKey,
-		)
-		if err != nil {
-			err = fmt.Errorf("[DCY][PWD]%v", err)
-			return
-		}
-	} else if cfg.Genkey {
-		err = fmt.Errorf("[DCY][GEN] generate new key for decryption makes no sense")
+	if cfg.Kms != "" {
+		input, err = unsealKey(cfg, alg, input)
+	} else if cfg.Agent == "" {
+		salt, err = populateKey(cfg, alg, eck, input, true)
+	}
+	if err != nil {
+		err = fmt.Errorf("[DCY]%v", err)
 		return
-	} else {
-		if eck == nil || !alg.Type() {
-			key, err = utils.Read(cfg.Key, cfg.Buffer)
-			if err != nil {
-				err = fmt.Errorf("[DCY][KEY]%v", err)
-				return
-			}
-		} else {
-			key, err = utils.Read(cfg.Key, cfg.Buffer, eck)
-			if err != nil {
-				err = fmt.Errorf("[DCY][KEY]%v", err)
-				return
-			}
-		}
-		err = alg.PopulateKey(key)
-		if err != nil {
-			err = fmt.Errorf("[DCY][POP]%v", err)
-			return
-		}
 	}
 
 	if cfg.Iv != "" {
@@ -196,23 +131,43 @@ func decrypt(
 		}
 	}
 
-	if salt != nil {
-		results, err = alg.Decrypt(input[:len(input)-len(salt)], iv, tag, aad)
-	} else {
-		results, err = alg.Decrypt(input, iv, tag, aad)
+	if cfg.Agent != "" {
+		result, err = agent.NewClient(cfg.Socket).Decrypt(cfg.Agent, input, aad)
+	} else if rst, err = encrypts.V2(alg).Decrypt(encrypts.DecryptRequest{
+		Ciphertext: input[:len(input)-len(salt)],
+		Nonce:      iv,
+		Tag:        tag,
+		AAD:        aad,
+	}); err == nil {
+		result = rst.Plaintext
 	}
 	if err != nil {
 		err = fmt.Errorf("[DCY]%v", err)
 		return
-	} else if len(results) < 1 || results[0] == nil {
-		err = fmt.Errorf("[DCY] result missing")
-		return
 	}
 
-	result = results[0]
-	err = utils.Write(cfg.Output, result, unzip, eco)
+	if unzip != nil { // unzip after decrypt, then encode
+		result, err = encodes.Decompress(unzip, result)
+		if err != nil {
+			err = fmt.Errorf("[DCY][ZIP]%v", err)
+			return
+		}
+	}
+	err = writeOutput(cfg, result, eco)
 	if err != nil {
 		err = fmt.Errorf("[DCY][OUT]%v", err)
 	}
 	return
 }
+
+// writeOutput write the result to the output file, or replace the input file if '--in-place' is specified
+func writeOutput(cfg *cfgs.Config, dat []byte, encr ...utils.Encoder) error {
+	if cfg.InPlace {
+		bak := ""
+		if cfg.Backup {
+			bak = cfg.Input + ".bak"
+		}
+		return utils.Replace(cfg.Input, bak, dat, encr...)
+	}
+	return utils.Write(cfg.Output, dat, encr...)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sea9.org/go/c9ryptool/pkg/agent"
+	"sea9.org/go/c9ryptool/pkg/c9crypt"
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/encrypts/sym"
+)
+
+const pWDTEST = "secret"
+
+// encryptTestConfig config of password-based encryption from 'input' to 'output' in 'dir'
+func encryptTestConfig(dir, input, output string) *cfgs.Config {
+	return &cfgs.Config{
+		Input:   filepath.Join(dir, input),
+		Output:  filepath.Join(dir, output),
+		Passwd:  pWDTEST,
+		SaltLen: sym.SALTLEN,
+		Buffer:  cfgs.BUFFER,
+	}
+}
+
+func TestPassword(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "clr.txt"), []byte("top secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// command line to library
+	cfg := encryptTestConfig(dir, "clr.txt", "enc.bin")
+	if err := encrypt(cfg, encrypts.New(encrypts.Default()), nil, nil, nil, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	enc, err := os.ReadFile(cfg.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err = c9crypt.Decrypt(ctx, bytes.NewReader(enc), &out, c9crypt.Options{Password: pWDTEST}); err != nil {
+		t.Fatalf("TestPassword() decrypting command line output by the library: %v", err)
+	} else if out.String() != "top secret" {
+		t.Fatalf("TestPassword() unexpected result '%v'", out.String())
+	}
+	out.Reset()
+	if err = c9crypt.Decrypt(ctx, bytes.NewReader(enc), &out, c9crypt.Options{Password: pWDTEST[:len(pWDTEST)-1]}); err == nil {
+		t.Fatal("TestPassword() decryption without the last password character should fail")
+	}
+
+	// library to command line
+	out.Reset()
+	if err = c9crypt.Encrypt(ctx, bytes.NewReader([]byte("top secret")), &out, c9crypt.Options{Password: pWDTEST}); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "lib.bin"), out.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg = encryptTestConfig(dir, "lib.bin", "lib.txt")
+	if err = decrypt(cfg, encrypts.New(encrypts.Default()), nil, nil, nil, nil, nil, nil, nil); err != nil {
+		t.Fatalf("TestPassword() decrypting library output by the command line: %v", err)
+	}
+	if clr, err := os.ReadFile(cfg.Output); err != nil {
+		t.Fatal(err)
+	} else if string(clr) != "top secret" {
+		t.Fatalf("TestPassword() unexpected result '%v'", string(clr))
+	}
+	fmt.Println("TestPassword() test okay")
+}
+
+func TestAgentPassword(t *testing.T) {
+	// unix socket paths are limited in length, t.TempDir() may be too long
+	sock, err := os.MkdirTemp("", "c9a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(sock) })
+	sock = filepath.Join(sock, "agent.sock")
+	lsnr, err := agent.Listen(sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agt := agent.New(0)
+	go agt.Serve(lsnr)
+	t.Cleanup(agt.Stop)
+	if err = agent.NewClient(sock).Add("pwd", encrypts.Default(), nil, pWDTEST, sym.SALTLEN, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err = os.WriteFile(filepath.Join(dir, "clr.txt"), []byte("top secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range []struct {
+		input, output string
+		agent, enc    bool
+	}{
+		{"clr.txt", "cli.bin", false, true}, // command line to agent
+		{"cli.bin", "cli.txt", true, false},
+		{"clr.txt", "agt.bin", true, true}, // agent to command line
+		{"agt.bin", "agt.txt", false, false},
+	} {
+		cfg := encryptTestConfig(dir, step.input, step.output)
+		if step.agent {
+			cfg.Passwd, cfg.Agent, cfg.Socket = "", "pwd", sock
+		}
+		if step.enc {
+			err = encrypt(cfg, encrypts.New(encrypts.Default()), nil, nil, nil, nil, nil, nil)
+		} else {
+			err = decrypt(cfg, encrypts.New(encrypts.Default()), nil, nil, nil, nil, nil, nil, nil)
+		}
+		if err != nil {
+			t.Fatalf("TestAgentPassword() %v to %v: %v", step.input, step.output, err)
+		}
+	}
+	for _, name := range []string{"cli.txt", "agt.txt"} {
+		if clr, err := os.ReadFile(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		} else if string(clr) != "top secret" {
+			t.Fatalf("TestAgentPassword() unexpected result '%v' of %v", string(clr), name)
+		}
+	}
+	fmt.Println("TestAgentPassword() test okay")
+}
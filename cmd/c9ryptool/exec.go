@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+// SEPARATOR default separator of the nested keys in the environment variable names of 'exec'
+const SEPARATOR = "_"
+
+// execute decrypt the yaml input in memory, and run the child process with the decrypted values as environment
+// variables. Signals are forwarded to the child process, and its exit code is returned.
+func execute(
+	cfg *cfgs.Config,
+	alg encrypts.Algorithm,
+	eci, eck, ecv, ect, eca encodes.Encoding,
+) (code int, err error) {
+	clr, err := yamlDecrypted(cfg, alg, eci, eck, ecv, ect, eca)
+	if err != nil {
+		return
+	}
+
+	env := os.Environ()
+	for _, doc := range clr {
+		paths, vals := utils.FlattenNode(doc)
+		for i, p := range paths {
+			name := envName(cfg.Prefix, cfg.Sep, p)
+			if cfg.Verbose {
+				fmt.Printf("Setting '%v'\n", name)
+			}
+			env = append(env, name+"="+vals[i]) // the last value of duplicated names is used
+		}
+	}
+
+	cmd := exec.Command(cfg.Exec[0], cfg.Exec[1:]...)
+	cmd.Env = env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	defer signal.Stop(sig)
+	if err = cmd.Start(); err != nil {
+		return 0, fmt.Errorf("[EXEC] %v", err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case s := <-sig:
+				cmd.Process.Signal(s)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err = cmd.Wait()
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		if ws, ok := ee.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			return 128 + int(ws.Signal()), nil
+		}
+		return ee.ExitCode(), nil
+	} else if err != nil {
+		return 0, fmt.Errorf("[EXEC] %v", err)
+	}
+	return
+}
+
+// envName environment variable name of a key path, upper-cased, with characters other than letters, digits and
+// '_' in the keys replaced by '_'
+func envName(prefix, sep string, path []string) string {
+	keys := make([]string, len(path))
+	for i, k := range path {
+		keys[i] = strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+				return r
+			}
+			return '_'
+		}, strings.ToUpper(k))
+	}
+	return prefix + strings.Join(keys, sep)
+}
@@ -8,20 +8,23 @@ import (
 
 	"sea9.org/go/c9ryptool/pkg/cfgs"
 	"sea9.org/go/c9ryptool/pkg/hashes"
+	"sea9.org/go/c9ryptool/pkg/utils"
 )
 
 func calcHash(
 	cfg *cfgs.Config,
 	hsh hash.Hash,
 ) (err error) {
-	inp := os.Stdin
+	inp := utils.Stdin()
 	if cfg.Input != "" {
-		inp, err = os.Open(cfg.Input)
+		var f *os.File
+		f, err = os.Open(cfg.Input)
 		if err != nil {
 			err = fmt.Errorf("[READ] %v", err)
 			return
 		}
-		defer inp.Close()
+		defer f.Close()
+		inp = f
 	}
 	rdr := bufio.NewReaderSize(inp, cfg.Buffer)
 	if rdr.Size() != cfg.Buffer {
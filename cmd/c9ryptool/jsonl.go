@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+// jsonlEncrypt encrypt the values selected by '--path' and '--encrypted-regex' in the JSON Lines input, streamed
+// record by record. The raw literals are encrypted, written as '"ENC[ALGR,data:...,type:TYPE]"' strings, and bound
+// to their key paths as AAD, so the records may be reordered or filtered. Same as CSV, the salt is kept in each value.
+func jsonlEncrypt(
+	cfg *cfgs.Config,
+	alg encrypts.Algorithm,
+	eco, eck, ecv, eca encodes.Encoding,
+) (err error) {
+	var salt, aad []byte
+
+	if salt, err = populateKey(cfg, alg, eck, nil, false); err != nil {
+		return fmt.Errorf("[JSONL][ECY]%v", err)
+	}
+	if cfg.Aad != "" {
+		if aad, err = utils.Read(cfg.Aad, cfg.Buffer, eca); err != nil {
+			return fmt.Errorf("[JSONL][ECY][AAD]%v", err)
+		}
+	}
+	sel, err := pathSelector(cfg)
+	if err != nil {
+		return fmt.Errorf("[JSONL][ECY]%v", err)
+	}
+
+	av2 := encrypts.V2(alg)
+	err = jsonlStream(cfg, func(path []string, val *string) error {
+		if !sel(path) {
+			return nil
+		}
+		rst, err := av2.Encrypt(encrypts.EncryptRequest{Plaintext: []byte(*val), AAD: fieldAAD(av2, aad, 0, path)})
+		if err != nil {
+			return err
+		}
+		*val = strconv.Quote(saltedValue(encValue(alg, eco.EncodeToString(rst.Output), utils.JsonType(*val)), salt, eco))
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("[JSONL][ECY]%v", err)
+	}
+	return
+}
+
+// jsonlDecrypt decrypt the values of the JSON Lines input encrypted by jsonlEncrypt(), the values not encrypted
+// are kept as is
+func jsonlDecrypt(
+	cfg *cfgs.Config,
+	alg encrypts.Algorithm,
+	eci, eck, ecv, ect, eca encodes.Encoding,
+) (err error) {
+	var aad []byte
+
+	keys, err := newSaltedKeys(cfg, alg, eci, eck)
+	if err != nil {
+		return fmt.Errorf("[JSONL][DCY]%v", err)
+	}
+	if cfg.Aad != "" {
+		if aad, err = utils.Read(cfg.Aad, cfg.Buffer, eca); err != nil {
+			return fmt.Errorf("[JSONL][DCY][AAD]%v", err)
+		}
+	}
+	sel, err := pathSelector(cfg)
+	if err != nil {
+		return fmt.Errorf("[JSONL][DCY]%v", err)
+	}
+
+	av2 := encrypts.V2(alg)
+	err = jsonlStream(cfg, func(path []string, val *string) error {
+		if !sel(path) || utils.JsonType(*val) != "str" {
+			return nil
+		}
+		var str string
+		if err := json.Unmarshal([]byte(*val), &str); err != nil {
+			return err
+		}
+		label, data, _, wrapped := parseEncValue(str)
+		if !wrapped {
+			return nil
+		} else if label != encLabel(alg) {
+			return fmt.Errorf("[ALG] value encrypted by '%v', not '%v'", label, encLabel(alg))
+		}
+		if err := keys.populate(str); err != nil {
+			return err
+		}
+
+		enc, err := eci.DecodeString(data)
+		if err != nil {
+			return err
+		}
+		dec, err := av2.Decrypt(encrypts.DecryptRequest{Ciphertext: enc, AAD: fieldAAD(av2, aad, 0, path)})
+		if err != nil {
+			return err
+		}
+		*val = string(dec.Plaintext)
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("[JSONL][DCY]%v", err)
+	}
+	return
+}
+
+// jsonlStream read the JSON Lines input line by line, call 'action' with the key path and the raw literal of each
+// value of the records, and write the records to the output. Blank lines are kept as is, and errors are reported
+// with the line numbers.
+func jsonlStream(cfg *cfgs.Config, action func([]string, *string) error) (err error) {
+	inp, out, done, err := openStreams(cfg)
+	defer done()
+	if err != nil {
+		return
+	}
+
+	rdr := bufio.NewReaderSize(inp, cfg.Buffer)
+	wtr := bufio.NewWriterSize(out, cfg.Buffer)
+	defer wtr.Flush() // the records before any failed one
+	for num := 1; ; num++ {
+		line, err := rdr.ReadBytes('\n')
+		if len(line) > 0 {
+			body := bytes.TrimRight(line, "\r\n")
+			if len(bytes.TrimSpace(body)) > 0 {
+				rec, err := utils.TraverseJson(body, action)
+				if err != nil {
+					return fmt.Errorf("[NAV] line %v: %v", num, err)
+				}
+				line = append(rec, line[len(body):]...)
+			}
+			if _, err := wtr.Write(line); err != nil {
+				return fmt.Errorf("[OUT] %v", err)
+			}
+			// pass the records on while waiting for the input, e.g. of log pipelines
+			if rdr.Buffered() <= 0 {
+				if err := wtr.Flush(); err != nil {
+					return fmt.Errorf("[OUT] %v", err)
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("[INP] line %v: %v", num, err)
+		}
+	}
+	if err = wtr.Flush(); err != nil {
+		err = fmt.Errorf("[OUT] %v", err)
+	}
+	return
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+var sECRETKEY = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+var sECRETNAME = regexp.MustCompile(`[^-.a-z0-9]+`)
+
+// k8sEncrypt turn the Kubernetes Secret manifests of the input into git-safe documents. The base64 'data' and the
+// 'stringData' are decoded into plain 'data' values, which are encrypted as YAML values, by default the values under
+// 'data' only. Binary values are kept in base64, tagged '!!binary'.
+func k8sEncrypt(
+	cfg *cfgs.Config,
+	alg encrypts.Algorithm,
+	eco, eck, ecv, eca encodes.Encoding,
+) (err error) {
+	input, err := utils.Read(cfg.Input, cfg.Buffer)
+	if err != nil {
+		return fmt.Errorf("[K8S][ECY][INP]%v", err)
+	}
+	docs, err := utils.ReadNodes(input)
+	if err != nil {
+		return fmt.Errorf("[K8S][ECY][UNM]%v", err)
+	}
+	for d, doc := range docs {
+		if err = secretDecoded(doc); err != nil {
+			return fmt.Errorf("[K8S][ECY] document %v: %v", d, err)
+		}
+	}
+
+	kcfg := *cfg
+	if len(kcfg.Paths) <= 0 && kcfg.Regex == "" {
+		kcfg.Paths = []string{"$.data"}
+	}
+	if docs, err = yamlEncrypted(&kcfg, alg, eco, eck, ecv, eca, docs); err != nil {
+		return
+	}
+
+	output, err := utils.WriteNodes(docs)
+	if err != nil {
+		return fmt.Errorf("[K8S][ECY][MRS]%v", err)
+	}
+	if err = writeOutput(cfg, output); err != nil {
+		err = fmt.Errorf("[K8S][ECY][OUT]%v", err)
+	}
+	return
+}
+
+// k8sDecrypt decrypt the yaml input in memory, and output a 'v1/Secret' manifest of each document with the values
+// encoded in base64. Documents encrypted by 'encrypt -f k8s-secret' keep their metadata, the values of the other
+// documents are flattened into the 'data' keys, e.g. 'db.password', of a Secret named after the input file.
+func k8sDecrypt(
+	cfg *cfgs.Config,
+	alg encrypts.Algorithm,
+	eci, eck, ecv, ect, eca encodes.Encoding,
+) (err error) {
+	clr, err := yamlDecrypted(cfg, alg, eci, eck, ecv, ect, eca)
+	if err != nil {
+		return
+	}
+
+	b64 := encodes.Get("base64")
+	for d, doc := range clr {
+		name := secretName(cfg.Input)
+		if d > 0 {
+			name = fmt.Sprintf("%v-%v", name, d)
+		}
+		if clr[d], err = secretEncoded(doc, name, b64); err != nil {
+			return fmt.Errorf("[K8S][DCY] document %v: %v", d, err)
+		}
+	}
+
+	output, err := utils.WriteNodes(clr)
+	if err != nil {
+		return fmt.Errorf("[K8S][DCY][MRS]%v", err)
+	}
+	if err = writeOutput(cfg, output); err != nil {
+		err = fmt.Errorf("[K8S][DCY][OUT]%v", err)
+	}
+	return
+}
+
+// isSecret 'true' if the root map is a Kubernetes Secret
+func isSecret(root *yaml.Node) bool {
+	kind := mapValue(root, "kind")
+	return kind != nil && kind.Value == "Secret"
+}
+
+// mapValue the value of 'key' in the map node, nil if not found
+func mapValue(root *yaml.Node, key string) *yaml.Node {
+	if root == nil {
+		return nil
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == key {
+			return root.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// secretDecoded decode the 'data' of a Secret manifest, and merge the 'stringData' into it
+func secretDecoded(doc *yaml.Node) error {
+	root := rootMap(doc)
+	if !isSecret(root) {
+		return fmt.Errorf("not a Kubernetes Secret manifest")
+	}
+	data := mapValue(root, "data")
+	if data == nil {
+		data = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "data"}, data)
+	} else if data.Kind == yaml.ScalarNode && data.ShortTag() == "!!null" {
+		*data = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"} // 'data:' without any value
+	} else if data.Kind != yaml.MappingNode {
+		return fmt.Errorf("[data] map expected")
+	}
+
+	b64 := encodes.Get("base64")
+	for i := 0; i+1 < len(data.Content); i += 2 {
+		val := data.Content[i+1]
+		if val.Kind != yaml.ScalarNode {
+			return fmt.Errorf("[data][%v] base64 value expected", data.Content[i].Value)
+		}
+		dec, err := b64.DecodeString(strings.Join(strings.Fields(val.Value), ""))
+		if err != nil {
+			return fmt.Errorf("[data][%v]%v", data.Content[i].Value, err)
+		}
+		if utf8.Valid(dec) {
+			data.Content[i+1] = plainValue(string(dec))
+		} else {
+			val.Tag, val.Style = "!!binary", 0
+		}
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "stringData" {
+			continue
+		}
+		sdata := root.Content[i+1]
+		for j := 0; j+1 < len(sdata.Content); j += 2 {
+			key, val := sdata.Content[j], plainValue(sdata.Content[j+1].Value)
+			if old := mapValue(data, key.Value); old != nil {
+				*old = *val // 'stringData' overrides 'data'
+			} else {
+				data.Content = append(data.Content, key, val)
+			}
+		}
+		root.Content = append(root.Content[:i], root.Content[i+2:]...)
+		break
+	}
+	return nil
+}
+
+// secretEncoded the Secret manifest of a decrypted document, with the values encoded in base64
+func secretEncoded(doc *yaml.Node, name string, b64 encodes.Encoding) (*yaml.Node, error) {
+	root := rootMap(doc)
+	if isSecret(root) {
+		data := mapValue(root, "data")
+		if data == nil {
+			return doc, nil
+		}
+		for i := 0; i+1 < len(data.Content); i += 2 {
+			val := data.Content[i+1]
+			switch {
+			case val.Kind != yaml.ScalarNode:
+				return nil, fmt.Errorf("[data][%v] scalar value expected", data.Content[i].Value)
+			case val.ShortTag() == "!!binary":
+				val.Value = strings.Join(strings.Fields(val.Value), "")
+			case val.ShortTag() == "!!null":
+				val.Value = ""
+			default:
+				val.Value = b64.EncodeToString([]byte(val.Value))
+			}
+			val.Tag, val.Style = "!!str", 0
+		}
+		return doc, nil
+	}
+
+	data := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	paths, vals := utils.FlattenNode(doc)
+	for i, p := range paths {
+		key := sECRETKEY.ReplaceAllString(strings.Join(p, "."), "_")
+		if mapValue(data, key) != nil {
+			return nil, fmt.Errorf("[%v] duplicated key", key)
+		}
+		data.Content = append(data.Content, plainValue(key), plainValue(b64.EncodeToString([]byte(vals[i]))))
+	}
+	meta := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{plainValue("name"), plainValue(name)}}
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{
+		Kind: yaml.MappingNode,
+		Tag:  "!!map",
+		Content: []*yaml.Node{
+			plainValue("apiVersion"), plainValue("v1"),
+			plainValue("kind"), plainValue("Secret"),
+			plainValue("metadata"), meta,
+			plainValue("type"), plainValue("Opaque"),
+			plainValue("data"), data,
+		},
+	}}}, nil
+}
+
+// plainValue a string node, multi-line strings are written as literal blocks
+func plainValue(val string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: val}
+	if strings.Contains(val, "\n") {
+		node.Style = yaml.LiteralStyle
+	}
+	return node
+}
+
+// secretName the name of a Secret after the input file, e.g. 'db-secrets' of 'db_secrets.enc.yaml'
+func secretName(input string) string {
+	name := filepath.Base(input)
+	if idx := strings.Index(name, "."); idx > 0 {
+		name = name[:idx]
+	}
+	name = strings.Trim(sECRETNAME.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+	if input == "" || name == "" {
+		return "secret"
+	}
+	return name
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+// "admin", "abcd1234" and the non-UTF-8 bytes 0xff 0xfe
+const sECRETTEST = `apiVersion: v1
+kind: Secret
+metadata:
+  name: db-secrets
+type: Opaque
+data:
+  user: YWRtaW4=
+  password: YWJjZDEyMzQ=
+  cert: //4=
+stringData:
+  password: wxyz5678
+  host: db.local
+`
+
+// k8sTestDoc the only document of 'input'
+func k8sTestDoc(t *testing.T, input string) *yaml.Node {
+	docs, err := utils.ReadNodes([]byte(input))
+	if err != nil || len(docs) != 1 {
+		t.Fatalf("unexpected documents %v %v", docs, err)
+	}
+	return docs[0]
+}
+
+func TestSecretDecoded(t *testing.T) {
+	doc := k8sTestDoc(t, sECRETTEST)
+	if err := secretDecoded(doc); err != nil {
+		t.Fatal(err)
+	}
+	root := rootMap(doc)
+	if mapValue(root, "stringData") != nil {
+		t.Fatal("TestSecretDecoded() 'stringData' not merged into 'data'")
+	}
+	vals := yamlTestValues([]*yaml.Node{doc})
+	for key, val := range map[string]string{"user": "admin", "password": "wxyz5678", "host": "db.local", "cert": "//4="} {
+		if node := vals["data."+key]; node == nil || node.Value != val {
+			t.Fatalf("TestSecretDecoded() unexpected value of '%v': %v", key, node)
+		}
+	}
+	if vals["data.cert"].ShortTag() != "!!binary" {
+		t.Fatalf("TestSecretDecoded() binary value tagged '%v'", vals["data.cert"].ShortTag())
+	}
+
+	for _, input := range []string{
+		"kind: Secret\ndata:\nstringData:\n  host: db.local\n",
+		"kind: Secret\nstringData:\n  host: db.local\n",
+	} {
+		doc = k8sTestDoc(t, input)
+		if err := secretDecoded(doc); err != nil {
+			t.Fatalf("TestSecretDecoded() %q: %v", input, err)
+		}
+		if node := yamlTestValues([]*yaml.Node{doc})["data.host"]; node == nil || node.Value != "db.local" {
+			t.Fatalf("TestSecretDecoded() %q unexpected value %v", input, node)
+		}
+	}
+
+	for _, input := range []string{
+		"kind: ConfigMap\ndata:\n  host: db.local\n",
+		"kind: Secret\ndata: abc\n",
+		"kind: Secret\ndata:\n  host: not base64\n",
+		"kind: Secret\ndata:\n  host: [a]\n",
+	} {
+		if err := secretDecoded(k8sTestDoc(t, input)); err == nil {
+			t.Fatalf("TestSecretDecoded() %q expecting error", input)
+		} else {
+			fmt.Printf("TestSecretDecoded() %q %v\n", input, err)
+		}
+	}
+	fmt.Println("TestSecretDecoded() test okay")
+}
+
+func TestSecretEncoded(t *testing.T) {
+	b64 := encodes.Get("base64")
+	doc := k8sTestDoc(t, "kind: Secret\ndata:\n  user: admin\n  port: 5432\n  cert: !!binary //4=\n  none: null\n")
+	doc, err := secretEncoded(doc, "unused", b64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals := yamlTestValues([]*yaml.Node{doc})
+	for key, val := range map[string]string{"user": "YWRtaW4=", "port": "NTQzMg==", "cert": "//4=", "none": ""} {
+		if node := vals["data."+key]; node == nil || node.Value != val || node.ShortTag() != "!!str" {
+			t.Fatalf("TestSecretEncoded() unexpected value of '%v': %v", key, node)
+		}
+	}
+
+	// documents not being Secrets are flattened
+	doc, err = secretEncoded(k8sTestDoc(t, yAMLTEST), "db-secrets", b64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals = yamlTestValues([]*yaml.Node{doc})
+	if vals["kind"].Value != "Secret" || vals["metadata.name"].Value != "db-secrets" || vals["data.db.password"].Value != "YWJjZDEyMzQ=" {
+		t.Fatalf("TestSecretEncoded() unexpected values %v", vals)
+	}
+	if _, err = secretEncoded(k8sTestDoc(t, "a.b: 1\na:\n  b: 2\n"), "dup", b64); err == nil {
+		t.Fatal("TestSecretEncoded() expecting error of duplicated keys")
+	}
+	fmt.Println("TestSecretEncoded() test okay")
+}
+
+func TestK8sSecret(t *testing.T) {
+	dir := t.TempDir()
+	name := "AES-256-GCM"
+	b64 := encodes.Get("base64")
+	cfg := &cfgs.Config{
+		Input:  filepath.Join(dir, "db_secrets.yaml"),
+		Output: filepath.Join(dir, "db_secrets.enc.yaml"),
+		Key:    filepath.Join(dir, "k8s.key"),
+		Genkey: true,
+		Buffer: cfgs.BUFFER,
+	}
+	if err := os.WriteFile(cfg.Input, []byte(sECRETTEST), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := k8sEncrypt(cfg, encrypts.New(name), b64, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	enc, err := os.ReadFile(cfg.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(enc), "wxyz5678") || !strings.Contains(string(enc), "name: db-secrets") {
+		t.Fatalf("TestK8sSecret() unexpected encrypted output:\n%s", enc)
+	}
+
+	cfg.Genkey, cfg.Input, cfg.Output = false, cfg.Output, filepath.Join(dir, "db_secrets.dec.yaml")
+	if err = k8sDecrypt(cfg, encrypts.New(name), b64, nil, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	dec, err := os.ReadFile(cfg.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals := yamlTestValues([]*yaml.Node{k8sTestDoc(t, string(dec))})
+	for key, val := range map[string]string{"user": "YWRtaW4=", "password": "d3h5ejU2Nzg=", "host": "ZGIubG9jYWw=", "cert": "//4="} {
+		if node := vals["data."+key]; node == nil || node.Value != val {
+			t.Fatalf("TestK8sSecret() unexpected value of '%v': %v", key, node)
+		}
+	}
+	if vals["metadata.name"].Value != "db-secrets" || vals["type"].Value != "Opaque" {
+		t.Fatalf("TestK8sSecret() metadata not kept:\n%s", dec)
+	}
+	fmt.Println("TestK8sSecret() test okay")
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/encrypts/sym"
+	"sea9.org/go/c9ryptool/pkg/keystore"
+	"sea9.org/go/c9ryptool/pkg/kms"
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+// populateKey populate the key of 'alg' as specified in 'cfg':
+// - password : 'salted' is the ciphertext ending with the salt for decryption, nil to generate a new salt for encryption
+// - generate : generate a new key and write it to the key file, encryption only
+// - key file : read the key from the key file, 'eck' is ignored for asymmetric keys since they are PEM encoded
+// - keystore : read the key from the keystore if the key is given as '@NAME'
+func populateKey(
+	cfg *cfgs.Config,
+	alg encrypts.Algorithm,
+	eck encodes.Encoding,
+	salted []byte,
+	isDecrypt bool,
+) (salt []byte, err error) {
+	var key []byte
+	if cfg.Passwd != "" {
+		pwd := cfg.Passwd
+		if cfg.Passwd == PWD_INTERACTIVE {
+			hdr := ""
+			if cfg.Verbose {
+				hdr = fmt.Sprintf("%v [%v]", time.Now().Format(LOG_FRM_MILLI), desc())
+			}
+			pwd, err = utils.Prompt(hdr, "Enter password: ")
+			if err != nil {
+				err = fmt.Errorf("[PWD]%v", err)
+				return
+			}
+		}
+		salt, err = sym.PopulateKeyFromPassword(
+			pwd,
+			salted,
+			alg.KeyLength(), cfg.SaltLen,
+			alg.PopulateKey,
+		)
+		if err != nil {
+			err = fmt.Errorf("[PWD]%v", err)
+		}
+	} else if cfg.Genkey {
+		if isDecrypt {
+			err = fmt.Errorf("[GEN] generate new key for decryption makes no sense")
+			return
+		}
+		err = alg.PopulateKey(nil)
+		if err != nil {
+			err = fmt.Errorf("[GEN]%v", err)
+			return
+		}
+		if eck == nil || !alg.Type() { // since asymmetric keys uses PEM encoding
+			err = utils.Write(cfg.Key, alg.GetKey())
+		} else {
+			err = utils.Write(cfg.Key, alg.GetKey(), eck)
+		}
+	} else {
+		key, err = readKey(cfg, alg, eck)
+		if err != nil {
+			err = fmt.Errorf("[KEY]%v", err)
+			return
+		}
+		err = alg.PopulateKey(key)
+		if err != nil {
+			err = fmt.Errorf("[POP]%v", err)
+		}
+	}
+	return
+}
+
+// readKey read the key from the key file, or from the keystore if the key is given as '@NAME'
+func readKey(cfg *cfgs.Config, alg encrypts.Algorithm, eck encodes.Encoding) (key []byte, err error) {
+	if name, ok := keyName(cfg.Key); ok {
+		var k *keystore.Key
+		if k, err = storeKey(cfg, name, true); err != nil { // the state is checked by useKeystore()
+			return
+		}
+		return k.Key, nil
+	}
+	if eck == nil || !alg.Type() { // since asymmetric keys uses PEM encoding
+		return utils.Read(cfg.Key, cfg.Buffer)
+	}
+	return utils.Read(cfg.Key, cfg.Buffer, eck)
+}
+
+// keyName name of the key in the keystore if the key is given as '@NAME'
+func keyName(key string) (string, bool) {
+	if strings.HasPrefix(key, "@") {
+		return key[1:], true
+	}
+	return "", false
+}
+
+// kEYSTORE the keystore opened by openKeystore()
+var kEYSTORE *keystore.Keystore
+
+// openKeystore open the keystore, unlocked by the identity file if given, otherwise by the master password from
+// the environment variable C9_KEYSTORE_PASSWORD, or input interactively
+func openKeystore(cfg *cfgs.Config) (ks *keystore.Keystore, err error) {
+	if kEYSTORE != nil {
+		return kEYSTORE, nil
+	}
+	path := cfg.Keystore
+	if path == "" {
+		path = keystore.DefaultPath()
+	}
+
+	var lock keystore.Lock
+	if cfg.Identity != "" {
+		if lock.Identity, err = utils.Read(cfg.Identity, cfg.Buffer); err != nil {
+			return nil, fmt.Errorf("[IDENTITY]%v", err)
+		}
+	} else if lock.Password = os.Getenv(keystore.PASSWORD); lock.Password == "" {
+		hdr := ""
+		if cfg.Verbose {
+			hdr = fmt.Sprintf("%v [%v]", time.Now().Format(LOG_FRM_MILLI), desc())
+		}
+		if lock.Password, err = utils.Prompt(hdr, "Enter keystore password: "); err != nil {
+			return nil, fmt.Errorf("[PWD]%v", err)
+		}
+	}
+	if kEYSTORE, err = keystore.Open(path, lock); err != nil {
+		return nil, err
+	}
+	return kEYSTORE, nil
+}
+
+// storeKey get the named key from the keystore, keys not in the active state are for decryption only
+func storeKey(cfg *cfgs.Config, name string, isDecrypt bool) (k *keystore.Key, err error) {
+	ks, err := openKeystore(cfg)
+	if err != nil {
+		return
+	}
+	if k, err = ks.Get(name); err != nil {
+		return
+	}
+	if !isDecrypt && !k.Active() {
+		err = fmt.Errorf("[KEYS] key '%v' is %v, cannot be used for encryption", name, k.State)
+	}
+	return
+}
+
+// useKeystore use the algorithm of the key in the keystore if the key is given as '@NAME'
+func useKeystore(cfg *cfgs.Config, isDecrypt bool) (err error) {
+	name, ok := keyName(cfg.Key)
+	if !ok {
+		return
+	}
+	k, err := storeKey(cfg, name, isDecrypt)
+	if err == nil {
+		cfg.Algr = k.Algorithm
+	}
+	return
+}
+
+// sealKey populate 'alg' with a new data key, and return the envelope header with the data key wrapped by the KMS
+func sealKey(cfg *cfgs.Config, alg encrypts.Algorithm) (hdr []byte, err error) {
+	prv, err := kms.Open(cfg.Kms)
+	if err != nil {
+		return
+	}
+	if err = alg.PopulateKey(nil); err != nil {
+		err = fmt.Errorf("[GEN]%v", err)
+		return
+	}
+	return kms.Seal(context.Background(), prv, alg.GetKey())
+}
+
+// unsealKey unwrap the data key in the envelope header of 'input' using the KMS, populate 'alg' with it, and
+// return the remaining ciphertext
+func unsealKey(cfg *cfgs.Config, alg encrypts.Algorithm, input []byte) (rest []byte, err error) {
+	prv, err := kms.Open(cfg.Kms)
+	if err != nil {
+		return
+	}
+	key, rest, err := kms.Unseal(context.Background(), prv, input)
+	if err != nil {
+		return
+	}
+	if err = alg.PopulateKey(key); err != nil {
+		err = fmt.Errorf("[POP]%v", err)
+	}
+	return
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+// isLines 'true' if the format is a line based one, i.e. '.env', INI, '.properties' or TOML
+func isLines(format string) bool {
+	return format == FORMAT_DOTENV || format == FORMAT_INI || format == FORMAT_PROPERTIES || format == FORMAT_TOML
+}
+
+// lines the entries of a line based input, i.e. utils.Lines or utils.Toml
+type lines interface {
+	Traverse(func([]string, *string) error) error
+	Put(name, value string)
+	Take(name string) string
+	Bytes() []byte
+}
+
+// readLines parse the line based input of the given format
+func readLines(format string, input []byte) (lines, error) {
+	switch format {
+	case FORMAT_TOML:
+		return utils.ReadToml(input)
+	case FORMAT_DOTENV:
+		return utils.ReadDotenv(input)
+	case FORMAT_INI:
+		return utils.ReadIni(input)
+	case FORMAT_PROPERTIES:
+		return utils.ReadProperties(input)
+	}
+	return nil, fmt.Errorf("unsupported format '%v'", format)
+}
+
+// linesMac the MAC of the entries, same as docsMac()
+func linesMac(lns lines) []byte {
+	list := make([]string, 0)
+	lns.Traverse(func(path []string, val *string) error {
+		list = append(list, macEntry(0, path, *val))
+		return nil
+	})
+	return macSum(list)
+}
+
+// linesEncrypt encrypt the values of the line based input, the raw text of the values is encrypted, so the quoting
+// and escaping are restored by decryption, and the other lines are kept as is. The encrypted TOML values are quoted
+// strings tagged with the original type.
+func linesEncrypt(
+	cfg *cfgs.Config,
+	alg encrypts.Algorithm,
+	eco, eck, ecv, eca encodes.Encoding,
+) (err error) {
+	var input, salt, aad []byte
+	frmt := strings.ToUpper(cfg.Format)
+
+	input, err = utils.Read(cfg.Input, cfg.Buffer)
+	if err != nil {
+		return fmt.Errorf("[%v][ECY][INP]%v", frmt, err)
+	}
+	lns, err := readLines(cfg.Format, input)
+	if err != nil {
+		return fmt.Errorf("[%v][ECY][UNM]%v", frmt, err)
+	}
+
+	if salt, err = populateKey(cfg, alg, eck, nil, false); err != nil {
+		return fmt.Errorf("[%v][ECY]%v", frmt, err)
+	}
+	if cfg.Aad != "" {
+		if aad, err = utils.Read(cfg.Aad, cfg.Buffer, eca); err != nil {
+			return fmt.Errorf("[%v][ECY][AAD]%v", frmt, err)
+		}
+	}
+
+	sel, err := pathSelector(cfg)
+	if err != nil {
+		return fmt.Errorf("[%v][ECY]%v", frmt, err)
+	}
+	av2 := encrypts.V2(alg)
+	err = lns.Traverse(func(path []string, val *string) error {
+		if !sel(path) {
+			return nil
+		}
+		rst, err := av2.Encrypt(encrypts.EncryptRequest{Plaintext: []byte(*val), AAD: fieldAAD(av2, aad, 0, path)})
+		if err != nil {
+			return err
+		}
+		if cfg.Format == FORMAT_TOML {
+			*val = "\"" + encValue(alg, eco.EncodeToString(rst.Output), utils.TomlType(*val)) + "\""
+		} else {
+			*val = encValue(alg, eco.EncodeToString(rst.Output), "str")
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("[%v][ECY][NAV]%v", frmt, err)
+	}
+
+	sum, err := encryptMac(linesMac(lns), av2, aad, eco)
+	if err != nil {
+		return fmt.Errorf("[%v][ECY][MAC]%v", frmt, err)
+	}
+	if salt != nil {
+		lns.Put(sALT, eco.EncodeToString(salt))
+	}
+	lns.Put(mAC, sum)
+
+	if err = writeOutput(cfg, lns.Bytes()); err != nil {
+		err = fmt.Errorf("[%v][ECY][OUT]%v", frmt, err)
+	}
+	return
+}
+
+// linesDecrypt decrypt the values of the line based input encrypted by linesEncrypt()
+func linesDecrypt(
+	cfg *cfgs.Config,
+	alg encrypts.Algorithm,
+	eci, eck, ecv, ect, eca encodes.Encoding,
+) (err error) {
+	var input, salt, aad []byte
+	frmt := strings.ToUpper(cfg.Format)
+
+	input, err = utils.Read(cfg.Input, cfg.Buffer)
+	if err != nil {
+		return fmt.Errorf("[%v][DCY][INP]%v", frmt, err)
+	}
+	lns, err := readLines(cfg.Format, input)
+	if err != nil {
+		return fmt.Errorf("[%v][DCY][UNM]%v", frmt, err)
+	}
+
+	str, sum := lns.Take(sALT), lns.Take(mAC)
+	if sum == "" {
+		return fmt.Errorf("[%v][DCY][MAC] MAC missing, the input is not encrypted by 'encrypt -f %v'", frmt, cfg.Format)
+	}
+	if str != "" {
+		if salt, err = eci.DecodeString(str); err != nil {
+			return fmt.Errorf("[%v][DCY][SALT]%v", frmt, err)
+		}
+	} else if cfg.Passwd != "" {
+		return fmt.Errorf("[%v][DCY][SALT] salt missing, the input is not encrypted with a password", frmt)
+	}
+
+	if _, err = populateKey(cfg, alg, eck, salt, true); err != nil {
+		return fmt.Errorf("[%v][DCY]%v", frmt, err)
+	}
+	if cfg.Aad != "" {
+		if aad, err = utils.Read(cfg.Aad, cfg.Buffer, eca); err != nil {
+			return fmt.Errorf("[%v][DCY][AAD]%v", frmt, err)
+		}
+	}
+
+	av2 := encrypts.V2(alg)
+	if err = verifyMac(linesMac(lns), av2, aad, eci, sum); err != nil {
+		return fmt.Errorf("[%v][DCY][MAC]%v", frmt, err)
+	}
+
+	sel, err := pathSelector(cfg)
+	if err != nil {
+		return fmt.Errorf("[%v][DCY]%v", frmt, err)
+	}
+	err = lns.Traverse(func(path []string, val *string) error {
+		if !sel(path) {
+			return nil
+		}
+		str := *val
+		if cfg.Format == FORMAT_TOML {
+			str = strings.Trim(str, "\"")
+		}
+		label, data, _, wrapped := parseEncValue(str)
+		if !wrapped {
+			return nil
+		} else if label != encLabel(alg) {
+			return fmt.Errorf("[ALG] value encrypted by '%v', not '%v'", label, encLabel(alg))
+		}
+		enc, err := eci.DecodeString(data)
+		if err != nil {
+			return err
+		}
+		dec, err := av2.Decrypt(encrypts.DecryptRequest{Ciphertext: enc, AAD: fieldAAD(av2, aad, 0, path)})
+		if err != nil {
+			return err
+		}
+		*val = string(dec.Plaintext)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("[%v][DCY][NAV]%v", frmt, err)
+	}
+
+	if err = writeOutput(cfg, lns.Bytes()); err != nil {
+		err = fmt.Errorf("[%v][DCY][OUT]%v", frmt, err)
+	}
+	return
+}
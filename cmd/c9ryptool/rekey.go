@@ -0,0 +1,380 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+// rekeyConfigs split the config of the 'rekey' command into those of the old key and of the new key
+func rekeyConfigs(cfg *cfgs.Config) (ocfg, ncfg *cfgs.Config) {
+	o, n := *cfg, *cfg
+	o.Genkey = false
+	n.Key, n.Passwd = cfg.NewKey, cfg.NewPwd
+	if cfg.NewAlgr != "" {
+		n.Algr = cfg.NewAlgr
+	}
+	return &o, &n
+}
+
+// rekeyer decrypt using the old key and encrypt using the new key, entirely in memory. The new key is populated
+// once and shared by all the files. Old keys generated from passwords are populated once for each salt, since
+// files encrypted separately have different salts.
+type rekeyer struct {
+	old    *cfgs.Config
+	alg    encrypts.Algorithm            // old algorithm, nil if the old key is generated from a password
+	salted map[string]encrypts.Algorithm // old algorithms by salts, if the old key is generated from a password
+	lock   sync.Mutex
+	nalg   encrypts.Algorithm  // new algorithm
+	salt   []byte              // salt of the new key if generated from a password
+	sel    func([]string) bool // selector of the values to re-encrypt in field-level formats
+	eci    encodes.Encoding
+	eco    encodes.Encoding
+	eck    encodes.Encoding
+}
+
+func newRekeyer(cfg *cfgs.Config, eci, eco, eck encodes.Encoding) (r *rekeyer, err error) {
+	old, ncfg := rekeyConfigs(cfg)
+	if err = useKeystore(old, true); err != nil {
+		return
+	}
+	if cfg.NewAlgr == "" {
+		ncfg.Algr = old.Algr
+	}
+	if err = useKeystore(ncfg, false); err != nil {
+		return
+	}
+
+	hdr := ""
+	if cfg.Verbose {
+		hdr = fmt.Sprintf("%v [%v]", time.Now().Format(LOG_FRM_MILLI), desc())
+	}
+	if old.Passwd == PWD_INTERACTIVE {
+		if old.Passwd, err = utils.Prompt(hdr, "Enter password: "); err != nil {
+			return nil, fmt.Errorf("[PWD]%v", err)
+		}
+	}
+	if ncfg.Passwd == PWD_INTERACTIVE {
+		if ncfg.Passwd, err = utils.Prompt(hdr, "Enter new password: "); err != nil {
+			return nil, fmt.Errorf("[PWD]%v", err)
+		}
+	}
+
+	r = &rekeyer{old: old, salted: make(map[string]encrypts.Algorithm), eci: eci, eco: eco, eck: eck}
+	if r.sel, err = pathSelector(cfg); err != nil {
+		return nil, err
+	}
+	if old.Passwd == "" {
+		if r.alg = encrypts.New(encrypts.Parse(old.Algr)); r.alg == nil {
+			return nil, fmt.Errorf("[OLD] unsupported algorithm '%v'", old.Algr)
+		}
+		if _, err = populateKey(old, r.alg, eck, nil, true); err != nil {
+			return nil, fmt.Errorf("[OLD]%v", err)
+		}
+	}
+	if r.nalg = encrypts.New(encrypts.Parse(ncfg.Algr)); r.nalg == nil {
+		return nil, fmt.Errorf("[NEW] unsupported algorithm '%v'", ncfg.Algr)
+	}
+	if r.salt, err = populateKey(ncfg, r.nalg, eck, nil, false); err != nil {
+		return nil, fmt.Errorf("[NEW]%v", err)
+	}
+	return
+}
+
+// oldAlg the old algorithm populated with the old key, 'salted' is the ciphertext ending with the salt if the
+// old key is generated from a password
+func (r *rekeyer) oldAlg(salted []byte) (alg encrypts.Algorithm, salt []byte, err error) {
+	if r.alg != nil {
+		return r.alg, nil, nil
+	}
+	if len(salted) < r.old.SaltLen {
+		return nil, nil, fmt.Errorf("[OLD] salt missing")
+	}
+	key := string(salted[len(salted)-r.old.SaltLen:])
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if alg = r.salted[key]; alg == nil {
+		alg = encrypts.New(encrypts.Parse(r.old.Algr))
+		if salt, err = populateKey(r.old, alg, r.eck, salted, true); err != nil {
+			return nil, nil, fmt.Errorf("[OLD]%v", err)
+		}
+		r.salted[key] = alg
+	}
+	return alg, []byte(key), nil
+}
+
+// value re-encrypt a ciphertext, 'alg' is the old algorithm, 'oaad' and 'naad' the old and new AAD
+func (r *rekeyer) value(alg encrypts.Algorithm, input, oaad, naad []byte) (result []byte, err error) {
+	dec, err := encrypts.V2(alg).Decrypt(encrypts.DecryptRequest{Ciphertext: input, AAD: oaad})
+	if err != nil {
+		return
+	}
+	enc, err := encrypts.V2(r.nalg).Encrypt(encrypts.EncryptRequest{Plaintext: dec.Plaintext, AAD: naad})
+	if err != nil {
+		return
+	}
+	return enc.Output, nil
+}
+
+// raw re-encrypt the output of the 'encrypt' command, decoded already. The manifest of an encrypted directory
+// is updated with the new algorithm.
+func (r *rekeyer) raw(input []byte, isManifest bool) (result []byte, err error) {
+	alg, salt, err := r.oldAlg(input)
+	if err != nil {
+		return
+	}
+	dec, err := encrypts.V2(alg).Decrypt(encrypts.DecryptRequest{Ciphertext: input[:len(input)-len(salt)]})
+	if err != nil {
+		return
+	}
+	if isManifest {
+		var mnft manifest
+		if err = json.Unmarshal(dec.Plaintext, &mnft); err != nil {
+			return nil, fmt.Errorf("[MNFT]%v", err)
+		}
+		mnft.Algorithm = r.nalg.Name()
+		if dec.Plaintext, err = json.MarshalIndent(mnft, "", "  "); err != nil {
+			return nil, fmt.Errorf("[MNFT]%v", err)
+		}
+	}
+	enc, err := encrypts.V2(r.nalg).Encrypt(encrypts.EncryptRequest{Plaintext: dec.Plaintext})
+	if err != nil {
+		return
+	}
+	return append(enc.Output, r.salt...), nil
+}
+
+// yaml re-encrypt the field values of the YAML or JSON output of the 'encrypt' command, keeping the file structure
+func (r *rekeyer) yaml(input []byte) (output []byte, err error) {
+	docs, err := utils.ReadNodes(input)
+	if err != nil {
+		return nil, fmt.Errorf("[UNM]%v", err)
+	} else if isSops(docs) {
+		return nil, fmt.Errorf("[SOPS] SOPS documents not supported by 'rekey'")
+	}
+
+	var salt []byte
+	str, docs := takeMeta(docs, sALT)
+	sum, docs := takeMeta(docs, mAC)
+	if str != "" {
+		if salt, err = r.eci.DecodeString(str); err != nil || salt == nil {
+			return nil, fmt.Errorf("[SALT] invalid salt '%v'", str)
+		}
+	}
+	alg, _, err := r.oldAlg(salt)
+	if err != nil {
+		return
+	}
+	oav2, nav2 := encrypts.V2(alg), encrypts.V2(r.nalg)
+	// documents encrypted by earlier versions carry no MAC nor any wrapped value, and the key paths are not bound
+	if sum != "" {
+		if err = verifyMac(docsMac(docs), oav2, nil, r.eci, sum); err != nil {
+			return nil, fmt.Errorf("[MAC]%v", err)
+		}
+	} else if hasWrapped(docs) {
+		return nil, fmt.Errorf("[MAC] MAC missing, the document has been modified")
+	}
+
+	rekey := func(doc int, path []string, node *yaml.Node) error {
+		if !r.sel(path) {
+			return nil
+		}
+		label, data, typ, wrapped := parseEncValue(node.Value)
+		if !wrapped {
+			// values encrypted since the MAC is added are all wrapped, the others are not selected
+			if sum != "" || node.ShortTag() != "!!str" {
+				return nil
+			}
+			data = node.Value
+		} else if label != encLabel(alg) {
+			return fmt.Errorf("[ALG] value encrypted by '%v', not '%v'", label, encLabel(alg))
+		}
+		enc, err := r.eci.DecodeString(data)
+		if err != nil {
+			return err
+		}
+		var oaad []byte
+		if sum != "" {
+			oaad = fieldAAD(oav2, nil, doc, path)
+		}
+		if enc, err = r.value(alg, enc, oaad, fieldAAD(nav2, nil, doc, path)); err != nil {
+			return err
+		}
+		if wrapped {
+			node.Value = encValue(r.nalg, r.eco.EncodeToString(enc), typ)
+		} else {
+			node.Value = r.eco.EncodeToString(enc)
+		}
+		return nil
+	}
+	for d, doc := range docs {
+		err = utils.TraverseNode(doc, func(path []string, node *yaml.Node) error { return rekey(d, path, node) })
+		if err != nil {
+			return nil, fmt.Errorf("[NAV]%v", err)
+		}
+	}
+
+	if sum, err = encryptMac(docsMac(docs), nav2, nil, r.eco); err != nil {
+		return nil, fmt.Errorf("[MAC]%v", err)
+	}
+	if r.salt != nil {
+		docs = putMeta(docs, sALT, r.eco.EncodeToString(r.salt))
+	}
+	docs = putMeta(docs, mAC, sum)
+
+	if output, err = writeDocs(r.old, docs); err != nil {
+		err = fmt.Errorf("[MRS]%v", err)
+	}
+	return
+}
+
+// file re-encrypt the content of a file read from 'path', decoded if not a field-level format
+func (r *rekeyer) file(cfg *cfgs.Config, path string, isManifest bool) (output []byte, err error) {
+	if isNodes(cfg.Format) {
+		if output, err = utils.Read(path, cfg.Buffer); err != nil {
+			return nil, fmt.Errorf("[INP]%v", err)
+		}
+		return r.yaml(output)
+	}
+	if output, err = utils.Read(path, cfg.Buffer, r.eci); err != nil {
+		return nil, fmt.Errorf("[INP]%v", err)
+	}
+	return r.raw(output, isManifest)
+}
+
+// rekey re-encrypt the input file, or the files in the input directory, using the new key
+func rekey(cfg *cfgs.Config, eci, eco, eck encodes.Encoding) (err error) {
+	r, err := newRekeyer(cfg, eci, eco, eck)
+	if err != nil {
+		return fmt.Errorf("[RKY]%v", err)
+	}
+	if isDir(cfg.Input) {
+		return rekeyDir(cfg, r)
+	}
+
+	output, err := r.file(cfg, cfg.Input, false)
+	if err != nil {
+		return fmt.Errorf("[RKY]%v", err)
+	}
+	if isNodes(cfg.Format) {
+		err = writeOutput(cfg, output)
+	} else {
+		err = writeOutput(cfg, output, eco)
+	}
+	if err != nil {
+		err = fmt.Errorf("[RKY][OUT]%v", err)
+	}
+	return
+}
+
+// rekeyDir re-encrypt the selected files in the input directory, to the output directory or in-place. All files
+// of a directory encrypted with a manifest are re-encrypted, including the manifest. When in-place, the files are
+// replaced only after all of them are re-encrypted, so a failure of re-encryption leaves all files untouched. A
+// failure of replacing a file leaves those replaced before it encrypted with the new key, and the rest with the
+// old key.
+func rekeyDir(cfg *cfgs.Config, r *rekeyer) (err error) {
+	files := make([]string, 0)
+	modes := make([]fs.FileMode, 0)
+	err = filepath.WalkDir(cfg.Input, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(cfg.Input, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if d.IsDir() {
+			for _, p := range cfg.Exclude {
+				if utils.MatchGlob(p, rel) {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !selected(cfg, rel) && rel != MANIFEST {
+			if cfg.Verbose {
+				fmt.Printf("Skipping '%v'\n", rel)
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, rel)
+		modes = append(modes, info.Mode().Perm())
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("[RKY][DIR]%v", err)
+	}
+
+	staged := make([]*utils.Staged, len(files))
+	errs := runWorkers(cfg.Workers, len(files), func(i int) (err error) {
+		rel := files[i]
+		path := filepath.Join(cfg.Input, filepath.FromSlash(rel))
+		output, err := r.file(cfg, path, rel == MANIFEST && !isNodes(cfg.Format))
+		if err != nil {
+			return fmt.Errorf("%v: %v", rel, err)
+		}
+
+		var eco encodes.Encoding
+		if !isNodes(cfg.Format) {
+			eco = r.eco
+		}
+		if cfg.InPlace {
+			if staged[i], err = utils.Stage(path, output, eco); err != nil {
+				return fmt.Errorf("%v: [OUT]%v", rel, err)
+			}
+		} else if err = writeFile(cfg.Output, rel, output, modes[i], eco); err != nil {
+			return
+		}
+		if cfg.Verbose {
+			fmt.Printf("Re-encrypted '%v'\n", rel)
+		}
+		return
+	})
+	if len(errs) <= 0 && cfg.InPlace {
+		for i, stg := range staged {
+			bak := ""
+			if cfg.Backup {
+				bak = filepath.Join(cfg.Input, filepath.FromSlash(files[i])) + ".bak"
+			}
+			if err = stg.Commit(bak); err != nil {
+				errs = append(errs, fmt.Errorf("%v: [OUT]%v, %v files replaced before it", files[i], err, i))
+				staged = staged[i+1:]
+				break
+			}
+		}
+	}
+	if len(errs) > 0 {
+		for _, stg := range staged {
+			if stg != nil {
+				stg.Discard()
+			}
+		}
+		err = fmt.Errorf("[RKY]%v", batchError(errs, len(files)))
+	}
+	return
+}
+
+// hasManifest 'true' if 'dir' is a directory encrypted with a manifest
+func hasManifest(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, MANIFEST))
+	return err == nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/encrypts/sym"
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+func TestRekeyYaml(t *testing.T) {
+	name := "AES-256-GCM"
+	b64 := encodes.Get("base64")
+	for _, paths := range [][]string{nil, {"$.db.password"}} {
+		dir := t.TempDir()
+		cfg, docs := yamlTestEncrypt(t, dir, name, paths...)
+		out, err := utils.WriteNodes(docs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		input := filepath.Join(dir, "enc.yaml")
+		if err = os.WriteFile(input, out, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		// the values not selected when encrypting are kept as they are
+		rcfg := &cfgs.Config{
+			Algr:   name,
+			Input:  input,
+			Output: filepath.Join(dir, "new.yaml"),
+			Format: FORMAT_YAML,
+			Key:    cfg.Key,
+			NewKey: filepath.Join(dir, "new.key"),
+			Genkey: true,
+			Buffer: cfgs.BUFFER,
+		}
+		if err = rekey(rcfg, b64, b64, nil); err != nil {
+			t.Fatalf("TestRekeyYaml() %v: %v", paths, err)
+		}
+		ncfg := &cfgs.Config{Key: rcfg.NewKey, Input: rcfg.Output, Buffer: cfgs.BUFFER}
+		clr, err := yamlDecrypted(ncfg, encrypts.New(name), b64, nil, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("TestRekeyYaml() %v decrypting with the new key: %v", paths, err)
+		}
+		vals := yamlTestValues(clr)
+		if vals["db.user"].Value != "admin" || vals["db.password"].Value != "abcd1234" || vals["port"].ShortTag() != "!!int" {
+			t.Fatalf("TestRekeyYaml() %v unexpected decrypted values %v", paths, vals)
+		}
+		cfg.Input = rcfg.Output
+		if _, err = yamlDecrypted(cfg, encrypts.New(name), b64, nil, nil, nil, nil); err == nil {
+			t.Fatalf("TestRekeyYaml() %v decryption with the old key should fail", paths)
+		}
+	}
+	fmt.Println("TestRekeyYaml() test okay")
+}
+
+func TestRekeyDir(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{"a", "b"}
+	if err := os.Mkdir(filepath.Join(dir, "enc"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		if err := os.WriteFile(filepath.Join(dir, name+".txt"), []byte("top secret "+name), 0600); err != nil {
+			t.Fatal(err)
+		}
+		cfg := encryptTestConfig(dir, name+".txt", filepath.Join("enc", name+".bin"))
+		if err := encrypt(cfg, encrypts.New(encrypts.Default()), nil, nil, nil, nil, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rcfg := &cfgs.Config{
+		Algr:    encrypts.Default(),
+		Input:   filepath.Join(dir, "enc"),
+		Passwd:  pWDTEST,
+		NewPwd:  pWDTEST + "2",
+		SaltLen: sym.SALTLEN,
+		InPlace: true,
+		Buffer:  cfgs.BUFFER,
+	}
+
+	// a file failing to re-encrypt leaves all files untouched
+	bad := filepath.Join(rcfg.Input, "b.bin")
+	org, err := os.ReadFile(bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	org[0] ^= 1
+	if err = os.WriteFile(bad, org, 0600); err != nil {
+		t.Fatal(err)
+	}
+	unchanged, err := os.ReadFile(filepath.Join(rcfg.Input, "a.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = rekey(rcfg, nil, nil, nil); err == nil {
+		t.Fatal("TestRekeyDir() expecting error rekeying a modified file")
+	}
+	if dat, err := os.ReadFile(filepath.Join(rcfg.Input, "a.bin")); err != nil || !bytes.Equal(dat, unchanged) {
+		t.Fatalf("TestRekeyDir() file replaced despite the failure: %v", err)
+	}
+	org[0] ^= 1
+	if err = os.WriteFile(bad, org, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = rekey(rcfg, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		cfg := encryptTestConfig(dir, filepath.Join("enc", name+".bin"), name+".new")
+		cfg.Passwd = rcfg.NewPwd
+		if err = decrypt(cfg, encrypts.New(encrypts.Default()), nil, nil, nil, nil, nil, nil, nil); err != nil {
+			t.Fatalf("TestRekeyDir() decrypting %v with the new password: %v", name, err)
+		}
+		if clr, err := os.ReadFile(cfg.Output); err != nil || string(clr) != "top secret "+name {
+			t.Fatalf("TestRekeyDir() unexpected result '%v' of %v: %v", string(clr), name, err)
+		}
+	}
+	fmt.Println("TestRekeyDir() test okay")
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/keystore"
+	"sea9.org/go/c9ryptool/pkg/server"
+)
+
+// serve serve the JSON/HTTP endpoints until interrupted
+func serve(cfg *cfgs.Config) (err error) {
+	var keys *keystore.Keystore
+	if cfg.Keystore != "" {
+		if keys, err = openKeystore(cfg); err != nil {
+			return
+		}
+	}
+
+	var hdlr http.Handler = server.New(keys, cfg.MaxSize)
+	if cfg.Verbose {
+		hdlr = logged(hdlr)
+	}
+	svr := &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           hdlr,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       time.Minute,
+		WriteTimeout:      time.Minute,
+	}
+	if cfg.ClientCA != "" {
+		buf, e := os.ReadFile(cfg.ClientCA)
+		if e != nil {
+			return fmt.Errorf("[SERV] %v", e)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(buf) {
+			return fmt.Errorf("[SERV] no certificate found in '%v'", cfg.ClientCA)
+		}
+		svr.TLSConfig = &tls.Config{
+			ClientCAs:  pool,
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			MinVersion: tls.VersionTLS12,
+		}
+	} else if cfg.TlsCert != "" {
+		svr.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		svr.Shutdown(ctx)
+	}()
+
+	if cfg.Verbose {
+		fmt.Printf("%v [%v] serving on %v\n", time.Now().Format(LOG_FRM_MILLI), desc(), cfg.Listen)
+	}
+	if cfg.TlsCert != "" {
+		err = svr.ListenAndServeTLS(cfg.TlsCert, cfg.TlsKey)
+	} else {
+		err = svr.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	} else if err != nil {
+		err = fmt.Errorf("[SERV] %v", err)
+	}
+	return
+}
+
+// logged log the requests
+func logged(hdlr http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		hdlr.ServeHTTP(w, r)
+		fmt.Printf("%v %v %v %v (%v)\n", start.Format(LOG_FRM_MILLI), r.RemoteAddr, r.Method, r.URL.Path, time.Since(start))
+	})
+}
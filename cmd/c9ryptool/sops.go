@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/kms"
+	"sea9.org/go/c9ryptool/pkg/sops"
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+// isSops 'true' if the YAML documents are encrypted in the SOPS format
+func isSops(docs []*yaml.Node) bool {
+	if len(docs) <= 0 {
+		return false
+	}
+	root := rootMap(docs[0])
+	if root == nil {
+		return false
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == sops.METADATA && root.Content[i+1].Kind == yaml.MappingNode {
+			return true
+		}
+	}
+	return false
+}
+
+// sopsEncrypt encrypt the YAML input in the SOPS format. The data key is wrapped by the KMS if '--kms' is given,
+// readable by SOPS if the KMS is Vault, otherwise by the encryption key or password.
+func sopsEncrypt(cfg *cfgs.Config, alg encrypts.Algorithm, eck encodes.Encoding) (err error) {
+	input, err := utils.Read(cfg.Input, cfg.Buffer)
+	if err != nil {
+		return fmt.Errorf("[SOPS][ECY][INP]%v", err)
+	}
+	docs, err := utils.ReadNodes(input)
+	if err != nil {
+		return fmt.Errorf("[SOPS][ECY][UNM]%v", err)
+	}
+	if len(docs) != 1 || rootMap(docs[0]) == nil {
+		return fmt.Errorf("[SOPS][ECY] a single document with a top level map expected")
+	} else if isSops(docs) {
+		return fmt.Errorf("[SOPS][ECY] the input is encrypted already")
+	}
+	root := rootMap(docs[0])
+	sops.Expand(docs[0])
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	meta := &sops.Metadata{LastModified: now, Version: sops.VERSION}
+	if cfg.Regex != "" {
+		meta.EncryptedRegex = cfg.Regex
+	} else {
+		meta.UnencryptedSuffix = sops.UNENCRYPTED_SUFFIX
+	}
+	key, err := sops.NewKey()
+	if err != nil {
+		return fmt.Errorf("[SOPS][ECY]%v", err)
+	}
+	if err = sopsWrap(cfg, alg, eck, meta, key); err != nil {
+		return fmt.Errorf("[SOPS][ECY]%v", err)
+	}
+
+	sel, err := meta.Selector()
+	if err != nil {
+		return fmt.Errorf("[SOPS][ECY]%v", err)
+	}
+	mac := sops.NewMac()
+	err = sops.Walk(root, func(path []string, node *yaml.Node) error {
+		val, err := sops.Value(node)
+		if err != nil || val == nil {
+			return err
+		}
+		if err = mac.Add(val); err != nil || !sel(path) {
+			return err
+		}
+		enc, err := sops.Encrypt(key, val, sops.AAD(path))
+		if err != nil {
+			return err
+		}
+		sops.SetValue(node, enc)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("[SOPS][ECY][NAV]%v", err)
+	}
+	if meta.Mac, err = sops.Encrypt(key, mac.Sum(), meta.LastModified); err != nil {
+		return fmt.Errorf("[SOPS][ECY][MAC]%v", err)
+	}
+
+	mnode := &yaml.Node{}
+	if err = mnode.Encode(meta); err != nil {
+		return fmt.Errorf("[SOPS][ECY][MTD]%v", err)
+	}
+	root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: sops.METADATA}, mnode)
+
+	output, err := writeDocs(cfg, docs)
+	if err != nil {
+		return fmt.Errorf("[SOPS][ECY][MRS]%v", err)
+	}
+	if err = writeOutput(cfg, output); err != nil {
+		err = fmt.Errorf("[SOPS][ECY][OUT]%v", err)
+	}
+	return
+}
+
+// sopsWrap wrap the data key, and add it to the key groups of the metadata
+func sopsWrap(cfg *cfgs.Config, alg encrypts.Algorithm, eck encodes.Encoding, meta *sops.Metadata, key []byte) (err error) {
+	now := meta.LastModified
+	if cfg.Kms != "" {
+		prv, err := kms.Open(cfg.Kms)
+		if err != nil {
+			return err
+		}
+		wrp, err := prv.WrapKey(context.Background(), key)
+		if err != nil {
+			return fmt.Errorf("[KMS][WRAP]%v", err)
+		}
+		if vlt, ok := prv.(*kms.Vault); ok {
+			mount, name := path.Split(vlt.KeyID())
+			meta.HcVault = append(meta.HcVault, sops.VaultKey{
+				VaultAddress: vlt.Address(),
+				EnginePath:   path.Clean(mount),
+				KeyName:      name,
+				CreatedAt:    now,
+				Enc:          string(wrp),
+			})
+		} else {
+			meta.C9ryptool = append(meta.C9ryptool, sops.Key{
+				Kms:       prv.Name(),
+				KeyID:     prv.KeyID(),
+				CreatedAt: now,
+				Enc:       base64.StdEncoding.EncodeToString(wrp),
+			})
+		}
+		return nil
+	}
+
+	salt, err := populateKey(cfg, alg, eck, nil, false)
+	if err != nil {
+		return
+	}
+	enc, err := encrypts.V2(alg).Encrypt(encrypts.EncryptRequest{Plaintext: key})
+	if err != nil {
+		return fmt.Errorf("[WRAP]%v", err)
+	}
+	k := sops.Key{Algorithm: alg.Name(), CreatedAt: now, Enc: base64.StdEncoding.EncodeToString(enc.Output)}
+	if salt != nil {
+		k.Salt = base64.StdEncoding.EncodeToString(salt)
+	}
+	meta.C9ryptool = append(meta.C9ryptool, k)
+	return
+}
+
+// sopsDecrypt decrypt the YAML documents encrypted in the SOPS format in memory, and verify the MAC. The data key
+// is unwrapped by the KMS if '--kms' is given, otherwise by the encryption key or password.
+func sopsDecrypt(cfg *cfgs.Config, alg encrypts.Algorithm, eck encodes.Encoding, docs []*yaml.Node) (err error) {
+	if len(docs) != 1 {
+		return fmt.Errorf("[SOPS][DCY] multiple documents not supported")
+	} else if len(cfg.Paths) > 0 || cfg.Regex != "" {
+		return fmt.Errorf("[SOPS][DCY] the values to decrypt are selected by the metadata, '--path' and '--encrypted-regex' not applicable")
+	}
+	root := rootMap(docs[0])
+	meta := &sops.Metadata{}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == sops.METADATA {
+			if err = root.Content[i+1].Decode(meta); err != nil {
+				return fmt.Errorf("[SOPS][DCY][MTD]%v", err)
+			}
+			root.Content = append(root.Content[:i], root.Content[i+2:]...)
+			break
+		}
+	}
+
+	key, err := sopsUnwrap(cfg, alg, eck, meta)
+	if err != nil {
+		return fmt.Errorf("[SOPS][DCY]%v", err)
+	}
+
+	sel, err := meta.Selector()
+	if err != nil {
+		return fmt.Errorf("[SOPS][DCY]%v", err)
+	}
+	mac := sops.NewMac()
+	err = sops.Walk(root, func(path []string, node *yaml.Node) error {
+		val, err := sops.Value(node)
+		if err != nil || val == nil {
+			return err
+		}
+		isSel := sel(path)
+		if str, ok := val.(string); ok && isSel {
+			if val, err = sops.Decrypt(key, str, sops.AAD(path)); err != nil {
+				return err
+			}
+			sops.SetValue(node, val)
+		}
+		if !meta.MacOnlyEncrypted || isSel {
+			return mac.Add(val)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("[SOPS][DCY][NAV]%v", err)
+	}
+
+	sum, err := sops.Decrypt(key, meta.Mac, meta.LastModified)
+	if err != nil {
+		return fmt.Errorf("[SOPS][DCY][MAC]%v", err)
+	} else if sum != mac.Sum() {
+		return fmt.Errorf("[SOPS][DCY][MAC] MAC mismatched, the document has been modified")
+	}
+	sops.DecryptComments(key, root)
+	return
+}
+
+// sopsUnwrap unwrap the data key from the key groups of the metadata
+func sopsUnwrap(cfg *cfgs.Config, alg encrypts.Algorithm, eck encodes.Encoding, meta *sops.Metadata) (key []byte, err error) {
+	if cfg.Kms != "" {
+		prv, err := kms.Open(cfg.Kms)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := prv.(*kms.Vault); ok {
+			for _, k := range meta.HcVault {
+				if kid := k.EnginePath + "/" + k.KeyName; kid == prv.KeyID() {
+					return unwrapped(prv.UnwrapKey(context.Background(), kid, []byte(k.Enc)))
+				}
+			}
+		} else {
+			for _, k := range meta.C9ryptool {
+				if k.Kms == prv.Name() {
+					wrp, err := base64.StdEncoding.DecodeString(k.Enc)
+					if err != nil {
+						return nil, fmt.Errorf("[KMS] invalid wrapped key: %v", err)
+					}
+					return unwrapped(prv.UnwrapKey(context.Background(), k.KeyID, wrp))
+				}
+			}
+		}
+		return nil, fmt.Errorf("[KMS] data key not wrapped by '%v', but by: %v", cfg.Kms, meta.Groups())
+	}
+
+	for _, k := range meta.C9ryptool {
+		if k.Kms != "" || k.Algorithm != alg.Name() {
+			continue
+		}
+		var salt, wrp []byte
+		if k.Salt != "" {
+			if salt, err = base64.StdEncoding.DecodeString(k.Salt); err != nil {
+				return nil, fmt.Errorf("[SALT] invalid salt: %v", err)
+			}
+		}
+		if wrp, err = base64.StdEncoding.DecodeString(k.Enc); err != nil {
+			return nil, fmt.Errorf("[KEY] invalid wrapped key: %v", err)
+		}
+		kcfg := *cfg
+		if salt != nil {
+			kcfg.SaltLen = len(salt)
+		}
+		if _, err = populateKey(&kcfg, alg, eck, salt, true); err != nil {
+			return
+		}
+		dec, err := encrypts.V2(alg).Decrypt(encrypts.DecryptRequest{Ciphertext: wrp})
+		if err != nil {
+			return nil, fmt.Errorf("[UNWRAP]%v", err)
+		}
+		return dec.Plaintext, nil
+	}
+	return nil, fmt.Errorf("[KEY] data key not wrapped by a '%v' key, but by: %v", alg.Name(), meta.Groups())
+}
+
+// unwrapped check the unwrapped data key
+func unwrapped(key []byte, err error) ([]byte, error) {
+	if err != nil {
+		return nil, fmt.Errorf("[KMS][UNWRAP]%v", err)
+	} else if len(key) != sops.KEY_SIZE {
+		return nil, fmt.Errorf("[KMS][UNWRAP] invalid data key size %v", len(key))
+	}
+	return key, nil
+}
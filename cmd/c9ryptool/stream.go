@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+// openStreams open the input and the output of the streamed formats, i.e. CSV and JSON Lines, stdin and stdout if
+// not given
+func openStreams(cfg *cfgs.Config) (inp io.Reader, out io.Writer, done func(), err error) {
+	var fin, fout *os.File
+	done = func() {
+		if fin != nil {
+			fin.Close()
+		}
+		if fout != nil {
+			fout.Close()
+		}
+	}
+	inp, out = utils.Stdin(), os.Stdout
+	if cfg.Input != "" {
+		if fin, err = os.Open(cfg.Input); err != nil {
+			return nil, nil, done, fmt.Errorf("[INP] %v", err)
+		}
+		inp = fin
+	}
+	if cfg.Output != "" {
+		if fout, err = os.Create(cfg.Output); err != nil {
+			return nil, nil, done, fmt.Errorf("[OUT] %v", err)
+		}
+		out = fout
+	}
+	return
+}
+
+// saltedValue add the salt to a wrapped field value of the streamed formats, since no metadata of the entire input
+// is kept, e.g. 'ENC[ALGR,data:...,type:str,salt:...]'
+func saltedValue(val string, salt []byte, eco encodes.Encoding) string {
+	if salt == nil {
+		return val
+	}
+	return fmt.Sprintf("%v,salt:%v]", strings.TrimSuffix(val, "]"), eco.EncodeToString(salt))
+}
+
+// encField the value of the field 'name' of a wrapped field value, e.g. the salt
+func encField(str, name string) string {
+	if !strings.HasPrefix(str, "ENC[") || !strings.HasSuffix(str, "]") {
+		return ""
+	}
+	for _, f := range strings.Split(str[4:len(str)-1], ",") {
+		if v, found := strings.CutPrefix(f, name+":"); found {
+			return v
+		}
+	}
+	return ""
+}
+
+// saltedKeys the keys generated from the password by the salts of the values of the streamed formats, the
+// password is prompted once
+type saltedKeys struct {
+	cfg  cfgs.Config
+	alg  encrypts.Algorithm
+	eci  encodes.Encoding
+	eck  encodes.Encoding
+	keys map[string][]byte
+}
+
+// newSaltedKeys populate the key if no password is given, otherwise the keys are generated by populate()
+func newSaltedKeys(cfg *cfgs.Config, alg encrypts.Algorithm, eci, eck encodes.Encoding) (k *saltedKeys, err error) {
+	k = &saltedKeys{cfg: *cfg, alg: alg, eci: eci, eck: eck, keys: make(map[string][]byte)}
+	if k.cfg.Passwd == PWD_INTERACTIVE {
+		if k.cfg.Passwd, err = utils.Prompt(desc(), "Enter password: "); err != nil {
+			return nil, fmt.Errorf("[PWD]%v", err)
+		}
+	} else if k.cfg.Passwd == "" {
+		if _, err = populateKey(cfg, alg, eck, nil, true); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// populate populate the key of the salt of the wrapped field value 'val', if a password is given
+func (k *saltedKeys) populate(val string) error {
+	if k.cfg.Passwd == "" {
+		return nil
+	}
+	str := encField(val, "salt")
+	if str == "" {
+		return fmt.Errorf("[SALT] salt missing, the value is not encrypted with a password")
+	}
+	if key, ok := k.keys[str]; ok {
+		if err := k.alg.PopulateKey(key); err != nil {
+			return fmt.Errorf("[POP]%v", err)
+		}
+		return nil
+	}
+	salt, err := k.eci.DecodeString(str)
+	if err != nil {
+		return fmt.Errorf("[SALT]%v", err)
+	}
+	k.cfg.SaltLen = len(salt)
+	if _, err = populateKey(&k.cfg, k.alg, k.eck, salt, true); err != nil {
+		return err
+	}
+	k.keys[str] = k.alg.GetKey()
+	return nil
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 	"sea9.org/go/c9ryptool/pkg/cfgs"
 	"sea9.org/go/c9ryptool/pkg/encodes"
 	"sea9.org/go/c9ryptool/pkg/encrypts"
@@ -13,6 +19,225 @@ import (
 )
 
 const sALT = "s94ffb825" // "s" + fnv32 hashing of the string "c9rypTool-salt"
+const mAC = "m3e0c8bae"  // "m" + fnv32 hashing of the string "c9rypTool-mac"
+
+// encLabel the algorithm label of the encrypted field values, e.g. 'AES-256-GCM' becomes 'AES_256_GCM'
+func encLabel(alg encrypts.Algorithm) string {
+	return strings.ToUpper(strings.ReplaceAll(alg.Name(), "-", "_"))
+}
+
+// encValue wrap the encoded ciphertext of a field value, along with the algorithm and the original YAML type,
+// e.g. 'ENC[AES_256_GCM,data:...,type:str]'
+func encValue(alg encrypts.Algorithm, data, typ string) string {
+	return fmt.Sprintf("ENC[%v,data:%v,type:%v]", encLabel(alg), data, typ)
+}
+
+// parseEncValue split a wrapped field value into the algorithm label, the encoded ciphertext and the YAML type,
+// 'ok' is false if the value is not wrapped, i.e. encrypted by earlier versions
+func parseEncValue(str string) (label, data, typ string, ok bool) {
+	if !strings.HasPrefix(str, "ENC[") || !strings.HasSuffix(str, "]") {
+		return
+	}
+	flds := strings.Split(str[4:len(str)-1], ",")
+	label = flds[0]
+	for _, f := range flds[1:] {
+		if v, found := strings.CutPrefix(f, "data:"); found {
+			data = v
+		} else if v, found = strings.CutPrefix(f, "type:"); found {
+			typ = v
+		}
+	}
+	ok = label != "" && data != "" && typ != ""
+	return
+}
+
+// putMeta add the metadata, e.g. the salt, to the first document if it is a non-empty map, otherwise to a new
+// document prepended to 'docs'
+func putMeta(docs []*yaml.Node, name, value string) []*yaml.Node {
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}
+	val := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	if len(docs) > 0 {
+		// an empty map followed by other documents would be taken as a prepended metadata document
+		if root := rootMap(docs[0]); root != nil && (len(root.Content) > 0 || len(docs) == 1) {
+			root.Content = append(root.Content, key, val)
+			return docs
+		}
+	}
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{
+		{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{key, val}},
+	}}
+	return append([]*yaml.Node{doc}, docs...)
+}
+
+// takeMeta remove the metadata from the first document, which is removed as well if it contains the metadata only
+// and is followed by other documents
+func takeMeta(docs []*yaml.Node, name string) (value string, rest []*yaml.Node) {
+	if len(docs) <= 0 {
+		return "", docs
+	}
+	root := rootMap(docs[0])
+	if root == nil {
+		return "", docs
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == name {
+			value = root.Content[i+1].Value
+			root.Content = append(root.Content[:i], root.Content[i+2:]...)
+			if len(root.Content) <= 0 && len(docs) > 1 {
+				return value, docs[1:]
+			}
+			break
+		}
+	}
+	return value, docs
+}
+
+// fieldAAD the AAD of a field value, i.e. 'aad' followed by the document index and the key path of the value, so
+// the value cannot be moved to another key; nil if the algorithm does not support AAD
+func fieldAAD(alg encrypts.AlgorithmV2, aad []byte, doc int, path []string) []byte {
+	if !alg.SupportsAAD() {
+		return nil
+	}
+	kp, _ := json.Marshal(append([]string{strconv.Itoa(doc)}, path...))
+	return append(aad[:len(aad):len(aad)], kp...)
+}
+
+// docsMac the MAC of the documents, i.e. the SHA-256 hash of the sorted list of the document indices, key paths and
+// values (the ciphertexts of the encrypted ones) of all scalars, so values cannot be swapped, removed or copied from
+// other files without being detected
+func docsMac(docs []*yaml.Node) []byte {
+	list := make([]string, 0)
+	for d, doc := range docs {
+		utils.TraverseNode(doc, func(path []string, node *yaml.Node) error {
+			list = append(list, macEntry(d, path, node.Value))
+			return nil
+		})
+	}
+	return macSum(list)
+}
+
+// macEntry an entry of the MAC list
+func macEntry(doc int, path []string, val string) string {
+	ent, _ := json.Marshal([]interface{}{doc, path, val})
+	return string(ent)
+}
+
+// macSum the SHA-256 hash of the sorted MAC list
+func macSum(list []string) []byte {
+	sort.Strings(list)
+	sum := sha256.Sum256([]byte(strings.Join(list, "\n")))
+	return sum[:]
+}
+
+// hasWrapped 'true' if any value of the documents is wrapped, i.e. encrypted since the MAC is added, so the MAC
+// cannot be missing
+func hasWrapped(docs []*yaml.Node) bool {
+	found := false
+	for _, doc := range docs {
+		utils.TraverseNode(doc, func(_ []string, node *yaml.Node) error {
+			_, _, _, wrapped := parseEncValue(node.Value)
+			found = found || wrapped
+			return nil
+		})
+	}
+	return found
+}
+
+// encryptMac encrypt the MAC 'sum' with 'alg', encoded by 'eco'
+func encryptMac(sum []byte, alg encrypts.AlgorithmV2, aad []byte, eco encodes.Encoding) (string, error) {
+	enc, err := alg.Encrypt(encrypts.EncryptRequest{Plaintext: sum, AAD: fieldAAD(alg, aad, -1, []string{mAC})})
+	if err != nil {
+		return "", err
+	}
+	return eco.EncodeToString(enc.Output), nil
+}
+
+// verifyMac verify the MAC 'sum' against the one encrypted by encryptMac()
+func verifyMac(sum []byte, alg encrypts.AlgorithmV2, aad []byte, eci encodes.Encoding, str string) error {
+	enc, err := eci.DecodeString(str)
+	if err != nil {
+		return err
+	}
+	dec, err := alg.Decrypt(encrypts.DecryptRequest{Ciphertext: enc, AAD: fieldAAD(alg, aad, -1, []string{mAC})})
+	if err != nil {
+		return err
+	} else if !hmac.Equal(dec.Plaintext, sum) {
+		return fmt.Errorf(" MAC mismatched, the document has been modified")
+	}
+	return nil
+}
+
+// isNodes 'true' if the input of the format is processed as YAML nodes, i.e. 'yaml' and 'json'
+func isNodes(format string) bool {
+	return format == FORMAT_YAML || format == FORMAT_JSON
+}
+
+// writeDocs serialize the documents as YAML, or as JSON for format 'json', which must be a single document
+func writeDocs(cfg *cfgs.Config, docs []*yaml.Node) ([]byte, error) {
+	if cfg.Format != FORMAT_JSON {
+		return utils.WriteNodes(docs)
+	} else if len(docs) != 1 || (len(docs[0].Content) > 0 && docs[0].Content[0].Kind != yaml.MappingNode) {
+		return nil, fmt.Errorf("[JSON] a single document with a top level object expected")
+	}
+	return utils.WriteJson(docs[0])
+}
+
+// rootMap the top level map of a YAML document, nil if the top level is not a map
+func rootMap(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 && doc.Content[0].Kind == yaml.MappingNode {
+		return doc.Content[0]
+	}
+	return nil
+}
+
+// pathSelector the selector of the values to encrypt/decrypt in field-level formats. A value is selected if its key
+// path matches any '--path' expression, or any key in the path matches '--encrypted-regex', and does not match any
+// '!' prefixed '--path' expression. All values are selected if no including selector is given.
+func pathSelector(cfg *cfgs.Config) (sel func([]string) bool, err error) {
+	var rgx *regexp.Regexp
+	if cfg.Regex != "" {
+		if rgx, err = regexp.Compile(cfg.Regex); err != nil {
+			return nil, fmt.Errorf("[PATH] invalid regular expression '%v'", cfg.Regex)
+		}
+	}
+	incl, excl := make([][]string, 0), make([][]string, 0)
+	for _, p := range cfg.Paths {
+		isExcl := strings.HasPrefix(p, "!")
+		elms, err := utils.ParsePath(strings.TrimPrefix(p, "!"))
+		if err != nil {
+			return nil, err
+		}
+		if isExcl {
+			excl = append(excl, elms)
+		} else {
+			incl = append(incl, elms)
+		}
+	}
+
+	return func(path []string) bool {
+		for _, e := range excl {
+			if utils.MatchPath(e, path) {
+				return false
+			}
+		}
+		if len(incl) <= 0 && rgx == nil {
+			return true
+		}
+		for _, e := range incl {
+			if utils.MatchPath(e, path) {
+				return true
+			}
+		}
+		if rgx != nil {
+			for _, k := range path {
+				if rgx.MatchString(k) {
+					return true
+				}
+			}
+		}
+		return false
+	}, nil
+}
 
 // yamlEncrypt yaml input is printable, so don't need input encoding. but IV and AAG may need encoding so use output encoding in these cases.
 func yamlEncrypt(
@@ -20,7 +245,11 @@ func yamlEncrypt(
 	alg encrypts.Algorithm,
 	eco, eck, ecv, eca encodes.Encoding,
 ) (err error) {
-	var key, input, output, salt, iv, aad []byte
+	var input, output []byte
+
+	if cfg.Sops {
+		return sopsEncrypt(cfg, alg, eck)
+	}
 
 	input, err = utils.Read(cfg.Input, cfg.Buffer)
 	if err != nil {
@@ -28,6 +257,38 @@ func yamlEncrypt(
 		return
 	}
 
+	docs, err := utils.ReadNodes(input)
+	if err != nil {
+		err = fmt.Errorf("[YAML][ECY][UNM]%v", err)
+		return
+	}
+
+	if docs, err = yamlEncrypted(cfg, alg, eco, eck, ecv, eca, docs); err != nil {
+		return
+	}
+
+	output, err = writeDocs(cfg, docs)
+	if err != nil {
+		err = fmt.Errorf("[YAML][ECY][MRS]%v", err)
+		return
+	}
+
+	err = writeOutput(cfg, output)
+	if err != nil {
+		err = fmt.Errorf("[YAML][ECY][OUT]%v", err)
+	}
+	return
+}
+
+// yamlEncrypted encrypt the field values of the yaml documents in memory, and add the salt and the MAC
+func yamlEncrypted(
+	cfg *cfgs.Config,
+	alg encrypts.Algorithm,
+	eco, eck, ecv, eca encodes.Encoding,
+	docs []*yaml.Node,
+) (_ []*yaml.Node, err error) {
+	var key, salt, aad []byte
+
 	if cfg.Passwd != "" {
 		pwd := cfg.Passwd
 		if cfg.Passwd == PWD_INTERACTIVE {
@@ -58,18 +319,10 @@ func yamlEncrypt(
 			return
 		}
 	} else {
-		if eck == nil || !alg.Type() {
-			key, err = utils.Read(cfg.Key, cfg.Buffer)
-			if err != nil {
-				err = fmt.Errorf("[YAML][ECY][KEY]%v", err)
-				return
-			}
-		} else {
-			key, err = utils.Read(cfg.Key, cfg.Buffer, eck)
-			if err != nil {
-				err = fmt.Errorf("[YAML][ECY][KEY]%v", err)
-				return
-			}
+		key, err = readKey(cfg, alg, eck)
+		if err != nil {
+			err = fmt.Errorf("[YAML][ECY][KEY]%v", err)
+			return
 		}
 		err = alg.PopulateKey(key)
 		if err != nil {
@@ -78,89 +331,92 @@ func yamlEncrypt(
 		}
 	}
 
-	if cfg.Iv != "" {
-		iv, err = utils.Read(cfg.Iv, cfg.Buffer, ecv)
+	if cfg.Aad != "" {
+		aad, err = utils.Read(cfg.Aad, cfg.Buffer, eca)
 		if err != nil {
-			err = fmt.Errorf("[YAML][ECY][IV]%v", err)
+			err = fmt.Errorf("[YAML][ECY][AAD]%v", err)
 			return
 		}
 	}
 
-	if cfg.Aad != "" {
-		aad, err = utils.Read(cfg.Aad, cfg.Buffer, eca)
+	sel, err := pathSelector(cfg)
+	if err != nil {
+		err = fmt.Errorf("[YAML][ECY]%v", err)
+		return
+	}
+	av2 := encrypts.V2(alg)
+	encrypt := func(doc int, path []string, node *yaml.Node) error {
+		if !sel(path) {
+			return nil
+		}
+		// the original text is encrypted, along with the type; custom tags are kept as is
+		typ, isStd := strings.CutPrefix(node.ShortTag(), "!!")
+		if !isStd {
+			typ = "str"
+		}
+		rst, err := av2.Encrypt(encrypts.EncryptRequest{Plaintext: []byte(node.Value), AAD: fieldAAD(av2, aad, doc, path)})
 		if err != nil {
-			err = fmt.Errorf("[YAML][ECY][AAD]%v", err)
-			return
+			return err
+		}
+		node.Value = encValue(alg, eco.EncodeToString(rst.Output), typ)
+		if isStd {
+			node.Tag = "!!str"
+			node.Style &^= yaml.TaggedStyle
 		}
+		return nil
 	}
 
-	encrypt := func(inp interface{}) (interface{}, error) {
-		switch typ := inp.(type) {
-		case string:
-			enc, err := alg.Encrypt([]byte(typ), iv, aad)
-			if err != nil {
-				return nil, err
-			} else if len(enc) < 1 || enc[0] == nil {
-				return nil, fmt.Errorf("[YAML][ECY] result missing")
-			}
-			return eco.EncodeToString(enc[0]), nil
-		case int:
-			enc, err := alg.Encrypt([]byte(fmt.Sprintf("%v", typ)), iv, aad)
-			if err != nil {
-				return nil, err
-			} else if len(enc) < 1 || enc[0] == nil {
-				return nil, fmt.Errorf("[YAML][ECY] result missing")
-			}
-			return eco.EncodeToString(enc[0]), nil
-		case bool:
-			enc, err := alg.Encrypt([]byte(fmt.Sprintf("%v", typ)), iv, aad)
-			if err != nil {
-				return nil, err
-			} else if len(enc) < 1 || enc[0] == nil {
-				return nil, fmt.Errorf("[YAML][ECY] result missing")
-			}
-			return eco.EncodeToString(enc[0]), nil
-		default:
-			return inp, nil
+	for d, doc := range docs {
+		err = utils.TraverseNode(doc, func(path []string, node *yaml.Node) error { return encrypt(d, path, node) })
+		if err != nil {
+			err = fmt.Errorf("[YAML][ECY][NAV]%v", err)
+			return
 		}
 	}
 
-	inp := make([]yaml.MapItem, 0)
-	err = yaml.Unmarshal(input, &inp)
+	str, err := encryptMac(docsMac(docs), av2, aad, eco)
 	if err != nil {
-		err = fmt.Errorf("[YAML][ECY][UNM]%v", err)
+		err = fmt.Errorf("[YAML][ECY][MAC]%v", err)
 		return
 	}
+	if salt != nil {
+		docs = putMeta(docs, sALT, eco.EncodeToString(salt))
+	}
+	return putMeta(docs, mAC, str), nil
+}
+
+func yamlDecrypt(
+	cfg *cfgs.Config,
+	alg encrypts.Algorithm,
+	eci, eck, ecv, ect, eca encodes.Encoding,
+) (err error) {
+	var output []byte
 
-	sec, err := utils.Traverse(inp, encrypt)
+	clr, err := yamlDecrypted(cfg, alg, eci, eck, ecv, ect, eca)
 	if err != nil {
-		err = fmt.Errorf("[YAML][ECY][NAV]%v", err)
 		return
 	}
 
-	if salt != nil {
-		sec = append(sec, yaml.MapItem{Key: sALT, Value: eco.EncodeToString(salt)})
-	}
-
-	output, err = yaml.Marshal(sec)
+	output, err = writeDocs(cfg, clr)
 	if err != nil {
-		err = fmt.Errorf("[YAML][ECY][MRS]%v", err)
+		err = fmt.Errorf("[YAML][DCY][MRS]%v", err)
 		return
 	}
 
-	err = utils.Write(cfg.Output, output)
+	err = writeOutput(cfg, output)
 	if err != nil {
-		err = fmt.Errorf("[YAML][ECY][OUT]%v", err)
+		err = fmt.Errorf("[YAML][DCY][OUT]%v", err)
 	}
 	return
 }
 
-func yamlDecrypt(
+// yamlDecrypted decrypt the field values of the yaml input in memory
+func yamlDecrypted(
 	cfg *cfgs.Config,
 	alg encrypts.Algorithm,
 	eci, eck, ecv, ect, eca encodes.Encoding,
-) (err error) {
-	var key, input, output, salt, iv, tag, aad []byte
+) (clr []*yaml.Node, err error) {
+	var key, input, salt, aad []byte
 
 	input, err = utils.Read(cfg.Input, cfg.Buffer)
 	if err != nil {
@@ -168,18 +424,25 @@ func yamlDecrypt(
 		return
 	}
 
-	inp := make([]yaml.MapItem, 0)
-	err = yaml.Unmarshal(input, &inp)
+	clr, err = utils.ReadNodes(input)
 	if err != nil {
 		err = fmt.Errorf("[YAML][DCY][UNM]%v", err)
 		return
 	}
+	if isSops(clr) {
+		err = sopsDecrypt(cfg, alg, eck, clr)
+		return
+	} else if cfg.Kms != "" {
+		err = fmt.Errorf("[YAML][DCY] option '--kms' only supported for SOPS documents")
+		return
+	}
 
-	for i, itm := range inp {
-		if itm.Key.(string) == sALT {
-			salt, err = eci.DecodeString(itm.Value.(string))
-			inp = append(inp[:i], inp[i+1:]...)
-			break
+	str, clr := takeMeta(clr, sALT)
+	sum, clr := takeMeta(clr, mAC)
+	if str != "" {
+		if salt, err = eci.DecodeString(str); err != nil {
+			err = fmt.Errorf("[YAML][DCY][SALT]%v", err)
+			return
 		}
 	}
 
@@ -202,38 +465,14 @@ func yamlDecrypt(
 		err = fmt.Errorf("[YAML][DCY][GEN] generate new key for decryption makes no sense")
 		return
 	} else {
-		if eck == nil || !alg.Type() {
-			key, err = utils.Read(cfg.Key, cfg.Buffer)
-			if err != nil {
-				err = fmt.Errorf("[YAML][DCY][KEY]%v", err)
-				return
-			}
-		} else {
-			key, err = utils.Read(cfg.Key, cfg.Buffer, eck)
-			if err != nil {
-				err = fmt.Errorf("[YAML][DCY][KEY]%v", err)
-				return
-			}
-		}
-		err = alg.PopulateKey(key)
+		key, err = readKey(cfg, alg, eck)
 		if err != nil {
-			err = fmt.Errorf("[YAML][DCY][POP]%v", err)
+			err = fmt.Errorf("[YAML][DCY][KEY]%v", err)
 			return
 		}
-	}
-
-	if cfg.Iv != "" {
-		iv, err = utils.Read(cfg.Iv, cfg.Buffer, ecv)
-		if err != nil {
-			err = fmt.Errorf("[YAML][DCY][IV]%v", err)
-			return
-		}
-	}
-
-	if cfg.Tag != "" {
-		tag, err = utils.Read(cfg.Tag, cfg.Buffer, ect)
+		err = alg.PopulateKey(key)
 		if err != nil {
-			err = fmt.Errorf("[YAML][DCY][TAG]%v", err)
+			err = fmt.Errorf("[YAML][DCY][POP]%v", err)
 			return
 		}
 	}
@@ -246,51 +485,76 @@ func yamlDecrypt(
 		}
 	}
 
-	decrypt := func(inp interface{}) (interface{}, error) {
-		switch typ := inp.(type) {
-		case string:
-			enc, err := eci.DecodeString(typ)
-			if err != nil {
-				return nil, err
-			}
-			dec, err := alg.Decrypt(enc, iv, tag, aad)
-			if err != nil {
-				return nil, err
-			} else if len(dec) < 1 || dec[0] == nil {
-				return nil, fmt.Errorf("[YAML][ECY] result missing")
+	sel, err := pathSelector(cfg)
+	if err != nil {
+		err = fmt.Errorf("[YAML][DCY]%v", err)
+		return
+	}
+	av2 := encrypts.V2(alg)
+	decrypt := func(doc int, path []string, node *yaml.Node) error {
+		if !sel(path) {
+			return nil
+		}
+		label, data, typ, wrapped := parseEncValue(node.Value)
+		if !wrapped {
+			// values encrypted since the MAC is added are all wrapped, the others are not selected
+			if sum != "" || node.ShortTag() != "!!str" {
+				return nil
 			}
+			data = node.Value
+		} else if label != encLabel(alg) {
+			return fmt.Errorf("[ALG] value encrypted by '%v', not '%v'", label, encLabel(alg))
+		}
+		enc, err := eci.DecodeString(data)
+		if err != nil {
+			return err
+		}
+		// the key paths are bound since the MAC is added
+		req := encrypts.DecryptRequest{Ciphertext: enc, AAD: aad}
+		if sum != "" {
+			req.AAD = fieldAAD(av2, aad, doc, path)
+		}
+		dec, err := av2.Decrypt(req)
+		if err != nil {
+			return err
+		}
 
-			str := string(dec[0])
-			v0, err := strconv.Atoi(str)
-			if err == nil {
-				return v0, nil
+		node.Value = string(dec.Plaintext)
+		if wrapped {
+			if strings.HasPrefix(node.Tag, "!!") {
+				node.Tag = "!!" + typ
 			}
-			v1, err := strconv.ParseBool(str)
-			if err == nil {
-				return v1, nil
+			if typ != "str" {
+				// quotes may be forced by the ciphertext, e.g. in flow sequences, but change the type of the plaintext
+				node.Style &^= yaml.SingleQuotedStyle | yaml.DoubleQuotedStyle
 			}
-
-			return str, nil
-		default:
-			return inp, nil
+			return nil
 		}
+		// values encrypted by earlier versions carry no type
+		if _, err = strconv.Atoi(node.Value); err == nil {
+			node.Tag = "!!int"
+		} else if v, err := strconv.ParseBool(node.Value); err == nil {
+			node.Tag, node.Value = "!!bool", strconv.FormatBool(v)
+		}
+		return nil
 	}
 
-	clr, err := utils.Traverse(inp, decrypt)
-	if err != nil {
-		err = fmt.Errorf("[YAML][DCY][NAV]%v", err)
-		return
-	}
-
-	output, err = yaml.Marshal(clr)
-	if err != nil {
-		err = fmt.Errorf("[YAML][DCY][MRS]%v", err)
+	// documents encrypted by earlier versions carry no MAC, nor any wrapped value
+	if sum != "" {
+		if err = verifyMac(docsMac(clr), av2, aad, eci, sum); err != nil {
+			err = fmt.Errorf("[YAML][DCY][MAC]%v", err)
+			return
+		}
+	} else if hasWrapped(clr) {
+		err = fmt.Errorf("[YAML][DCY][MAC] MAC missing, the document has been modified")
 		return
 	}
-
-	err = utils.Write(cfg.Output, output)
-	if err != nil {
-		err = fmt.Errorf("[YAML][DCY][OUT]%v", err)
+	for d, doc := range clr {
+		err = utils.TraverseNode(doc, func(path []string, node *yaml.Node) error { return decrypt(d, path, node) })
+		if err != nil {
+			err = fmt.Errorf("[YAML][DCY][NAV]%v", err)
+			return
+		}
 	}
 	return
 }
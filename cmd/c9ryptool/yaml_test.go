@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+const yAMLTEST = "db:\n  user: admin\n  password: abcd1234\nport: 5432\n"
+
+// yamlTestEncrypt encrypt the values of yAMLTEST selected by 'paths', all if none, with a new key of 'name'
+// written to 'dir'
+func yamlTestEncrypt(t *testing.T, dir, name string, paths ...string) (*cfgs.Config, []*yaml.Node) {
+	cfg := &cfgs.Config{Key: filepath.Join(dir, "yaml.key"), Genkey: true, Paths: paths, Buffer: cfgs.BUFFER}
+	docs, err := utils.ReadNodes([]byte(yAMLTEST))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64 := encodes.Get("base64")
+	if docs, err = yamlEncrypted(cfg, encrypts.New(name), b64, nil, nil, nil, docs); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Genkey = false
+	return cfg, docs
+}
+
+// yamlTestDecrypt decrypt the documents by the key of 'cfg'
+func yamlTestDecrypt(t *testing.T, cfg *cfgs.Config, name string, docs []*yaml.Node) ([]*yaml.Node, error) {
+	out, err := utils.WriteNodes(docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Input = filepath.Join(filepath.Dir(cfg.Key), "enc.yaml")
+	if err = os.WriteFile(cfg.Input, out, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return yamlDecrypted(cfg, encrypts.New(name), encodes.Get("base64"), nil, nil, nil, nil)
+}
+
+// yamlTestValues the value nodes of the key paths joined by '.'
+func yamlTestValues(docs []*yaml.Node) map[string]*yaml.Node {
+	vals := make(map[string]*yaml.Node)
+	for _, doc := range docs {
+		utils.TraverseNode(doc, func(path []string, node *yaml.Node) error {
+			vals[strings.Join(path, ".")] = node
+			return nil
+		})
+	}
+	return vals
+}
+
+func TestYamlMac(t *testing.T) {
+	for _, name := range []string{"AES-256-GCM", "RSA-2048-OAEP-SHA256"} {
+		cfg, docs := yamlTestEncrypt(t, t.TempDir(), name)
+		clr, err := yamlTestDecrypt(t, cfg, name, docs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vals := yamlTestValues(clr); vals["db.password"].Value != "abcd1234" || vals["port"].ShortTag() != "!!int" {
+			t.Fatalf("TestYamlMac() %v unexpected decrypted values %v", name, vals)
+		}
+
+		// swapped values
+		cfg, docs = yamlTestEncrypt(t, t.TempDir(), name)
+		vals := yamlTestValues(docs)
+		vals["db.user"].Value, vals["db.password"].Value = vals["db.password"].Value, vals["db.user"].Value
+		if _, err = yamlTestDecrypt(t, cfg, name, docs); err == nil || !strings.Contains(err.Error(), "MAC") {
+			t.Fatalf("TestYamlMac() %v expecting MAC error decrypting swapped values, got %v", name, err)
+		}
+
+		// swapped values with the MAC removed
+		_, docs = takeMeta(docs, mAC)
+		if _, err = yamlTestDecrypt(t, cfg, name, docs); err == nil || !strings.Contains(err.Error(), "MAC missing") {
+			t.Fatalf("TestYamlMac() %v expecting error decrypting without the MAC, got %v", name, err)
+		}
+
+		// removed value
+		cfg, docs = yamlTestEncrypt(t, t.TempDir(), name)
+		root := rootMap(docs[0])
+		root.Content = root.Content[2:]
+		if _, err = yamlTestDecrypt(t, cfg, name, docs); err == nil || !strings.Contains(err.Error(), "MAC") {
+			t.Fatalf("TestYamlMac() %v expecting MAC error decrypting removed value, got %v", name, err)
+		}
+	}
+	fmt.Println("TestYamlMac() test okay")
+}
+
+func TestYamlAad(t *testing.T) {
+	name := "AES-256-GCM"
+	cfg, docs := yamlTestEncrypt(t, t.TempDir(), name)
+	vals := yamlTestValues(docs)
+	vals["db.user"].Value, vals["db.password"].Value = vals["db.password"].Value, vals["db.user"].Value
+
+	// a MAC valid for the swapped values, the values are still bound to their key paths
+	alg := encrypts.New(name)
+	key, err := readKey(cfg, alg, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if err = alg.PopulateKey(key); err != nil {
+		t.Fatal(err)
+	}
+	_, docs = takeMeta(docs, mAC)
+	sum, err := encryptMac(docsMac(docs), encrypts.V2(alg), nil, encodes.Get("base64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs = putMeta(docs, mAC, sum)
+	if _, err = yamlTestDecrypt(t, cfg, name, docs); err == nil || !strings.Contains(err.Error(), "[NAV]") {
+		t.Fatalf("TestYamlAad() expecting error decrypting values moved to other key paths, got %v", err)
+	}
+	fmt.Printf("TestYamlAad() test okay: %v\n", err)
+}
@@ -10,18 +10,21 @@ import (
 	"sea9.org/go/c9ryptool/pkg/cfgs"
 	"sea9.org/go/c9ryptool/pkg/encodes"
 	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/keystore"
 )
 
 const CMD_VERSION = 1
 const CMD_GENKEY = 2
 const CMD_PUBKEY = 3
 const CMD_SPLIT = 4
+const CMD_KEYS = 5
 
 var ENVIVARS = []string{
 	"C9_BUFFER",
 	"C9_VERBOSE",
 	"C9_ENCRYPTION",
 	"C9_ENCODING",
+	"C9_KEYSTORE",
 }
 
 func usage() string {
@@ -43,6 +46,16 @@ func usage() string {
 		"   [-o FILE | --out0=FILE]\n" +
 		"   [-p FILE | --out1=FILE]\n" +
 		"   [-l LEN | --len=LEN]\n\n" +
+		"  [keys] [add | list | export | remove] {NAME}\n" +
+		"   {--keystore=FILE}\n" +
+		"   {--identity=FILE | --recipient=FILE | --password=PASS}\n" +
+		"   {-a ALGR | --algorithm=ALGR}\n" +
+		"   {-i FILE | --in=FILE}\n" +
+		"   {-o FILE | --out0=FILE}\n" +
+		"   {-p FILE | --out1=FILE}\n" +
+		"   {-n ENC | --encoding=ENC}\n" +
+		"   {--label=KEY=VALUE}\n" +
+		"   {--state=STATE}\n\n" +
 		"  all commands\n" +
 		"   {-b SIZE | --buffer=SIZE}\n" +
 		"   {-v | --verbose}"
@@ -61,6 +74,7 @@ func parse(args []string) (cfg *cfgs.Config, err error) {
 		"genkey",  // 2
 		"pubkey",  // 3
 		"split",   // 4
+		"keys",    // 5
 	})
 	cfg.Algr = encrypts.Default()
 
@@ -73,6 +87,20 @@ func parse(args []string) (cfg *cfgs.Config, err error) {
 		return
 	}
 
+	start := 2
+	if cfg.Cmd() == CMD_KEYS {
+		if len(args) < 3 || strings.HasPrefix(args[2], "-") {
+			err = fmt.Errorf("[CONF] Missing keys action, expecting one of %v", kEYSACTIONS)
+			return
+		}
+		cfg.Action = args[2]
+		start = 3
+		if len(args) > 3 && !strings.HasPrefix(args[3], "-") {
+			cfg.Name = args[3]
+			start = 4
+		}
+	}
+
 	var val, lgh int
 	for _, enm := range ENVIVARS {
 		env := os.Getenv(enm)
@@ -95,11 +123,13 @@ func parse(args []string) (cfg *cfgs.Config, err error) {
 				cfg.Algr = env
 			case "C9_ENCODING":
 				cfg.Encd = env
+			case "C9_KEYSTORE":
+				cfg.Keystore = env
 			}
 		}
 	}
 
-	for i := 2; i < len(args); i++ {
+	for i := start; i < len(args); i++ {
 		lgh = 7
 		switch {
 		case args[i] == "-v" || args[i] == "--verbose":
@@ -231,6 +261,48 @@ func parse(args []string) (cfg *cfgs.Config, err error) {
 			} else {
 				cfg.Key = args[i][7:]
 			}
+		case strings.HasPrefix(args[i], "--keystore="):
+			if len(args[i]) <= 11 {
+				err = fmt.Errorf("[CONF] Missing keystore filename")
+				return
+			} else {
+				cfg.Keystore = args[i][11:]
+			}
+		case strings.HasPrefix(args[i], "--identity="):
+			if len(args[i]) <= 11 {
+				err = fmt.Errorf("[CONF] Missing identity filename")
+				return
+			} else {
+				cfg.Identity = args[i][11:]
+			}
+		case strings.HasPrefix(args[i], "--recipient="):
+			if len(args[i]) <= 12 {
+				err = fmt.Errorf("[CONF] Missing recipient filename")
+				return
+			} else {
+				cfg.Rcpt = args[i][12:]
+			}
+		case strings.HasPrefix(args[i], "--password="):
+			if len(args[i]) <= 11 {
+				err = fmt.Errorf("[CONF] Missing password")
+				return
+			} else {
+				cfg.Passwd = args[i][11:]
+			}
+		case strings.HasPrefix(args[i], "--label="):
+			if len(args[i]) <= 8 {
+				err = fmt.Errorf("[CONF] Missing label")
+				return
+			} else {
+				cfg.Labels = append(cfg.Labels, args[i][8:])
+			}
+		case strings.HasPrefix(args[i], "--state="):
+			if len(args[i]) <= 8 {
+				err = fmt.Errorf("[CONF] Missing key state")
+				return
+			} else {
+				cfg.State = args[i][8:]
+			}
 		default:
 			err = fmt.Errorf("[CONF] Invalid option '%v'", args[i])
 			return
@@ -244,12 +316,16 @@ func validate(cfg *cfgs.Config) (err error) {
 	errs := make([]error, 0)
 
 	var algTyp bool
-	if cfg.Cmd() != CMD_SPLIT {
-		if algTyp, err = encrypts.Validate(cfg.Algr, 1); err != nil {
+	if cfg.Cmd() != CMD_SPLIT && (cfg.Cmd() != CMD_KEYS || cfg.Action == KEYS_ADD) {
+		typ := 1
+		if cfg.Cmd() == CMD_KEYS {
+			typ = 0
+		}
+		if algTyp, err = encrypts.Validate(cfg.Algr, typ); err != nil {
 			errs = append(errs, err)
 		}
 		if cfg.Encd != "" {
-			if _, err = encodes.Validate(cfg.Encd, 1); err != nil {
+			if err = encodes.Validate(cfg.Encd); err != nil {
 				errs = append(errs, err)
 			}
 		}
@@ -292,6 +368,67 @@ func validate(cfg *cfgs.Config) (err error) {
 		if cfg.Key == "" {
 			errs = append(errs, fmt.Errorf("[VLDT] missing 2nd output filename"))
 		}
+	case CMD_KEYS:
+		algTyp = true // the public key output of 'export' depends on the key in the keystore
+		switch cfg.Action {
+		case KEYS_ADD:
+			for _, l := range cfg.Labels {
+				if idx := strings.Index(l, "="); idx <= 0 {
+					errs = append(errs, fmt.Errorf("[VLDT] invalid label '%v', expecting 'KEY=VALUE'", l))
+				}
+			}
+			if cfg.State != "" && cfg.State != keystore.STATE_ACTIVE && cfg.State != keystore.STATE_RETIRED {
+				errs = append(errs, fmt.Errorf("[VLDT] invalid key state '%v', expecting '%v' or '%v'", cfg.State, keystore.STATE_ACTIVE, keystore.STATE_RETIRED))
+			}
+			if cfg.Output != "" || cfg.Key != "" {
+				errs = append(errs, fmt.Errorf("[VLDT] output files only applicable to 'export'"))
+			}
+		case KEYS_EXPORT:
+			if cfg.Output == "" && cfg.Key == "" {
+				errs = append(errs, fmt.Errorf("[VLDT] missing output key filename"))
+			}
+			if cfg.Encd != "" {
+				if err = encodes.Validate(cfg.Encd); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			if cfg.Key != "" {
+				if _, err = os.Stat(cfg.Key); err == nil {
+					errs = append(errs, fmt.Errorf("output file '%v' already exists", cfg.Key))
+				} else if !errors.Is(err, os.ErrNotExist) {
+					err = fmt.Errorf("[VLDT] %v", err)
+					return
+				} else {
+					err = nil
+				}
+			}
+		case KEYS_LIST, KEYS_REMOVE:
+		default:
+			errs = append(errs, fmt.Errorf("[VLDT] invalid keys action '%v', expecting one of %v", cfg.Action, kEYSACTIONS))
+		}
+		if cfg.Action != KEYS_LIST && cfg.Name == "" {
+			errs = append(errs, fmt.Errorf("[VLDT] missing key name"))
+		}
+		if cfg.Action != KEYS_ADD && (cfg.Input != "" || len(cfg.Labels) > 0 || cfg.State != "") {
+			errs = append(errs, fmt.Errorf("[VLDT] input key file, labels and state only applicable to 'add'"))
+		}
+		if cfg.Rcpt != "" && cfg.Identity != "" {
+			errs = append(errs, fmt.Errorf("[VLDT] recipient and identity are mutually exclusive"))
+		}
+		if cfg.Passwd != "" && (cfg.Rcpt != "" || cfg.Identity != "") {
+			errs = append(errs, fmt.Errorf("[VLDT] password cannot be used with recipient or identity"))
+		}
+		for _, f := range []string{cfg.Identity, cfg.Rcpt} {
+			if f == "" {
+				continue
+			} else if _, err = os.Stat(f); errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("key file '%v' does not exist", f))
+			} else if err != nil {
+				err = fmt.Errorf("[VLDT] %v", err)
+				return
+			}
+		}
+		err = nil
 	}
 
 	if cfg.Input != "" {
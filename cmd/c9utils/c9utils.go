@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"sea9.org/go/c9ryptool/pkg/cfgs"
 	"sea9.org/go/c9ryptool/pkg/encodes"
@@ -19,13 +20,21 @@ func list(typ int) {
 	for i, n := range encrypts.List(typ) {
 		a := encrypts.Get(n)
 		if a.Type() {
-			fmt.Printf(" %2v sym  %v\n", i+1, n)
+			fmt.Printf(" %2v sym  %v%v\n", i+1, n, aliases(encrypts.Aliases(n)))
 		} else {
-			fmt.Printf(" %2v asym %v\n", i+1, n)
+			fmt.Printf(" %2v asym %v%v\n", i+1, n, aliases(encrypts.Aliases(n)))
 		}
 	}
 }
 
+// aliases format the aliases of an algorithm for listing
+func aliases(list []string) string {
+	if len(list) <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%v)", strings.Join(list, ", "))
+}
+
 func main() {
 	cfg, err := parse(os.Args)
 	if err != nil {
@@ -100,6 +109,26 @@ func main() {
 			}
 		}
 
+	case CMD_KEYS:
+		err = validate(cfg)
+		if err != nil {
+			log.Fatalf("[MAIN]%v", err)
+		}
+
+		if cfg.IsList() {
+			list(0)
+			return
+		}
+
+		err = keys(cfg)
+		if err == nil && cfg.Action != KEYS_LIST {
+			if cfg.Verbose {
+				fmt.Printf("%v finished %v key '%v' of keystore '%v'\n", desc(), cfg.Action, cfg.Name, cfg.Keystore)
+			} else {
+				fmt.Printf("%v finished %v key '%v'\n", desc(), cfg.Action, cfg.Name)
+			}
+		}
+
 	default:
 		err = fmt.Errorf(" unsupported command '%v'", cfg.Cmd())
 	}
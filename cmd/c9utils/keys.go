@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/keystore"
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+// actions of the 'keys' command
+const (
+	KEYS_ADD    = "add"
+	KEYS_LIST   = "list"
+	KEYS_EXPORT = "export"
+	KEYS_REMOVE = "remove"
+)
+
+var kEYSACTIONS = []string{KEYS_ADD, KEYS_LIST, KEYS_EXPORT, KEYS_REMOVE}
+
+func keys(cfg *cfgs.Config) (err error) {
+	if cfg.Keystore == "" {
+		cfg.Keystore = keystore.DefaultPath()
+	}
+	path := cfg.Keystore
+
+	var ks *keystore.Keystore
+	if cfg.Action == KEYS_ADD && !keystore.Exists(path) {
+		if ks, err = create(cfg, path); err != nil {
+			return
+		}
+	} else if ks, err = open(cfg, path); err != nil {
+		return
+	}
+
+	switch cfg.Action {
+	case KEYS_ADD:
+		k := &keystore.Key{Name: cfg.Name, State: cfg.State}
+		if k.Algorithm, err = encrypts.Resolve(cfg.Algr); err != nil {
+			return fmt.Errorf("[KEYS]%v", err)
+		}
+		if len(cfg.Labels) > 0 {
+			k.Labels = make(map[string]string)
+			for _, l := range cfg.Labels {
+				kv := strings.SplitN(l, "=", 2)
+				k.Labels[kv[0]] = kv[1]
+			}
+		}
+		if cfg.Input != "" {
+			alg := encrypts.Get(k.Algorithm)
+			if ecd := encodes.Get(encodes.Parse(cfg.Encd)); ecd != nil && alg.Type() {
+				k.Key, err = utils.Read(cfg.Input, cfg.Buffer, ecd)
+			} else {
+				k.Key, err = utils.Read(cfg.Input, cfg.Buffer)
+			}
+			if err != nil {
+				return fmt.Errorf("[KEYS][INP]%v", err)
+			}
+		}
+		if err = ks.Add(k); err != nil {
+			return
+		}
+		err = ks.Save()
+
+	case KEYS_LIST:
+		for i, k := range ks.List() {
+			state := k.State
+			if state == "" {
+				state = keystore.STATE_ACTIVE
+			}
+			lbls := make([]string, 0, len(k.Labels))
+			for n, v := range k.Labels {
+				lbls = append(lbls, fmt.Sprintf("%v=%v", n, v))
+			}
+			sort.Strings(lbls)
+			fmt.Printf(" %2v %v %v, %v, created %v %v\n", i+1, k.Name, k.Algorithm, state, k.Created.Format("2006-01-02"), lbls)
+		}
+
+	case KEYS_EXPORT:
+		var k *keystore.Key
+		var alg encrypts.Algorithm
+		if k, err = ks.Get(cfg.Name); err != nil {
+			return
+		}
+		if alg, err = k.New(); err != nil {
+			return
+		}
+		if cfg.Output != "" {
+			if ecd := encodes.Get(encodes.Parse(cfg.Encd)); ecd != nil && alg.Type() {
+				err = utils.Write(cfg.Output, k.Key, ecd)
+			} else {
+				err = utils.Write(cfg.Output, k.Key)
+			}
+			if err != nil {
+				return fmt.Errorf("[KEYS][KEY]%v", err)
+			}
+		}
+		if cfg.Key != "" {
+			aslg, ok := alg.(encrypts.AsymAlgorithm)
+			if !ok || alg.Type() {
+				return fmt.Errorf("[KEYS][PUB] key '%v' is not asymmetric", k.Name)
+			}
+			if err = utils.Write(cfg.Key, aslg.GetPublicKey()); err != nil {
+				return fmt.Errorf("[KEYS][PUB]%v", err)
+			}
+		}
+
+	case KEYS_REMOVE:
+		if err = ks.Remove(cfg.Name); err != nil {
+			return
+		}
+		err = ks.Save()
+	}
+	return
+}
+
+// create create a new keystore locked with the recipient if given, otherwise with a master password
+func create(cfg *cfgs.Config, path string) (ks *keystore.Keystore, err error) {
+	var lock keystore.Lock
+	if cfg.Rcpt != "" {
+		if lock.Recipient, err = utils.Read(cfg.Rcpt, cfg.Buffer); err != nil {
+			return nil, fmt.Errorf("[RECIPIENT]%v", err)
+		}
+	} else if lock.Password, err = password(cfg, true); err != nil {
+		return
+	}
+	if ks, err = keystore.Create(path, lock); err == nil && cfg.Verbose {
+		fmt.Printf("%v creating keystore '%v'\n", desc(), path)
+	}
+	return
+}
+
+// open open the keystore, unlocked by the identity if given, otherwise by the master password
+func open(cfg *cfgs.Config, path string) (ks *keystore.Keystore, err error) {
+	var lock keystore.Lock
+	if cfg.Identity != "" {
+		if lock.Identity, err = utils.Read(cfg.Identity, cfg.Buffer); err != nil {
+			return nil, fmt.Errorf("[IDENTITY]%v", err)
+		}
+	} else if lock.Password, err = password(cfg, false); err != nil {
+		return
+	}
+	return keystore.Open(path, lock)
+}
+
+// password master password from '--password=', the environment variable C9_KEYSTORE_PASSWORD, or input
+// interactively, confirmed if 'isNew'
+func password(cfg *cfgs.Config, isNew bool) (pwd string, err error) {
+	if cfg.Passwd != "" {
+		return cfg.Passwd, nil
+	} else if pwd = os.Getenv(keystore.PASSWORD); pwd != "" {
+		return
+	}
+	if !isNew {
+		if pwd, err = utils.Prompt("", "Enter keystore password: "); err != nil {
+			err = fmt.Errorf("[PWD]%v", err)
+		}
+		return
+	}
+
+	if pwd, err = utils.Prompt("", "Enter new keystore password: "); err != nil {
+		return "", fmt.Errorf("[PWD]%v", err)
+	}
+	cfm, err := utils.Prompt("", "Confirm keystore password: ")
+	if err != nil {
+		return "", fmt.Errorf("[PWD]%v", err)
+	} else if cfm != pwd {
+		return "", fmt.Errorf("[PWD] passwords mismatched")
+	} else if pwd == "" {
+		return "", fmt.Errorf("[PWD] empty password: ")
+	}
+	return
+}
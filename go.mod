@@ -5,8 +5,10 @@ go 1.25
 require (
 	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.4.0
 	github.com/ecies/go/v2 v2.0.11
+	github.com/klauspost/compress v1.16.0
 	golang.org/x/crypto v0.46.0
 	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
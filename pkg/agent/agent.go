@@ -0,0 +1,376 @@
+// Package agent key agent keeping unlocked keys in memory, serving encryption, decryption and signing requests over
+// a Unix domain socket, so the keys (and the keys generated from passwords) never touch the filesystem.
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"sea9.org/go/c9ryptool/pkg/c9crypt"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+)
+
+// SOCKET environment variable of the agent socket path
+const SOCKET = "C9_AGENT_SOCK"
+
+// DEFAULT name of the key used if no name is given
+const DEFAULT = "default"
+
+// MAX_REQUEST maximum size of a request in bytes
+const MAX_REQUEST = 64 << 20
+
+// operations
+const (
+	OP_ADD     = "add"
+	OP_REMOVE  = "remove"
+	OP_LIST    = "list"
+	OP_ENCRYPT = "encrypt"
+	OP_DECRYPT = "decrypt"
+	OP_SIGN    = "sign"
+	OP_STOP    = "stop"
+)
+
+// Request request to the agent, one request per connection
+type Request struct {
+	Op        string        `json:"op"`
+	Name      string        `json:"name,omitempty"`      // key name
+	Algorithm string        `json:"algorithm,omitempty"` // encryption algorithm of the key to add
+	Key       []byte        `json:"key,omitempty"`       // raw symmetric key, or PEM encoded asymmetric key to add
+	Password  string        `json:"password,omitempty"`  // key-generating password to add, instead of 'Key'
+	SaltLen   int           `json:"salt,omitempty"`      // length of salt for generating keys from the password
+	Timeout   time.Duration `json:"timeout,omitempty"`   // lifetime of the key to add, 0 - agent default, < 0 - never expire
+	Data      []byte        `json:"data,omitempty"`      // input of encryption / decryption / signing
+	AAD       []byte        `json:"aad,omitempty"`       // additional authenticated data
+}
+
+// Response response from the agent
+type Response struct {
+	Error string    `json:"error,omitempty"`
+	Data  []byte    `json:"data,omitempty"`
+	Keys  []KeyInfo `json:"keys,omitempty"`
+}
+
+// KeyInfo details of the keys kept by the agent, without the keys themselves
+type KeyInfo struct {
+	Name      string    `json:"name"`
+	Algorithm string    `json:"algorithm"`
+	Password  bool      `json:"password"`          // 'true' if keys are generated from a password
+	Expires   time.Time `json:"expires,omitempty"` // zero if never expire
+}
+
+// SocketPath path of the agent socket, from the environment variable C9_AGENT_SOCK if set
+func SocketPath() string {
+	if path := os.Getenv(SOCKET); path != "" {
+		return path
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "c9agent.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("c9agent-%v", os.Getuid()), "c9agent.sock")
+}
+
+// entry an unlocked key
+type entry struct {
+	info   KeyInfo
+	key    []byte
+	passwd string
+	salt   int
+	timer  *time.Timer
+}
+
+// wipe clear the key material
+func (e *entry) wipe() {
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	for i := range e.key {
+		e.key[i] = 0
+	}
+	e.key, e.passwd = nil, ""
+}
+
+// Agent the key agent
+type Agent struct {
+	timeout time.Duration // default lifetime of the keys, 0 means never expire
+	lock    sync.Mutex
+	keys    map[string]*entry
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// New create a new agent, keys expire after 'timeout' by default, 0 means never expire.
+func New(timeout time.Duration) *Agent {
+	return &Agent{
+		timeout: timeout,
+		keys:    make(map[string]*entry),
+		stop:    make(chan struct{}),
+	}
+}
+
+// Listen listen on the given socket path, accessible by the current user only. The directory of the socket is
+// created if not exist, and must not be accessible by other users, so the socket is never exposed before its mode
+// is set. Stale socket files are removed.
+func Listen(path string) (lsnr net.Listener, err error) {
+	dir := filepath.Dir(path)
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("[AGENT] %v", err)
+	}
+	if info, e := os.Stat(dir); e != nil {
+		return nil, fmt.Errorf("[AGENT] %v", e)
+	} else if info.Mode().Perm()&0077 != 0 && runtime.GOOS != "windows" {
+		return nil, fmt.Errorf("[AGENT] directory '%v' accessible by other users (%v), 0700 expected", dir, info.Mode().Perm())
+	}
+	if conn, e := net.Dial("unix", path); e == nil {
+		conn.Close()
+		return nil, fmt.Errorf("[AGENT] agent already listening on '%v'", path)
+	}
+	if err = os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("[AGENT] %v", err)
+	}
+	if lsnr, err = net.Listen("unix", path); err != nil {
+		return nil, fmt.Errorf("[AGENT] %v", err)
+	}
+	if err = os.Chmod(path, 0600); err != nil {
+		lsnr.Close()
+		return nil, fmt.Errorf("[AGENT] %v", err)
+	}
+	return
+}
+
+// Serve serve the requests until the listener is closed or the 'stop' request is received, all keys are wiped
+// before returning.
+func (a *Agent) Serve(lsnr net.Listener) (err error) {
+	defer a.removeAll()
+	go func() {
+		<-a.stop
+		lsnr.Close()
+	}()
+
+	for {
+		conn, e := lsnr.Accept()
+		if e != nil {
+			select {
+			case <-a.stop:
+				return
+			default:
+			}
+			if errors.Is(e, net.ErrClosed) {
+				return
+			}
+			return fmt.Errorf("[AGENT] %v", e)
+		}
+		if e = peerCheck(conn); e != nil {
+			conn.Close() // connections of other users
+			continue
+		}
+		go a.handle(conn)
+	}
+}
+
+// Stop stop serving.
+func (a *Agent) Stop() {
+	a.once.Do(func() {
+		close(a.stop)
+	})
+}
+
+func (a *Agent) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Minute))
+
+	var req Request
+	var rsp *Response
+	if err := json.NewDecoder(io.LimitReader(conn, MAX_REQUEST)).Decode(&req); err != nil {
+		rsp = &Response{Error: fmt.Sprintf("[AGENT] invalid request: %v", err)}
+	} else {
+		rsp = a.Handle(&req)
+	}
+	json.NewEncoder(conn).Encode(rsp)
+}
+
+// Handle process the given request.
+func (a *Agent) Handle(req *Request) (rsp *Response) {
+	rsp = &Response{}
+	var err error
+	name := req.Name
+	if name == "" {
+		name = DEFAULT
+	}
+
+	switch req.Op {
+	case OP_ADD:
+		err = a.add(name, req)
+	case OP_REMOVE:
+		if req.Name == "" {
+			a.removeAll()
+		} else {
+			err = a.remove(name)
+		}
+	case OP_LIST:
+		rsp.Keys = a.list()
+	case OP_ENCRYPT, OP_DECRYPT:
+		rsp.Data, err = a.crypt(name, req)
+	case OP_SIGN:
+		rsp.Data, err = a.sign(name, req.Data)
+	case OP_STOP:
+		a.Stop()
+	default:
+		err = fmt.Errorf("[AGENT] unsupported operation '%v'", req.Op)
+	}
+	if err != nil {
+		rsp.Error = err.Error()
+	}
+	return
+}
+
+func (a *Agent) add(name string, req *Request) (err error) {
+	algr, err := encrypts.Resolve(req.Algorithm)
+	if err != nil {
+		return fmt.Errorf("[AGENT]%v", err)
+	}
+	if (req.Key == nil) == (req.Password == "") {
+		return fmt.Errorf("[AGENT] either a key or a password is required")
+	}
+	alg := encrypts.New(algr)
+	if req.Key != nil {
+		if err = alg.PopulateKey(req.Key); err != nil {
+			return fmt.Errorf("[AGENT][KEY]%v", err)
+		}
+	} else if !alg.Type() {
+		return fmt.Errorf("[AGENT] cannot generate keys from password for asymmetric algorithm '%v'", algr)
+	}
+
+	ent := &entry{
+		info:   KeyInfo{Name: name, Algorithm: algr, Password: req.Password != ""},
+		key:    bytes.Clone(req.Key),
+		passwd: req.Password,
+		salt:   req.SaltLen,
+	}
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = a.timeout
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if old, ok := a.keys[name]; ok {
+		old.wipe()
+	}
+	if timeout > 0 {
+		ent.info.Expires = time.Now().Add(timeout)
+		ent.timer = time.AfterFunc(timeout, func() {
+			a.lock.Lock()
+			defer a.lock.Unlock()
+			if a.keys[name] == ent {
+				ent.wipe()
+				delete(a.keys, name)
+			}
+		})
+	}
+	a.keys[name] = ent
+	return
+}
+
+func (a *Agent) remove(name string) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	ent, ok := a.keys[name]
+	if !ok {
+		return fmt.Errorf("[AGENT] key '%v' not found", name)
+	}
+	ent.wipe()
+	delete(a.keys, name)
+	return nil
+}
+
+func (a *Agent) removeAll() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for name, ent := range a.keys {
+		ent.wipe()
+		delete(a.keys, name)
+	}
+}
+
+func (a *Agent) list() (list []KeyInfo) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	list = make([]KeyInfo, 0, len(a.keys))
+	for _, ent := range a.keys {
+		list = append(list, ent.info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return
+}
+
+// get copy the key material of the given key, so it can be used after the key is removed or expired
+func (a *Agent) get(name string) (ent entry, err error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	e, ok := a.keys[name]
+	if !ok {
+		return ent, fmt.Errorf("[AGENT] key '%v' not found", name)
+	}
+	ent = entry{info: e.info, key: bytes.Clone(e.key), passwd: e.passwd, salt: e.salt}
+	return
+}
+
+// crypt encrypt or decrypt, the outputs are the same as the 'encrypt' / 'decrypt' commands
+func (a *Agent) crypt(name string, req *Request) ([]byte, error) {
+	ent, err := a.get(name)
+	if err != nil {
+		return nil, err
+	}
+	defer ent.wipe()
+
+	opts := c9crypt.Options{
+		Algorithm: ent.info.Algorithm,
+		Key:       ent.key,
+		Password:  ent.passwd,
+		SaltLen:   ent.salt,
+		AAD:       req.AAD,
+	}
+	var out bytes.Buffer
+	if req.Op == OP_ENCRYPT {
+		err = c9crypt.Encrypt(context.Background(), bytes.NewReader(req.Data), &out, opts)
+	} else {
+		err = c9crypt.Decrypt(context.Background(), bytes.NewReader(req.Data), &out, opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("[AGENT]%v", err)
+	}
+	return out.Bytes(), nil
+}
+
+// sign HMAC-SHA256 of 'dat' using the given symmetric key
+func (a *Agent) sign(name string, dat []byte) ([]byte, error) {
+	ent, err := a.get(name)
+	if err != nil {
+		return nil, err
+	}
+	defer ent.wipe()
+
+	if ent.key == nil {
+		return nil, fmt.Errorf("[AGENT] signing not supported by password-generated key '%v'", name)
+	} else if alg := encrypts.Get(ent.info.Algorithm); alg == nil || !alg.Type() {
+		return nil, fmt.Errorf("[AGENT] signing not supported by asymmetric key '%v'", name)
+	}
+	mac := hmac.New(sha256.New, ent.key)
+	mac.Write(dat)
+	return mac.Sum(nil), nil
+}
@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sea9.org/go/c9ryptool/pkg/c9crypt"
+	"sea9.org/go/c9ryptool/pkg/encrypts/sym"
+)
+
+func start(t *testing.T, timeout time.Duration) (*Client, chan error) {
+	// unix socket paths are limited in length, t.TempDir() may be too long
+	dir, err := os.MkdirTemp("", "c9a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "agent.sock")
+	lsnr, err := Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("unexpected socket mode %v", info.Mode())
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- New(timeout).Serve(lsnr)
+	}()
+	return NewClient(path), done
+}
+
+func TestAgent(t *testing.T) {
+	clnt, done := start(t, 0)
+	key, _ := sym.Generate(32)
+	plain := []byte("HelloHowAreYou?I'mFineThankYouVeryMuch!")
+
+	if err := clnt.Add("k1", "AES-256-GCM", key, "", 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := clnt.Add("", "chacha", nil, "abcd1234", 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := clnt.List()
+	if err != nil || len(keys) != 2 || keys[0].Name != DEFAULT || !keys[0].Password || keys[1].Algorithm != "AES-256-GCM" {
+		t.Fatalf("TestAgent() unexpected keys %v %v", keys, err)
+	}
+
+	// outputs compatible with c9crypt, i.e. the 'encrypt' / 'decrypt' commands
+	enc, err := clnt.Encrypt("k1", plain, []byte("hdr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err = c9crypt.Decrypt(context.Background(), bytes.NewReader(enc), &out, c9crypt.Options{Algorithm: "AES-256-GCM", Key: key, AAD: []byte("hdr")})
+	if err != nil || !bytes.Equal(out.Bytes(), plain) {
+		t.Fatalf("TestAgent() decrypt with c9crypt failed: %v", err)
+	}
+
+	enc, err = clnt.Encrypt("", plain, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := clnt.Decrypt(DEFAULT, enc, nil)
+	if err != nil || !bytes.Equal(dec, plain) {
+		t.Fatalf("TestAgent() round trip with password failed: %v", err)
+	}
+
+	sig, err := clnt.Sign("k1", plain)
+	if err != nil || len(sig) != 32 {
+		t.Fatalf("TestAgent() sign failed: %v", err)
+	}
+	if _, err = clnt.Sign(DEFAULT, plain); err == nil {
+		t.Fatal("TestAgent() expecting error signing with password-generated key")
+	}
+
+	if err = clnt.Remove("k1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = clnt.Encrypt("k1", plain, nil); err == nil {
+		t.Fatal("TestAgent() expecting error using removed key")
+	}
+
+	if err = clnt.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println("TestAgent() test okay")
+}
+
+func TestTimeout(t *testing.T) {
+	clnt, _ := start(t, time.Hour)
+	defer clnt.Stop()
+	key, _ := sym.Generate(32)
+
+	if err := clnt.Add("short", "AES-256-GCM", key, "", 0, 100*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := clnt.Add("long", "AES-256-GCM", key, "", 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	keys, _ := clnt.List()
+	if len(keys) != 1 || keys[0].Name != "long" || keys[0].Expires.IsZero() {
+		t.Fatalf("TestTimeout() unexpected keys %v", keys)
+	}
+	_, err := clnt.Encrypt("short", []byte("top secret"), nil)
+	if err == nil {
+		t.Fatal("TestTimeout() expecting error using expired key")
+	}
+	fmt.Printf("TestTimeout() test okay: %v\n", err)
+}
+
+func TestListen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "c9a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	if err = os.Chmod(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Listen(filepath.Join(dir, "agent.sock")); err == nil {
+		t.Fatal("TestListen() expecting error listening in a directory accessible by other users")
+	}
+
+	lsnr, e := Listen(filepath.Join(dir, "sub", "agent.sock"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	lsnr.Close()
+	if info, _ := os.Stat(filepath.Join(dir, "sub")); info.Mode().Perm() != 0700 {
+		t.Fatalf("TestListen() unexpected directory mode %v", info.Mode())
+	}
+	fmt.Printf("TestListen() test okay: %v\n", err)
+}
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Client client of the key agent
+type Client struct {
+	Socket string // path of the agent socket
+}
+
+// NewClient create a client of the agent listening on the given socket path, SocketPath() is used if empty.
+func NewClient(socket string) *Client {
+	if socket == "" {
+		socket = SocketPath()
+	}
+	return &Client{Socket: socket}
+}
+
+// Call send the request to the agent and wait for the response.
+func (c *Client) Call(req *Request) (rsp *Response, err error) {
+	conn, err := net.DialTimeout("unix", c.Socket, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("[AGENT] agent not available: %v", err)
+	}
+	defer conn.Close()
+
+	if err = json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("[AGENT] %v", err)
+	}
+	rsp = &Response{}
+	if err = json.NewDecoder(conn).Decode(rsp); err != nil {
+		return nil, fmt.Errorf("[AGENT] invalid response: %v", err)
+	}
+	if rsp.Error != "" {
+		err = fmt.Errorf("%v", rsp.Error)
+	}
+	return
+}
+
+// Add add a key (or a key-generating password if 'key' is nil) of the given algorithm to the agent, the key
+// expires after 'timeout', 0 to use the agent default, negative to never expire.
+func (c *Client) Add(name, algr string, key []byte, passwd string, saltLen int, timeout time.Duration) (err error) {
+	_, err = c.Call(&Request{Op: OP_ADD, Name: name, Algorithm: algr, Key: key, Password: passwd, SaltLen: saltLen, Timeout: timeout})
+	return
+}
+
+// Remove remove the given key from the agent, all keys are removed if 'name' is empty.
+func (c *Client) Remove(name string) (err error) {
+	_, err = c.Call(&Request{Op: OP_REMOVE, Name: name})
+	return
+}
+
+// List list the keys kept by the agent.
+func (c *Client) List() ([]KeyInfo, error) {
+	rsp, err := c.Call(&Request{Op: OP_LIST})
+	if err != nil {
+		return nil, err
+	}
+	return rsp.Keys, nil
+}
+
+// Encrypt encrypt 'dat' using the given key, the output is the same as the 'encrypt' command.
+func (c *Client) Encrypt(name string, dat, aad []byte) ([]byte, error) {
+	rsp, err := c.Call(&Request{Op: OP_ENCRYPT, Name: name, Data: dat, AAD: aad})
+	if err != nil {
+		return nil, err
+	}
+	return rsp.Data, nil
+}
+
+// Decrypt decrypt 'dat' using the given key.
+func (c *Client) Decrypt(name string, dat, aad []byte) ([]byte, error) {
+	rsp, err := c.Call(&Request{Op: OP_DECRYPT, Name: name, Data: dat, AAD: aad})
+	if err != nil {
+		return nil, err
+	}
+	return rsp.Data, nil
+}
+
+// Sign sign 'dat' (HMAC-SHA256) using the given symmetric key.
+func (c *Client) Sign(name string, dat []byte) ([]byte, error) {
+	rsp, err := c.Call(&Request{Op: OP_SIGN, Name: name, Data: dat})
+	if err != nil {
+		return nil, err
+	}
+	return rsp.Data, nil
+}
+
+// Stop stop the agent, all keys are wiped.
+func (c *Client) Stop() (err error) {
+	_, err = c.Call(&Request{Op: OP_STOP})
+	return
+}
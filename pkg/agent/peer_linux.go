@@ -0,0 +1,34 @@
+package agent
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// peerCheck accept connections of the current user only, by the credentials of the peer process (SO_PEERCRED)
+func peerCheck(conn net.Conn) (err error) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return fmt.Errorf("[AGENT] unix socket connection expected")
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("[AGENT] %v", err)
+	}
+
+	var cred *syscall.Ucred
+	var e error
+	if err = raw.Control(func(fd uintptr) {
+		cred, e = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err == nil {
+		err = e
+	}
+	if err != nil {
+		return fmt.Errorf("[AGENT] %v", err)
+	} else if int(cred.Uid) != os.Getuid() {
+		return fmt.Errorf("[AGENT] connection of user %v refused", cred.Uid)
+	}
+	return
+}
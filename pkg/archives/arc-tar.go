@@ -0,0 +1,92 @@
+package archives
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// /// //
+// Tar
+type Tar int
+
+func (n Tar) Name() string {
+	return "tar"
+}
+
+// Pack write the content of 'dir' to 'out' in the PAX tar format, which keeps sub-second modification times
+func (n Tar) Pack(dir string, out io.Writer) (err error) {
+	entries, err := walk(dir)
+	if err != nil {
+		return
+	}
+
+	wtr := tar.NewWriter(out)
+	for _, ent := range entries {
+		var hdr *tar.Header
+		hdr, err = tar.FileInfoHeader(ent.info, ent.link)
+		if err != nil {
+			return
+		}
+		hdr.Name = ent.path
+		if ent.info.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.Format = tar.FormatPAX
+
+		err = wtr.WriteHeader(hdr)
+		if err != nil {
+			return
+		}
+		if ent.info.Mode().IsRegular() {
+			err = copyFile(wtr, filepath.Join(dir, filepath.FromSlash(ent.path)))
+			if err != nil {
+				return
+			}
+		}
+	}
+	return wtr.Close()
+}
+
+// Unpack extract the tar archive read from 'in' to 'dir'
+func (n Tar) Unpack(in io.Reader, dir string) (err error) {
+	x, err := newExtractor(dir)
+	if err != nil {
+		return
+	}
+
+	rdr := tar.NewReader(in)
+	for {
+		var hdr *tar.Header
+		hdr, err = rdr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = x.mkdir(hdr.Name, hdr.FileInfo())
+		case tar.TypeReg:
+			err = x.file(hdr.Name, hdr.FileInfo(), rdr)
+		case tar.TypeSymlink:
+			err = x.symlink(hdr.Name, hdr.Linkname)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return x.done()
+}
+
+func copyFile(wtr io.Writer, path string) error {
+	inp, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer inp.Close()
+	_, err = io.Copy(wtr, inp)
+	return err
+}
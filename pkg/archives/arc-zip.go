@@ -0,0 +1,108 @@
+package archives
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"path/filepath"
+)
+
+// /// //
+// Zip
+// NOTE: zip archives are compressed with deflate entry by entry, and need to be read
+// entirely into memory during extraction, as the central directory is at the end
+type Zip int
+
+func (n Zip) Name() string {
+	return "zip"
+}
+
+// Pack write the content of 'dir' to 'out' as a zip archive, symbolic links are stored as their targets
+func (n Zip) Pack(dir string, out io.Writer) (err error) {
+	entries, err := walk(dir)
+	if err != nil {
+		return
+	}
+
+	wtr := zip.NewWriter(out)
+	for _, ent := range entries {
+		var hdr *zip.FileHeader
+		hdr, err = zip.FileInfoHeader(ent.info)
+		if err != nil {
+			return
+		}
+		hdr.Name = ent.path
+		if ent.info.IsDir() {
+			hdr.Name += "/"
+		} else {
+			hdr.Method = zip.Deflate
+		}
+
+		var w io.Writer
+		w, err = wtr.CreateHeader(hdr)
+		if err != nil {
+			return
+		}
+		switch {
+		case ent.link != "":
+			_, err = io.WriteString(w, ent.link)
+		case ent.info.Mode().IsRegular():
+			err = copyFile(w, filepath.Join(dir, filepath.FromSlash(ent.path)))
+		}
+		if err != nil {
+			return
+		}
+	}
+	return wtr.Close()
+}
+
+// Unpack extract the zip archive read from 'in' to 'dir'
+func (n Zip) Unpack(in io.Reader, dir string) (err error) {
+	buf, err := io.ReadAll(in)
+	if err != nil {
+		return
+	}
+	rdr, err := zip.NewReader(bytes.NewReader(buf), int64(len(buf)))
+	if err != nil {
+		return
+	}
+
+	x, err := newExtractor(dir)
+	if err != nil {
+		return
+	}
+	for _, f := range rdr.File {
+		err = unzip(x, f)
+		if err != nil {
+			return
+		}
+	}
+	return x.done()
+}
+
+func unzip(x *extractor, f *zip.File) (err error) {
+	info := f.FileInfo()
+	if info.IsDir() {
+		return x.mkdir(f.Name, info)
+	}
+
+	rdr, err := f.Open()
+	if err != nil {
+		return
+	}
+	defer rdr.Close()
+
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		var link []byte
+		link, err = io.ReadAll(rdr)
+		if err != nil {
+			return
+		}
+		err = x.symlink(f.Name, string(link))
+	case info.Mode().IsRegular():
+		err = x.file(f.Name, info, rdr)
+	}
+	return
+}
@@ -0,0 +1,262 @@
+package archives
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+// Archiver archive file format
+type Archiver interface {
+	// Name archive format name.
+	Name() string
+
+	// Pack write all files under the given directory, with their modes and modification times, as an archive to the given output.
+	Pack(string, io.Writer) error
+
+	// Unpack read the archive from the given input and extract its content to the given directory.
+	Unpack(io.Reader, string) error
+}
+
+var aRCHIVERS = map[string]Archiver{
+	"tar": Tar(0),
+	"zip": Zip(0),
+}
+
+func Default() string {
+	return "tar"
+}
+
+func List() (list []string) {
+	list = make([]string, 0)
+	for k := range aRCHIVERS {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	return
+}
+
+func Get(format string) Archiver {
+	return aRCHIVERS[format]
+}
+
+// Validate validate the given archive format name.
+func Validate(inp string) (err error) {
+	format := Parse(inp)
+	if format == "" {
+		err = fmt.Errorf("[ARCH] unsupported archive format '%v'", inp)
+	}
+	return
+}
+
+// Parse return the actual archive format name
+func Parse(inp string) (name string) {
+	frmts := make([]string, len(aRCHIVERS))
+	i := 0
+	for n := range aRCHIVERS {
+		frmts[i] = n
+		i++
+	}
+
+	indices, str, _ := utils.BestMatch(inp, frmts, true)
+	if len(indices) == 1 {
+		name = str
+	}
+	return
+}
+
+// entry an item to be archived
+type entry struct {
+	path string // path relative to the archived directory, '/' separated
+	info fs.FileInfo
+	link string // target of symbolic links
+}
+
+// walk list all entries under 'dir' in lexical order
+func walk(dir string) (entries []entry, err error) {
+	entries = make([]entry, 0)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		} else if rel == "." {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		ent := entry{path: filepath.ToSlash(rel), info: info}
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			ent.link, err = os.Readlink(path)
+			if err != nil {
+				return err
+			}
+		case info.IsDir(), info.Mode().IsRegular():
+		default:
+			return nil // skip devices, sockets and named pipes
+		}
+		entries = append(entries, ent)
+		return nil
+	})
+	return
+}
+
+// target resolve the path of an archive entry within 'dir', entries escaping 'dir' are rejected
+func target(dir, name string) (path string, err error) {
+	path = filepath.Join(dir, filepath.FromSlash(name))
+	if !within(dir, path) {
+		err = fmt.Errorf("[ARCH] illegal path '%v' in archive", name)
+	}
+	return
+}
+
+// within check if 'path' is inside 'dir'
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// extractor create the extracted files, and restore the directories' modes and modification times at the end
+type extractor struct {
+	dir  string
+	root string // the real path of 'dir', with the symbolic links resolved
+	dirs []entry
+}
+
+func newExtractor(dir string) (*extractor, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return nil, err
+	}
+	return &extractor{
+		dir:  dir,
+		root: root,
+		dirs: make([]entry, 0),
+	}, nil
+}
+
+// resolve the path of an archive entry with the symbolic links of its existing part resolved, which must be within
+// the extracted directory, so the symbolic links extracted earlier cannot redirect the entries outside of it
+func (x *extractor) resolve(name string) (string, error) {
+	path, err := target(x.dir, name)
+	if err != nil {
+		return "", err
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return "", err
+	}
+	rest := ""
+	for cur := path; ; {
+		real, err := filepath.EvalSymlinks(cur)
+		if err == nil {
+			real = filepath.Join(real, rest)
+			if !within(x.root, real) {
+				return "", fmt.Errorf("[ARCH] path '%v' in archive resolved outside of the extracted directory", name)
+			}
+			return real, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(cur)
+		if parent == cur {
+			return "", err
+		}
+		rest, cur = filepath.Join(filepath.Base(cur), rest), parent
+	}
+}
+
+func (x *extractor) mkdir(name string, info fs.FileInfo) error {
+	path, err := x.resolve(name)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(path, 0o700) // keep writable until done
+	if err != nil {
+		return err
+	}
+	x.dirs = append(x.dirs, entry{path: path, info: info})
+	return nil
+}
+
+func (x *extractor) file(name string, info fs.FileInfo, rdr io.Reader) error {
+	path, err := x.resolve(name)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, rdr)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	err = out.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(path, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(path, info.ModTime(), info.ModTime())
+}
+
+func (x *extractor) symlink(name, link string) error {
+	path, err := x.resolve(name)
+	if err != nil {
+		return err
+	}
+	dest := link
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(filepath.Dir(path), dest)
+	}
+	if !within(x.root, dest) {
+		return fmt.Errorf("[ARCH] symbolic link '%v' -> '%v' points outside of the extracted directory", name, link)
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+	return os.Symlink(link, path)
+}
+
+// done restore the modes and modification times of the extracted directories, deepest first
+func (x *extractor) done() (err error) {
+	for i := len(x.dirs) - 1; i >= 0; i-- {
+		d := x.dirs[i]
+		err = os.Chmod(d.path, d.info.Mode().Perm())
+		if err != nil {
+			return
+		}
+		err = os.Chtimes(d.path, d.info.ModTime(), d.info.ModTime())
+		if err != nil {
+			return
+		}
+	}
+	return
+}
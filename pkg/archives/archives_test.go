@@ -0,0 +1,129 @@
+package archives
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func prepare(t *testing.T) string {
+	dir := t.TempDir()
+	mtime := time.Date(2025, 10, 14, 12, 34, 56, 0, time.UTC)
+	files := map[string]os.FileMode{
+		"a.txt":           0o644,
+		"sub/b.sh":        0o755,
+		"sub/deep/c.key":  0o600,
+		"sub/deep/.empty": 0o400,
+	}
+	for name, mode := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("content of %v", name)), mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("deep/c.key", filepath.Join(dir, "sub", "link")); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestPackUnpack(t *testing.T) {
+	src := prepare(t)
+	for _, n := range List() {
+		var buf bytes.Buffer
+		a := Get(n)
+		if err := a.Pack(src, &buf); err != nil {
+			t.Fatalf("TestPackUnpack() %v %v", n, err)
+		}
+		dst := filepath.Join(t.TempDir(), "out")
+		if err := a.Unpack(&buf, dst); err != nil {
+			t.Fatalf("TestPackUnpack() %v %v", n, err)
+		}
+
+		entries, err := walk(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			path := filepath.Join(dst, filepath.FromSlash(e.path))
+			info, err := os.Lstat(path)
+			if err != nil {
+				t.Fatalf("TestPackUnpack() %v %v", n, err)
+			}
+			if info.Mode() != e.info.Mode() {
+				t.Fatalf("TestPackUnpack() %v '%v' mode %v, expecting %v", n, e.path, info.Mode(), e.info.Mode())
+			}
+			if e.info.Mode().IsRegular() {
+				if !info.ModTime().Equal(e.info.ModTime()) {
+					t.Fatalf("TestPackUnpack() %v '%v' mtime %v, expecting %v", n, e.path, info.ModTime(), e.info.ModTime())
+				}
+				exp, _ := os.ReadFile(filepath.Join(src, filepath.FromSlash(e.path)))
+				act, _ := os.ReadFile(path)
+				if !bytes.Equal(exp, act) {
+					t.Fatalf("TestPackUnpack() %v '%v' content mismatched", n, e.path)
+				}
+			}
+		}
+		fmt.Printf("TestPackUnpack() %v %v entries okay\n", n, len(entries))
+	}
+}
+
+func TestUnpackTraversal(t *testing.T) {
+	var buf bytes.Buffer
+	wtr := tar.NewWriter(&buf)
+	err := wtr.WriteHeader(&tar.Header{Name: "../evil.txt", Mode: 0o644, Size: 4, Typeflag: tar.TypeReg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wtr.Write([]byte("evil"))
+	wtr.Close()
+
+	dir := t.TempDir()
+	if err = Get("tar").Unpack(&buf, filepath.Join(dir, "out")); err == nil {
+		t.Fatal("TestUnpackTraversal() expecting illegal path error")
+	}
+	if _, err = os.Stat(filepath.Join(dir, "evil.txt")); err == nil {
+		t.Fatal("TestUnpackTraversal() file written outside of the target directory")
+	}
+}
+
+func TestUnpackSymlinkTraversal(t *testing.T) {
+	var buf bytes.Buffer
+	wtr := tar.NewWriter(&buf)
+	for _, hdr := range []*tar.Header{
+		{Name: "x", Linkname: ".", Mode: 0o777, Typeflag: tar.TypeSymlink},
+		{Name: "x/y", Linkname: "../evil", Mode: 0o777, Typeflag: tar.TypeSymlink},
+		{Name: "y/pwn", Mode: 0o644, Size: 4, Typeflag: tar.TypeReg},
+	} {
+		if err := wtr.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wtr.Write([]byte("pwnd"))
+	wtr.Close()
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "evil"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Get("tar").Unpack(&buf, filepath.Join(dir, "out")); err == nil {
+		t.Fatal("TestUnpackSymlinkTraversal() expecting error of symbolic link pointing outside")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil", "pwn")); err == nil {
+		t.Fatal("TestUnpackSymlinkTraversal() file written outside of the target directory")
+	}
+	fmt.Println("TestUnpackSymlinkTraversal() test okay")
+}
@@ -0,0 +1,184 @@
+// Package c9crypt library interface of c9ryptool, for embedding the encryption functions in other programs.
+// All the functions are safe for concurrent use, since a new algorithm instance is created for each call.
+// The outputs are compatible with the 'encrypt' and 'decrypt' commands of c9ryptool.
+package c9crypt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/encrypts/sym"
+)
+
+// Options options of encryption and decryption. 'Password' generates the same key as '--password' of the command line.
+type Options struct {
+	Algorithm string // encryption algorithm name, default: encrypts.Default()
+	Key       []byte // raw symmetric key, or PEM encoded asymmetric key
+	Password  string // key-generating password, mutually exclusive with 'Key', symmetric algorithms only
+	SaltLen   int    // length of salt to use for generating keys from password, default: sym.SALTLEN
+	AAD       []byte // additional authenticated data, symmetric algorithms only
+	Compress  string // compression algorithm name, compressed before encryption and decompressed after decryption
+}
+
+// Encrypt read the plaintext from 'in', and write the ciphertext to 'out'.
+func Encrypt(ctx context.Context, in io.Reader, out io.Writer, opts Options) (err error) {
+	alg, zip, err := prepare(opts)
+	if err != nil {
+		return fmt.Errorf("[C9CRYPT][ECY]%v", err)
+	}
+
+	input, err := read(ctx, in)
+	if err != nil {
+		return fmt.Errorf("[C9CRYPT][ECY][INP]%v", err)
+	}
+	if zip != nil {
+		input, err = encodes.Compress(zip, input)
+		if err != nil {
+			return fmt.Errorf("[C9CRYPT][ECY][ZIP]%v", err)
+		}
+	}
+
+	salt, err := populateKey(alg, opts, nil)
+	if err != nil {
+		return fmt.Errorf("[C9CRYPT][ECY]%v", err)
+	}
+	if err = ctx.Err(); err != nil {
+		return fmt.Errorf("[C9CRYPT][ECY] %v", err)
+	}
+
+	rst, err := alg.Encrypt(encrypts.EncryptRequest{Plaintext: input, AAD: opts.AAD})
+	if err != nil {
+		return fmt.Errorf("[C9CRYPT][ECY]%v", err)
+	}
+
+	if _, err = out.Write(append(rst.Output, salt...)); err != nil {
+		return fmt.Errorf("[C9CRYPT][ECY][OUT] %v", err)
+	}
+	return
+}
+
+// Decrypt read the ciphertext from 'in', and write the plaintext to 'out'.
+func Decrypt(ctx context.Context, in io.Reader, out io.Writer, opts Options) (err error) {
+	alg, unzip, err := prepare(opts)
+	if err != nil {
+		return fmt.Errorf("[C9CRYPT][DCY]%v", err)
+	}
+
+	input, err := read(ctx, in)
+	if err != nil {
+		return fmt.Errorf("[C9CRYPT][DCY][INP]%v", err)
+	}
+
+	salt, err := populateKey(alg, opts, input)
+	if err != nil {
+		return fmt.Errorf("[C9CRYPT][DCY]%v", err)
+	}
+	if err = ctx.Err(); err != nil {
+		return fmt.Errorf("[C9CRYPT][DCY] %v", err)
+	}
+
+	rst, err := alg.Decrypt(encrypts.DecryptRequest{Ciphertext: input[:len(input)-len(salt)], AAD: opts.AAD})
+	if err != nil {
+		return fmt.Errorf("[C9CRYPT][DCY]%v", err)
+	}
+
+	result := rst.Plaintext
+	if unzip != nil {
+		result, err = encodes.Decompress(unzip, result)
+		if err != nil {
+			return fmt.Errorf("[C9CRYPT][DCY][ZIP]%v", err)
+		}
+	}
+	if _, err = out.Write(result); err != nil {
+		return fmt.Errorf("[C9CRYPT][DCY][OUT] %v", err)
+	}
+	return
+}
+
+// prepare validate the options, and create a new instance of the algorithm
+func prepare(opts Options) (alg encrypts.AlgorithmV2, zip encodes.Compressor, err error) {
+	name := opts.Algorithm
+	if name == "" {
+		name = encrypts.Default()
+	}
+	if name, err = encrypts.Resolve(name); err != nil {
+		err = fmt.Errorf("[OPTS]%v", err)
+		return
+	}
+	alg = encrypts.NewV2(name)
+
+	if opts.Key != nil && opts.Password != "" {
+		err = fmt.Errorf("[OPTS] 'Key' and 'Password' are mutually exclusive")
+	} else if opts.Key == nil && opts.Password == "" {
+		err = fmt.Errorf("[OPTS] encryption key missing")
+	} else if !alg.Type() && opts.Password != "" {
+		err = fmt.Errorf("[OPTS] cannot generate keys from password for asymmetric algorithm '%v'", alg.Name())
+	} else if !alg.SupportsAAD() && opts.AAD != nil {
+		err = fmt.Errorf("[OPTS] additional authenticated data not supported by '%v'", alg.Name())
+	}
+	if err != nil {
+		return
+	}
+
+	if opts.Compress != "" {
+		cmp, _ := encodes.ParseCompressor(opts.Compress)
+		if zip = encodes.GetCompressor(cmp); zip == nil {
+			err = fmt.Errorf("[OPTS] unsupported compression algorithm '%v'", opts.Compress)
+		}
+	}
+	return
+}
+
+// populateKey populate the key of 'alg', 'salted' is the ciphertext ending with the salt for decryption, nil to
+// generate a new salt for encryption. Returns the salt if the key is generated from password.
+func populateKey(alg encrypts.AlgorithmV2, opts Options, salted []byte) (salt []byte, err error) {
+	if opts.Password == "" {
+		if err = alg.PopulateKey(opts.Key); err != nil {
+			err = fmt.Errorf("[KEY]%v", err)
+		}
+		return
+	}
+
+	saltLen := opts.SaltLen
+	if saltLen <= 0 {
+		saltLen = sym.SALTLEN
+	}
+	if salted != nil && len(salted) < saltLen {
+		err = fmt.Errorf("[PWD] input too short")
+		return
+	}
+	salt, err = sym.PopulateKeyFromPassword(opts.Password, salted, alg.KeyLength(), saltLen, alg.PopulateKey)
+	if err != nil {
+		err = fmt.Errorf("[PWD]%v", err)
+	}
+	return
+}
+
+// read read everything from 'in', stop if 'ctx' is done
+func read(ctx context.Context, in io.Reader) (dat []byte, err error) {
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, &ctxReader{ctx: ctx, rdr: in})
+	if err != nil {
+		err = fmt.Errorf("[READ] %v", err)
+		return
+	}
+	dat = buf.Bytes()
+	return
+}
+
+// ctxReader reader which stops reading once the context is done
+type ctxReader struct {
+	ctx context.Context
+	rdr io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.rdr.Read(p)
+}
@@ -0,0 +1,125 @@
+package c9crypt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"sea9.org/go/c9ryptool/pkg/encrypts/sym"
+)
+
+func roundTrip(ctx context.Context, plain []byte, enc, dec Options) (result []byte, err error) {
+	var cipher, out bytes.Buffer
+	if err = Encrypt(ctx, bytes.NewReader(plain), &cipher, enc); err != nil {
+		return
+	}
+	if err = Decrypt(ctx, &cipher, &out, dec); err != nil {
+		return
+	}
+	result = out.Bytes()
+	return
+}
+
+func TestConcurrent(t *testing.T) {
+	ctx := context.Background()
+	algrs := []string{"AES-128-GCM", "AES-256-GCM", "ChaCha20-Poly1305"}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 24)
+	for i := 0; i < 24; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			opts := Options{Algorithm: algrs[i%len(algrs)]}
+			switch {
+			case i%12 == 1: // keys from password are slow to generate
+				opts.Password = fmt.Sprintf("password-%v", i)
+			case i%2 == 0:
+				opts.Key, _ = sym.Generate(32)
+				if opts.Algorithm == "AES-128-GCM" {
+					opts.Key = opts.Key[:16]
+				}
+			default:
+				opts.Key, _ = sym.Generate(32)
+				if opts.Algorithm == "AES-128-GCM" {
+					opts.Key = opts.Key[:16]
+				}
+				opts.Compress = "gzip"
+				opts.AAD = []byte(fmt.Sprintf("aad-%v", i))
+			}
+
+			plain := bytes.Repeat([]byte(fmt.Sprintf("goroutine %v;", i)), 100)
+			result, err := roundTrip(ctx, plain, opts, opts)
+			if err != nil {
+				errs <- fmt.Errorf("%v: %v", i, err)
+			} else if !bytes.Equal(plain, result) {
+				errs <- fmt.Errorf("%v: result mismatched", i)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	fmt.Println("TestConcurrent() test okay")
+}
+
+func TestWrongKey(t *testing.T) {
+	ctx := context.Background()
+	k0, _ := sym.Generate(32)
+	k1, _ := sym.Generate(32)
+
+	_, err := roundTrip(ctx, []byte("top secret"), Options{Key: k0}, Options{Key: k1})
+	if err == nil {
+		t.Fatal("TestWrongKey() decryption with a different key should fail")
+	}
+	_, err = roundTrip(ctx, []byte("top secret"), Options{Password: "abcd1234"}, Options{Password: "abcd9999"})
+	if err == nil {
+		t.Fatal("TestWrongKey() decryption with a different password should fail")
+	}
+	_, err = roundTrip(ctx, []byte("top secret"), Options{Password: "abcd1234"}, Options{Password: "abcd1235"})
+	if err == nil {
+		t.Fatal("TestWrongKey() decryption with a different last password character should fail")
+	}
+	_, err = roundTrip(ctx, []byte("top secret"), Options{Key: k0, AAD: []byte("a")}, Options{Key: k0, AAD: []byte("b")})
+	if err == nil {
+		t.Fatal("TestWrongKey() decryption with a different AAD should fail")
+	}
+	fmt.Printf("TestWrongKey() test okay: %v\n", err)
+}
+
+func TestOptions(t *testing.T) {
+	ctx := context.Background()
+	key, _ := sym.Generate(32)
+	tests := []Options{
+		{},
+		{Key: key, Password: "abcd1234"},
+		{Key: key, Algorithm: "AES-512-XYZ"},
+		{Key: key, Compress: "rar"},
+		{Password: "abcd1234", Algorithm: "RSA-2048-OAEP-SHA256"},
+	}
+	for i, opts := range tests {
+		var out bytes.Buffer
+		if err := Encrypt(ctx, bytes.NewReader([]byte("top secret")), &out, opts); err == nil {
+			t.Fatalf("TestOptions() %v expecting error", i)
+		} else {
+			fmt.Printf("TestOptions() %v %v\n", i, err)
+		}
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	key, _ := sym.Generate(32)
+
+	var out bytes.Buffer
+	err := Encrypt(ctx, bytes.NewReader([]byte("top secret")), &out, Options{Key: key})
+	if err == nil || out.Len() > 0 {
+		t.Fatal("TestCancel() expecting error")
+	}
+	fmt.Printf("TestCancel() test okay: %v\n", err)
+}
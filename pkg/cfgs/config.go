@@ -2,49 +2,88 @@ package cfgs
 
 import (
 	"fmt"
+	"time"
 
 	"sea9.org/go/c9ryptool/pkg/utils"
 )
 
 func Version() string {
-	return "v2.0.2 2026030610"
+	return "v2.1.0 2026101911"
 }
 
 const BUFFER = 1048576 // 1024x1024
 
+const LEVEL_DEFAULT = -1 << 31 // use the default compression level of the algorithm
+
 const MASK_LIST = 128
 const MASK_FLAG = 127
 
 type Config struct {
-	cmds    []string // command list
-	cmd     uint8    // e.g. 0 - encrypt; 1 - decrypt
-	Algr    string   // encryption algorithm name
-	Encd    string   // encoding schemes name
-	Encv    string   // encoding schemes name for IV
-	Enct    string   // encoding schemes name for TAG
-	Enca    string   // encoding schemes name for AAD
-	Enco    string   // encoding schemes name for outputs
-	Enck    string   // encoding schemes name for symmetric keys
-	Hash    string   // hashing algorithm name
-	Input   string   // input file path, nil - stdin
-	Output  string   // output file path, nil - stdout
-	Format  string   // input file format
-	Key     string   // secret key file path
-	Iv      string   // initialization vector file path, nil - auto-gen
-	Tag     string   // message authentication tag file path
-	Aad     string   // additional authenticated data file path
-	Genkey  bool     // generate key enabled
-	Passwd  string   // key-generating password
-	SaltLen int      // length of salt to use for generating keys from password
-	Zip     string   // compression algorithm name
-	Buffer  int      // buffer size
-	Verbose bool
+	cmds     []string      // command list
+	cmd      uint8         // e.g. 0 - encrypt; 1 - decrypt
+	Algr     string        // encryption algorithm name
+	Encd     string        // encoding schemes name
+	Encv     string        // encoding schemes name for IV
+	Enct     string        // encoding schemes name for TAG
+	Enca     string        // encoding schemes name for AAD
+	Enco     string        // encoding schemes name for outputs
+	Enck     string        // encoding schemes name for symmetric keys
+	Hash     string        // hashing algorithm name
+	Input    string        // input file path, nil - stdin
+	Output   string        // output file path, nil - stdout
+	Format   string        // input file format
+	Key      string        // secret key file path
+	Kms      string        // URI of the key management service wrapping the data keys
+	Agent    string        // name of the key kept by the key agent
+	Socket   string        // path of the key agent socket
+	Timeout  time.Duration // lifetime of the keys kept by the key agent
+	Keystore string        // keystore file path
+	Identity string        // private key file path of the keystore recipient
+	Rcpt     string        // public key file path of the keystore recipient, for creating keystores
+	Action   string        // sub-command, e.g. 'add' of 'keys'
+	Name     string        // name of the key in the keystore
+	Labels   []string      // labels of the key in the keystore, as 'KEY=VALUE'
+	State    string        // rotation state of the key in the keystore
+	Listen   string        // address the server listens on
+	TlsCert  string        // TLS certificate file path of the server
+	TlsKey   string        // TLS private key file path of the server
+	ClientCA string        // CA certificate file path for verifying client certificates (mTLS)
+	MaxSize  int64         // maximum size of a request body of the server
+	Iv       string        // initialization vector file path, nil - auto-gen
+	Tag      string        // message authentication tag file path
+	Aad      string        // additional authenticated data file path
+	Genkey   bool          // generate key enabled
+	Passwd   string        // key-generating password
+	NewAlgr  string        // encryption algorithm name of the new key, for re-encryption
+	NewKey   string        // new secret key file path, for re-encryption
+	NewPwd   string        // new key-generating password, for re-encryption
+	Exec     []string      // command line of the child process of 'exec'
+	Prefix   string        // prefix of the environment variable names of 'exec'
+	Sep      string        // separator of the nested keys in the environment variable names of 'exec'
+	SaltLen  int           // length of salt to use for generating keys from password
+	Zip      string        // compression algorithm name
+	Level    int           // compression level
+	Extract  bool          // decompress instead of compress when archiving
+	Include  []string      // glob patterns of files to include when encrypting directories
+	Exclude  []string      // glob patterns of files to exclude when encrypting directories
+	Paths    []string      // path expressions of the values to encrypt in field-level formats, '!' prefixed to exclude
+	Regex    string        // regular expression of the key names of the values to encrypt in field-level formats
+	Columns  []string      // names or indices of the columns to encrypt in CSV files
+	NoHeader bool          // the first row of CSV files is data instead of the header
+	Sops     bool          // write field-level formats in the SOPS format
+	Workers  int           // number of workers when encrypting directories
+	InPlace  bool          // replace the input file with the output
+	Backup   bool          // keep the original input file as a backup when replacing it
+	Buffer   int           // buffer size
+	Sentinel int           // end-of-input sentinel mode when reading from stdin, see utils.SENTINEL_AUTO
+	Verbose  bool
 }
 
 func New(comands []string) *Config {
 	return &Config{
 		cmds:    comands,
 		Buffer:  BUFFER,
+		Level:   LEVEL_DEFAULT,
 		Passwd:  "",
 		Verbose: false,
 	}
@@ -120,12 +159,27 @@ func (c *Config) String() string {
 			key = fmt.Sprintf("; generate new key%v", enck)
 		} else if c.Key != "" {
 			key = fmt.Sprintf("; key from %v%v", c.Key, enck)
+		} else if c.Kms != "" {
+			key = fmt.Sprintf("; data key wrapped by %v", c.Kms)
+		} else if c.Agent != "" {
+			key = fmt.Sprintf("; key '%v' from agent", c.Agent)
+		}
+		if c.NewPwd != "" {
+			key = fmt.Sprintf("%v; new key from passphrase", key)
+		} else if c.NewKey != "" {
+			key = fmt.Sprintf("%v; new key from %v", key, c.NewKey)
+		}
+		if c.NewAlgr != "" {
+			key = fmt.Sprintf("%v using '%v'", key, c.NewAlgr)
 		}
 		frmt := ""
 		if c.Format != "" {
 			frmt = fmt.Sprintf(" %v", c.Format)
 		}
 		strs = append(strs, fmt.Sprintf("%v(%v)%v using '%v'%v%v", c.Command(), c.Cmd(), frmt, c.Algr, key, vbrs))
+		if len(c.Exec) > 0 {
+			strs = append(strs, fmt.Sprintf("\n - exec: %v (prefix '%v', separator '%v')", c.Exec, c.Prefix, c.Sep))
+		}
 
 		if c.Encv != "" {
 			encv = fmt.Sprintf(" (%v)", c.Encv)
@@ -155,14 +209,35 @@ func (c *Config) String() string {
 
 		if c.Output != "" {
 			out = c.Output
+		} else if c.InPlace {
+			out = fmt.Sprintf("%v (in-place)", c.Input)
+			if c.Backup {
+				out = fmt.Sprintf("%v (in-place, backup %v.bak)", c.Input, c.Input)
+			}
 		}
 		if c.Enco != "" {
 			enco = fmt.Sprintf(" (%v)", c.Enco)
 		}
+		if c.Sops {
+			enco = fmt.Sprintf("%v (SOPS format)", enco)
+		}
 		strs = append(strs, fmt.Sprintf("\n - output: %v%v", out, enco))
 
+		if len(c.Include) > 0 || len(c.Exclude) > 0 {
+			strs = append(strs, fmt.Sprintf("\n - include: %v | exclude: %v", c.Include, c.Exclude))
+		}
+		if len(c.Paths) > 0 || c.Regex != "" {
+			strs = append(strs, fmt.Sprintf("\n - paths: %v | regex: '%v'", c.Paths, c.Regex))
+		}
+		if len(c.Columns) > 0 {
+			hdr := "header"
+			if c.NoHeader {
+				hdr = "no header"
+			}
+			strs = append(strs, fmt.Sprintf("\n - columns: %v (%v)", c.Columns, hdr))
+		}
 		if c.Zip != "" {
-			strs = append(strs, fmt.Sprintf("\n - compression with %v", c.Zip))
+			strs = append(strs, fmt.Sprintf("\n - compression with %v%v", c.Zip, c.level()))
 		}
 	} else if c.Hash != "" {
 		strs = append(strs, fmt.Sprintf("%v(%v) using '%v'%v", c.Command(), c.Cmd(), c.Hash, vbrs))
@@ -174,8 +249,25 @@ func (c *Config) String() string {
 			out = c.Output
 		}
 		strs = append(strs, fmt.Sprintf("\n - output: %v", out))
+	} else if c.Listen != "" {
+		scheme, keys := "http", "no keystore"
+		if c.TlsCert != "" {
+			scheme = "https"
+			if c.ClientCA != "" {
+				scheme = "https (mTLS)"
+			}
+		}
+		if c.Keystore != "" {
+			keys = fmt.Sprintf("keystore %v", c.Keystore)
+		}
+		strs = append(strs, fmt.Sprintf("%v(%v) %v on %v, %v%v", c.Command(), c.Cmd(), scheme, c.Listen, keys, vbrs))
+		strs = append(strs, fmt.Sprintf("\n - max request size: %v bytes", c.MaxSize))
 	} else if c.Encd != "" {
-		strs = append(strs, fmt.Sprintf("%v(%v) using '%v'%v", c.Command(), c.Cmd(), c.Encd, vbrs))
+		extr := ""
+		if c.Extract {
+			extr = " (extract)"
+		}
+		strs = append(strs, fmt.Sprintf("%v(%v) using '%v'%v%v%v", c.Command(), c.Cmd(), c.Encd, c.level(), extr, vbrs))
 		if c.Input != "" {
 			inp = c.Input
 		}
@@ -192,3 +284,10 @@ func (c *Config) String() string {
 	}
 	return str
 }
+
+func (c *Config) level() string {
+	if c.Level == LEVEL_DEFAULT {
+		return ""
+	}
+	return fmt.Sprintf(" level %v", c.Level)
+}
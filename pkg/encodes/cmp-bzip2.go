@@ -0,0 +1,44 @@
+package encodes
+
+import (
+	"compress/bzip2"
+	"fmt"
+	"io"
+)
+
+// ///// //
+// Bzip2
+// NOTE: the standard library only supports bzip2 decompression
+type Bzip2 int
+
+func (n Bzip2) Name() string {
+	return "bzip2"
+}
+
+func (n Bzip2) Level() int {
+	return 0
+}
+
+func (n Bzip2) Levels() (int, int) {
+	return 0, 0
+}
+
+func (n Bzip2) WithLevel(lvl int) (Compressor, error) {
+	if err := validLevel(n, lvl); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n Bzip2) CanCompress() bool {
+	return false
+}
+
+func (n Bzip2) Compress(in io.Reader, out io.Writer) error {
+	return fmt.Errorf("[BZIP2] compression not supported")
+}
+
+// Decompress read input from 'in' and write bzip2 decompressed result to 'out'
+func (n Bzip2) Decompress(in io.Reader, out io.Writer) error {
+	return decompress(bzip2.NewReader(in), bufferSize(in), out)
+}
@@ -0,0 +1,49 @@
+package encodes
+
+import (
+	"compress/flate"
+	"io"
+)
+
+// //// //
+// Flate
+type Flate int
+
+func (n Flate) Name() string {
+	return "flate"
+}
+
+func (n Flate) Level() int {
+	return int(n)
+}
+
+func (n Flate) Levels() (int, int) {
+	return flate.HuffmanOnly, flate.BestCompression
+}
+
+func (n Flate) WithLevel(lvl int) (Compressor, error) {
+	if err := validLevel(n, lvl); err != nil {
+		return nil, err
+	}
+	return Flate(lvl), nil
+}
+
+func (n Flate) CanCompress() bool {
+	return true
+}
+
+// Compress read input from 'in' and write flate compressed result to 'out'
+func (n Flate) Compress(in io.Reader, out io.Writer) error {
+	wtr, err := flate.NewWriter(out, n.Level())
+	if err != nil {
+		return err
+	}
+	return compress(in, wtr)
+}
+
+// Decompress read input from 'in' and write flate decompressed result to 'out'
+func (n Flate) Decompress(in io.Reader, out io.Writer) error {
+	rz := flate.NewReader(in)
+	defer rz.Close()
+	return decompress(rz, bufferSize(in), out)
+}
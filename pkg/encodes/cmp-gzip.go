@@ -0,0 +1,52 @@
+package encodes
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// //// //
+// Gzip
+type Gzip int
+
+func (n Gzip) Name() string {
+	return "gzip"
+}
+
+func (n Gzip) Level() int {
+	return int(n)
+}
+
+func (n Gzip) Levels() (int, int) {
+	return gzip.HuffmanOnly, gzip.BestCompression
+}
+
+func (n Gzip) WithLevel(lvl int) (Compressor, error) {
+	if err := validLevel(n, lvl); err != nil {
+		return nil, err
+	}
+	return Gzip(lvl), nil
+}
+
+func (n Gzip) CanCompress() bool {
+	return true
+}
+
+// Compress read input from 'in' and write gzipped result to 'out'
+func (n Gzip) Compress(in io.Reader, out io.Writer) error {
+	wtr, err := gzip.NewWriterLevel(out, n.Level())
+	if err != nil {
+		return err
+	}
+	return compress(in, wtr)
+}
+
+// Decompress read input from 'in' and write ungzipped result to 'out'
+func (n Gzip) Decompress(in io.Reader, out io.Writer) error {
+	rz, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
+	defer rz.Close()
+	return decompress(rz, bufferSize(in), out)
+}
@@ -0,0 +1,49 @@
+package encodes
+
+import (
+	"compress/lzw"
+	"io"
+)
+
+// LZW_LITWIDTH number of bits to use for literal codes, 8 for arbitrary bytes
+const LZW_LITWIDTH = 8
+
+// /// //
+// LZW
+// NOTE: using the LSB bit ordering as in the GIF file format
+type Lzw int
+
+func (n Lzw) Name() string {
+	return "lzw"
+}
+
+func (n Lzw) Level() int {
+	return 0
+}
+
+func (n Lzw) Levels() (int, int) {
+	return 0, 0
+}
+
+func (n Lzw) WithLevel(lvl int) (Compressor, error) {
+	if err := validLevel(n, lvl); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n Lzw) CanCompress() bool {
+	return true
+}
+
+// Compress read input from 'in' and write LZW compressed result to 'out'
+func (n Lzw) Compress(in io.Reader, out io.Writer) error {
+	return compress(in, lzw.NewWriter(out, lzw.LSB, LZW_LITWIDTH))
+}
+
+// Decompress read input from 'in' and write LZW decompressed result to 'out'
+func (n Lzw) Decompress(in io.Reader, out io.Writer) error {
+	rz := lzw.NewReader(in, lzw.LSB, LZW_LITWIDTH)
+	defer rz.Close()
+	return decompress(rz, bufferSize(in), out)
+}
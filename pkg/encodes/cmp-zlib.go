@@ -0,0 +1,52 @@
+package encodes
+
+import (
+	"compress/zlib"
+	"io"
+)
+
+// //// //
+// Zlib
+type Zlib int
+
+func (n Zlib) Name() string {
+	return "zlib"
+}
+
+func (n Zlib) Level() int {
+	return int(n)
+}
+
+func (n Zlib) Levels() (int, int) {
+	return zlib.HuffmanOnly, zlib.BestCompression
+}
+
+func (n Zlib) WithLevel(lvl int) (Compressor, error) {
+	if err := validLevel(n, lvl); err != nil {
+		return nil, err
+	}
+	return Zlib(lvl), nil
+}
+
+func (n Zlib) CanCompress() bool {
+	return true
+}
+
+// Compress read input from 'in' and write zlib compressed result to 'out'
+func (n Zlib) Compress(in io.Reader, out io.Writer) error {
+	wtr, err := zlib.NewWriterLevel(out, n.Level())
+	if err != nil {
+		return err
+	}
+	return compress(in, wtr)
+}
+
+// Decompress read input from 'in' and write zlib decompressed result to 'out'
+func (n Zlib) Decompress(in io.Reader, out io.Writer) error {
+	rz, err := zlib.NewReader(in)
+	if err != nil {
+		return err
+	}
+	defer rz.Close()
+	return decompress(rz, bufferSize(in), out)
+}
@@ -0,0 +1,55 @@
+package encodes
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// //// //
+// Zstd
+// NOTE: using the pure-go implementation 'github.com/klauspost/compress/zstd', the zstd
+// compression levels are mapped to the closest encoder levels supported by the library
+type Zstd int
+
+func (n Zstd) Name() string {
+	return "zstd"
+}
+
+func (n Zstd) Level() int {
+	return int(n)
+}
+
+func (n Zstd) Levels() (int, int) {
+	return 1, 22
+}
+
+func (n Zstd) WithLevel(lvl int) (Compressor, error) {
+	if err := validLevel(n, lvl); err != nil {
+		return nil, err
+	}
+	return Zstd(lvl), nil
+}
+
+func (n Zstd) CanCompress() bool {
+	return true
+}
+
+// Compress read input from 'in' and write zstd compressed result to 'out'
+func (n Zstd) Compress(in io.Reader, out io.Writer) error {
+	wtr, err := zstd.NewWriter(out, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(n.Level())))
+	if err != nil {
+		return err
+	}
+	return compress(in, wtr)
+}
+
+// Decompress read input from 'in' and write zstd decompressed result to 'out'
+func (n Zstd) Decompress(in io.Reader, out io.Writer) error {
+	rz, err := zstd.NewReader(in)
+	if err != nil {
+		return err
+	}
+	defer rz.Close()
+	return decompress(rz, bufferSize(in), out)
+}
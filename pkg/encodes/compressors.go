@@ -0,0 +1,195 @@
+package encodes
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"sea9.org/go/c9ryptool/pkg/cfgs"
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+// Compressor compression algorithm
+type Compressor interface {
+	// Name algorithm name.
+	Name() string
+
+	// Level compression level in use.
+	Level() int
+
+	// Levels range of the supported compression levels, both are 0 if the level is not configurable.
+	Levels() (int, int)
+
+	// WithLevel return a copy of the compressor using the given compression level.
+	WithLevel(int) (Compressor, error)
+
+	// CanCompress 'false' if the algorithm only supports decompression.
+	CanCompress() bool
+
+	// Compress read the given input and write the compressed result to the given output.
+	Compress(io.Reader, io.Writer) error
+
+	// Decompress read the given input and write the decompressed result to the given output.
+	Decompress(io.Reader, io.Writer) error
+}
+
+var cOMPRESSORS = map[string]Compressor{
+	"bzip2": Bzip2(0),
+	"flate": Flate(9),
+	"gzip":  Gzip(-1),
+	"lzw":   Lzw(0),
+	"zlib":  Zlib(-1),
+	"zstd":  Zstd(3),
+}
+
+// dECOMPRESSALIAS names referring to the decompression direction of a compressor, e.g. 'gunzip'
+var dECOMPRESSALIAS = map[string]string{
+	"bunzip2": "bzip2",
+	"inflate": "flate",
+	"gunzip":  "gzip",
+	"unlzw":   "lzw",
+	"unzlib":  "zlib",
+	"unzstd":  "zstd",
+}
+
+func ListCompressors() (list []string) {
+	list = make([]string, 0)
+	for k := range cOMPRESSORS {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	return
+}
+
+func GetCompressor(algr string) Compressor {
+	return cOMPRESSORS[algr]
+}
+
+// ValidateCompressor validate the given compression algorithm name.
+// compress: 'true' if the algorithm is used for compression, 'false' for decompression
+func ValidateCompressor(inp string, compress bool) (err error) {
+	algr, dec := ParseCompressor(inp)
+	if algr == "" {
+		err = fmt.Errorf("[CMPR] invalid compression algorithm name pattern '%v'", inp)
+	} else if c, k := cOMPRESSORS[algr]; !k {
+		err = fmt.Errorf("[CMPR] unsupported compression algorithm '%v'", algr)
+	} else if compress && dec {
+		err = fmt.Errorf("[CMPR] '%v' is for decompression, use '%v' instead", inp, c.Name())
+	} else if compress && !c.CanCompress() {
+		err = fmt.Errorf("[CMPR] %v only supports decompression", c.Name())
+	}
+	return
+}
+
+// ParseCompressor return the actual compression algorithm name, 'dec' is true if
+// the given name refers to the decompression direction of the algorithm (e.g. 'gunzip')
+func ParseCompressor(inp string) (name string, dec bool) {
+	if n, ok := dECOMPRESSALIAS[strings.ToLower(inp)]; ok {
+		return n, true
+	}
+
+	algrs := make([]string, len(cOMPRESSORS))
+	i := 0
+	for n := range cOMPRESSORS {
+		algrs[i] = n
+		i++
+	}
+
+	indices, str, _ := utils.BestMatch(inp, algrs, true)
+	if len(indices) == 1 {
+		name = str
+	}
+	return
+}
+
+// Compress compress the given bytes.
+func Compress(c Compressor, inp []byte) (out []byte, err error) {
+	var buf bytes.Buffer
+	err = c.Compress(bytes.NewReader(inp), &buf)
+	if err != nil {
+		return
+	}
+	out = buf.Bytes()
+	return
+}
+
+// Decompress decompress the given bytes.
+func Decompress(c Compressor, inp []byte) (out []byte, err error) {
+	var buf bytes.Buffer
+	err = c.Decompress(bytes.NewReader(inp), &buf)
+	if err != nil {
+		return
+	}
+	out = buf.Bytes()
+	return
+}
+
+// validLevel check if 'lvl' is within the supported compression levels of 'c'
+func validLevel(c Compressor, lvl int) error {
+	min, max := c.Levels()
+	if lvl < min || lvl > max {
+		if min == max {
+			return fmt.Errorf("[CMPR] compression level not configurable for '%v'", c.Name())
+		}
+		return fmt.Errorf("[CMPR] invalid compression level %v for '%v', expecting %v to %v", lvl, c.Name(), min, max)
+	}
+	return nil
+}
+
+// compress read everything from 'in' and write to 'wtr', which is closed afterward to flush the compressed result
+func compress(in io.Reader, wtr io.WriteCloser) error {
+	rdr, ok := in.(*bufio.Reader)
+	if !ok {
+		rdr = bufio.NewReaderSize(in, cfgs.BUFFER)
+	}
+
+	err := utils.BufferedRead(
+		rdr, rdr.Size(),
+		func(cnt int, inp []byte) (err error) {
+			if cnt > 0 {
+				_, err = wtr.Write(inp[:cnt])
+			}
+			return
+		},
+	)
+	if err != nil {
+		wtr.Close()
+		return err
+	}
+	return wtr.Close()
+}
+
+// decompress read everything from the decompressing reader 'rz' and write to 'out'
+func decompress(rz io.Reader, size int, out io.Writer) error {
+	rdr := bufio.NewReaderSize(rz, size)
+
+	wtr, ok := out.(*bufio.Writer)
+	if !ok {
+		wtr = bufio.NewWriter(out)
+	}
+
+	err := utils.BufferedRead(
+		rdr, rdr.Size(),
+		func(cnt int, inp []byte) (err error) {
+			if cnt > 0 {
+				_, err = wtr.Write(inp[:cnt])
+			}
+			return
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return wtr.Flush()
+}
+
+// bufferSize size of the read buffer of 'in' if it is buffered
+func bufferSize(in io.Reader) int {
+	if rdr, ok := in.(*bufio.Reader); ok {
+		return rdr.Size()
+	}
+	return cfgs.BUFFER
+}
@@ -31,10 +31,6 @@ func (n Base64) Name() string {
 	return "base64"
 }
 
-func (n Base64) Type() int {
-	return 0
-}
-
 func (n Base64) Padding(inp []byte) []byte {
 	out, err := padding(inp)
 	if err != nil {
@@ -72,10 +68,6 @@ func (n Base64Url) Name() string {
 	return "base64url"
 }
 
-func (n Base64Url) Type() int {
-	return 0
-}
-
 func (n Base64Url) Padding(inp []byte) []byte {
 	out, err := padding(inp)
 	if err != nil {
@@ -113,10 +105,6 @@ func (n RawBase64Url) Name() string {
 	return "raw-base64url"
 }
 
-func (n RawBase64Url) Type() int {
-	return 0
-}
-
 func (n RawBase64Url) Padding(inp []byte) []byte {
 	return inp
 }
@@ -13,10 +13,6 @@ func (n Hex) Name() string {
 	return "hex"
 }
 
-func (n Hex) Type() int {
-	return 0
-}
-
 func (n Hex) Padding(inp []byte) []byte {
 	return inp
 }
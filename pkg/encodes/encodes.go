@@ -4,8 +4,10 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"math"
 	"sort"
 	"strconv"
+	"strings"
 
 	"sea9.org/go/c9ryptool/pkg/cfgs"
 	"sea9.org/go/c9ryptool/pkg/utils"
@@ -16,9 +18,6 @@ type Encoding interface {
 	// Name algorithm name.
 	Name() string
 
-	// Type type of encoding. '0' is encoding, >0 is compression, <0 is decompression
-	Type() int
-
 	// Padding fill the input with the specific padding.
 	Padding([]byte) []byte
 
@@ -44,47 +43,74 @@ var eNCODINGS = map[string]Encoding{
 	"base64url":    Base64Url(13),
 	"rawbase64url": RawBase64Url(15),
 	"hex":          Hex(17),
-	"gzip":         Gzip(19),
-	"gunzip":       Gzip(-19),
-	"zlib":         Zlib(21),
-	"unzlib":       Zlib(-21),
-	"flate":        Flate(23),
-	"inflate":      Flate(-23),
 }
 
 func Default() string {
 	return "rawbase64url"
 }
 
+// aLIASES alternative names of the encoding schemes, in lower case
+var aLIASES = map[string]string{
+	"b64":       "base64",
+	"std":       "base64",
+	"b64url":    "base64url",
+	"url":       "base64url",
+	"b64u":      "rawbase64url",
+	"rawurl":    "rawbase64url",
+	"base64raw": "rawbase64url",
+	"base16":    "hex",
+	"b16":       "hex",
+}
+
+// Register register an additional encoding scheme, 'aliases' are alternative names matched exactly (ignoring
+// case) when parsing scheme names. Register is expected to be called during initialization, e.g. in init().
+func Register(enc Encoding, aliases ...string) (err error) {
+	if enc == nil || enc.Name() == "" {
+		return fmt.Errorf("[ENCD] invalid encoding scheme")
+	}
+	name := enc.Name()
+	if err = utils.CheckNames(name, aliases, List(), aLIASES); err != nil {
+		return fmt.Errorf("[ENCD]%v", err)
+	}
+
+	eNCODINGS[name] = enc
+	for _, a := range aliases {
+		aLIASES[strings.ToLower(a)] = name
+	}
+	return
+}
+
+// Aliases alternative names of the given encoding scheme.
+func Aliases(name string) []string {
+	return utils.AliasesOf(name, aLIASES)
+}
+
+// List built-in encoding schemes ordered by their values, followed by the registered ones ordered by names
 func List() (list []string) {
 	list = make([]string, 0)
-	lst := make([]Encoding, 0)
 	for k := range eNCODINGS {
-		l := len(lst)
-		s, _ := strconv.Atoi(fmt.Sprintf("%v", eNCODINGS[k]))
-		if s < 0 {
-			s = -s + 1
+		list = append(list, k)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		oi, oj := order(list[i]), order(list[j])
+		if oi != oj {
+			return oi < oj
 		}
+		return list[i] < list[j]
+	})
+	return
+}
 
-		idx := sort.Search(l, func(i int) bool {
-			t, _ := strconv.Atoi(fmt.Sprintf("%v", lst[i]))
-			if t < 0 {
-				t = -t + 1
-			}
-			return t >= s
-		})
-
-		if idx >= l {
-			lst = append(lst, eNCODINGS[k])
-			list = append(list, k)
-		} else {
-			lst = append(lst[:idx+1], lst[idx:]...)
-			lst[idx] = eNCODINGS[k]
-			list = append(list[:idx+1], list[idx:]...)
-			list[idx] = k
-		}
+// order display order of the given encoding scheme
+func order(name string) int {
+	s, err := strconv.Atoi(fmt.Sprintf("%v", eNCODINGS[name]))
+	if err != nil {
+		return math.MaxInt
 	}
-	return
+	if s < 0 {
+		s = -s + 1
+	}
+	return s
 }
 
 func Get(scheme string) Encoding {
@@ -92,38 +118,29 @@ func Get(scheme string) Encoding {
 }
 
 // Validate validate the given scheme name.
-// typ: -1 - compression/decompression; 0 - don't care; 1 - encoding
-// returns t: 0 is encoding, >0 is compression, <0 is decompression
-func Validate(inp string, typ int) (t int, err error) {
-	scheme := Parse(inp)
-	if scheme == "" {
-		err = fmt.Errorf("[ENCD] invalid encoding scheme name pattern '%v'", inp)
-	} else {
-		s, k := eNCODINGS[scheme]
-		t = s.Type()
-		if !k {
-			err = fmt.Errorf("[ENCD] unsupported encoding scheme '%v'", scheme)
-		} else if typ < 0 && t == 0 {
-			err = fmt.Errorf("[ENCD] %v is not a compression algorithm as expected", s.Name())
-		} else if typ > 0 && t != 0 {
-			err = fmt.Errorf("[ENCD] %v is not an encoding scheme as expected", s.Name())
-		}
+func Validate(inp string) (err error) {
+	scheme, err := Resolve(inp)
+	if err != nil {
+		return
+	} else if _, k := eNCODINGS[scheme]; !k {
+		err = fmt.Errorf("[ENCD] unsupported encoding scheme '%v'", scheme)
 	}
 	return
 }
 
 // Parse return the actual encoding scheme name
 func Parse(inp string) (name string) {
-	algrs := make([]string, len(eNCODINGS))
-	i := 0
-	for n := range eNCODINGS {
-		algrs[i] = n
-		i++
-	}
+	name, _ = Resolve(inp)
+	return
+}
 
-	indices, str, _ := utils.BestMatch(inp, algrs, true)
-	if len(indices) == 1 {
-		name = str
+// Resolve resolve the given encoding scheme name or alias, the error lists the candidates if the name is ambiguous.
+func Resolve(inp string) (name string, err error) {
+	name, cands := utils.Resolve(inp, List(), aLIASES)
+	if name == "" && len(cands) > 0 {
+		err = fmt.Errorf("[ENCD] ambiguous encoding scheme name '%v', candidates: %v", inp, strings.Join(cands, ", "))
+	} else if name == "" {
+		err = fmt.Errorf("[ENCD] invalid encoding scheme name pattern '%v'", inp)
 	}
 	return
 }
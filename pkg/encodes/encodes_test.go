@@ -8,6 +8,7 @@ import (
 	"io"
 	"sort"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -45,8 +46,6 @@ func TestList(t *testing.T) {
 			val = int(typ)
 		case Hex:
 			val = int(typ)
-		case Gzip:
-			val = int(typ)
 		}
 		fmt.Printf("TestList() - %v %v %v\n", i, val, eNCODINGS[k].Name())
 	}
@@ -132,12 +131,30 @@ func TestDecode(t *testing.T) {
 	}
 }
 
+type codec interface {
+	Encode(io.Reader, io.Writer) error
+	Decode(io.Reader, io.Writer) error
+}
+
+// archiver compress when encoding and decompress when decoding
+type archiver struct {
+	Compressor
+}
+
+func (a archiver) Encode(in io.Reader, out io.Writer) error {
+	return a.Compress(in, out)
+}
+
+func (a archiver) Decode(in io.Reader, out io.Writer) error {
+	return a.Decompress(in, out)
+}
+
 // pipedEncoding piped encoding/decoding
 // e0: in->w0
 // e1: r0->w1  c0
 // e2: r1->w2  c1
 // e3: r2->out c2
-func pipedEncoding(in io.Reader, out io.Writer, isEncode bool, encoders ...Encoding) (err error) {
+func pipedEncoding(in io.Reader, out io.Writer, isEncode bool, encoders ...codec) (err error) {
 	lgth := len(encoders)
 	cs := make([]chan error, 0)
 	rs := make([]io.Reader, 0)
@@ -207,10 +224,10 @@ func TestPipeEncode(t *testing.T) {
 
 	err := pipedEncoding(
 		in, out, true,
-		[]Encoding{
+		[]codec{
 			Get("hex"),
 			Get("rawbase64url"),
-			Get("gzip"),
+			archiver{GetCompressor("gzip")},
 			Get("base64"),
 		}...,
 	)
@@ -229,10 +246,10 @@ func TestPipeDecode(t *testing.T) {
 
 	err := pipedEncoding(
 		in, out, false,
-		[]Encoding{
+		[]codec{
 			Get("hex"),
 			Get("rawbase64url"),
-			Get("gzip"),
+			archiver{GetCompressor("gzip")},
 			Get("base64"),
 		}...,
 	)
@@ -243,3 +260,79 @@ func TestPipeDecode(t *testing.T) {
 	rslt := buf.Bytes()
 	fmt.Printf("TestPipeDecode() result: %s\n", rslt)
 }
+
+func TestCompressors(t *testing.T) {
+	inp := bytes.Repeat([]byte("HelloHowAreYou?I'mFineThankYouVeryMuch!\n.\n"), 100)
+	for _, n := range ListCompressors() {
+		c := GetCompressor(n)
+		if !c.CanCompress() {
+			if _, err := Compress(c, inp); err == nil {
+				t.Fatalf("TestCompressors() %v expecting compression not supported", n)
+			}
+			continue
+		}
+		cmp, err := Compress(c, inp)
+		if err != nil {
+			t.Fatalf("TestCompressors() %v %v", n, err)
+		}
+		dcp, err := Decompress(c, cmp)
+		if err != nil {
+			t.Fatalf("TestCompressors() %v %v", n, err)
+		}
+		if !bytes.Equal(inp, dcp) {
+			t.Fatalf("TestCompressors() %v round trip mismatched", n)
+		}
+		fmt.Printf("TestCompressors() %-5v %v -> %v\n", n, len(inp), len(cmp))
+	}
+}
+
+func TestCompressorLevel(t *testing.T) {
+	if _, err := GetCompressor("gzip").WithLevel(10); err == nil {
+		t.Fatal("TestCompressorLevel() expecting invalid level for gzip")
+	}
+	if _, err := GetCompressor("lzw").WithLevel(1); err == nil {
+		t.Fatal("TestCompressorLevel() expecting level not configurable for lzw")
+	}
+	c, err := GetCompressor("zstd").WithLevel(19)
+	if err != nil {
+		t.Fatal(err)
+	} else if c.Level() != 19 {
+		t.Fatalf("TestCompressorLevel() expecting level 19, got %v", c.Level())
+	}
+	if n, dec := ParseCompressor("gunzip"); n != "gzip" || !dec {
+		t.Fatalf("TestCompressorLevel() 'gunzip' parsed to '%v' %v", n, dec)
+	}
+}
+
+// upperHex hex encoding in upper case for testing registration
+type upperHex struct {
+	Hex
+}
+
+func (e upperHex) Name() string {
+	return "upperhex"
+}
+
+func (e upperHex) EncodeToString(inp []byte) string {
+	return strings.ToUpper(e.Hex.EncodeToString(inp))
+}
+
+func TestRegister(t *testing.T) {
+	if err := Register(upperHex{Hex(99)}, "HEXUP"); err != nil {
+		t.Fatal(err)
+	}
+	lst := List()
+	if lst[len(lst)-1] != "upperhex" {
+		t.Fatalf("TestRegister() registered schemes should be listed last: %v", lst)
+	}
+	if p := Parse("hexup"); p != "upperhex" {
+		t.Fatalf("TestRegister() parsing alias expecting 'upperhex', got '%v'", p)
+	}
+	if err := Register(upperHex{Hex(99)}); err == nil {
+		t.Fatal("TestRegister() expecting error registering duplicated name")
+	}
+	if s := Get(Parse("hexup")).EncodeToString([]byte{0xab, 0xcd}); s != "ABCD" {
+		t.Fatalf("TestRegister() unexpected result '%v'", s)
+	}
+	fmt.Printf("TestRegister() test okay: %v\n", lst)
+}
@@ -2,7 +2,9 @@ package encrypts
 
 import (
 	"fmt"
+	"reflect"
 	"sort"
+	"strings"
 
 	"sea9.org/go/c9ryptool/pkg/encrypts/asym"
 	"sea9.org/go/c9ryptool/pkg/encrypts/sym"
@@ -41,11 +43,12 @@ type AsymAlgorithm interface {
 }
 
 var aLGORITHMS = map[string]Algorithm{
-	"AES-128-GCM":       &sym.AesGcm128{},
-	"AES-192-GCM":       &sym.AesGcm192{},
-	"AES-256-GCM":       &sym.AesGcm256{},
-	"AES-256-CBC":       &sym.AesCbc256{},
-	"ChaCha20-Poly1305": &sym.ChaCha20Poly1305{},
+	"AES-128-GCM":        &sym.AesGcm128{},
+	"AES-192-GCM":        &sym.AesGcm192{},
+	"AES-256-GCM":        &sym.AesGcm256{},
+	"AES-256-CBC":        &sym.AesCbc256{},
+	"ChaCha20-Poly1305":  &sym.ChaCha20Poly1305{},
+	"XChaCha20-Poly1305": &sym.XChaCha20Poly1305{},
 }
 
 var aSYMALGORITHMS = map[string]AsymAlgorithm{
@@ -57,6 +60,75 @@ var aSYMALGORITHMS = map[string]AsymAlgorithm{
 	"ECIES-SECP256K1-ECIESGO": &asym.Secp256k1Eciesgo{},
 }
 
+// cONSTRUCTORS constructors of the registered algorithms
+var cONSTRUCTORS = map[string]func() Algorithm{}
+
+// aLIASES alternative names of the algorithms, in lower case, including the common JOSE, OpenSSL and age names
+var aLIASES = map[string]string{
+	"a128gcm":           "AES-128-GCM",
+	"aes128gcm":         "AES-128-GCM",
+	"id-aes128-gcm":     "AES-128-GCM",
+	"a192gcm":           "AES-192-GCM",
+	"aes192gcm":         "AES-192-GCM",
+	"id-aes192-gcm":     "AES-192-GCM",
+	"a256gcm":           "AES-256-GCM",
+	"aes256gcm":         "AES-256-GCM",
+	"id-aes256-gcm":     "AES-256-GCM",
+	"aes256cbc":         "AES-256-CBC",
+	"chacha":            "ChaCha20-Poly1305",
+	"chacha20":          "ChaCha20-Poly1305",
+	"chacha20poly1305":  "ChaCha20-Poly1305",
+	"c20p1305":          "ChaCha20-Poly1305",
+	"xchacha":           "XChaCha20-Poly1305",
+	"xchacha20":         "XChaCha20-Poly1305",
+	"xchacha20poly1305": "XChaCha20-Poly1305",
+	"xc20p":             "XChaCha20-Poly1305",
+	"rsa-oaep-256":      "RSA-2048-OAEP-SHA256",
+	"rsa-oaep-sha256":   "RSA-2048-OAEP-SHA256",
+	"rsa-oaep-512":      "RSA-2048-OAEP-SHA512",
+	"rsa-oaep-sha512":   "RSA-2048-OAEP-SHA512",
+	"rsa1_5":            "RSA-2048-PKCS1v15",
+	"rsa-pkcs1":         "RSA-2048-PKCS1v15",
+	"decred":            "ECIES-SECP256K1-DECRED",
+	"eciesgo":           "ECIES-SECP256K1-ECIESGO",
+}
+
+// Register register an additional encryption algorithm, 'ctor' creates new instances of the algorithm, and
+// 'aliases' are alternative names matched exactly (ignoring case) when parsing algorithm names. Asymmetric
+// algorithms must implement AsymAlgorithm. Register is expected to be called during initialization, e.g. in
+// init(), before any algorithm is used.
+func Register(ctor func() Algorithm, aliases ...string) (err error) {
+	if ctor == nil {
+		return fmt.Errorf("[ENCR] algorithm constructor missing")
+	}
+	alg := ctor()
+	if alg == nil || alg.Name() == "" {
+		return fmt.Errorf("[ENCR] invalid algorithm")
+	}
+	name := alg.Name()
+	if err = utils.CheckNames(name, aliases, List(0), aLIASES); err != nil {
+		return fmt.Errorf("[ENCR]%v", err)
+	}
+
+	if alg.Type() {
+		aLGORITHMS[name] = alg
+	} else if a, ok := alg.(AsymAlgorithm); ok {
+		aSYMALGORITHMS[name] = a
+	} else {
+		return fmt.Errorf("[ENCR] asymmetric algorithm '%v' does not implement AsymAlgorithm", name)
+	}
+	cONSTRUCTORS[name] = ctor
+	for _, a := range aliases {
+		aLIASES[strings.ToLower(a)] = name
+	}
+	return
+}
+
+// Aliases alternative names of the given algorithm.
+func Aliases(name string) []string {
+	return utils.AliasesOf(name, aLIASES)
+}
+
 func Default() string {
 	return "ChaCha20-Poly1305" //"AES-256-GCM"
 }
@@ -80,6 +152,8 @@ func List(typ int) (list []string) {
 	return
 }
 
+// Get get the algorithm of the given name. The returned instance is shared, use New instead if the key is
+// populated concurrently.
 func Get(inp string) Algorithm {
 	a, ok := aLGORITHMS[inp]
 	if !ok {
@@ -88,14 +162,24 @@ func Get(inp string) Algorithm {
 	return a
 }
 
+// New create a new instance of the algorithm of the given name, nil if the algorithm is not found.
+func New(inp string) Algorithm {
+	if ctor, ok := cONSTRUCTORS[inp]; ok {
+		return ctor()
+	}
+	a := Get(inp)
+	if a == nil {
+		return nil
+	}
+	return reflect.New(reflect.TypeOf(a).Elem()).Interface().(Algorithm)
+}
+
 // Validate validate the given algorithm name.
 // typ: -1 - asymmetric; 0 - don't care; 1 - symmetric
 // returns t: 'true' is symmetric, false is asymmetric
 func Validate(algr string, typ int) (t bool, err error) {
-	real := Parse(algr)
-	if real == "" {
-		err = fmt.Errorf("[ENCR] invalid encryption algorithm name pattern '%v'", algr)
-	} else {
+	real, err := Resolve(algr)
+	if err == nil {
 		a0, k0 := aLGORITHMS[real]
 		a1, k1 := aSYMALGORITHMS[real]
 		if !k0 && !k1 {
@@ -112,20 +196,17 @@ func Validate(algr string, typ int) (t bool, err error) {
 
 // Parse return details of the given encryption algorithm
 func Parse(inp string) (name string) {
-	algrs := make([]string, len(aLGORITHMS)+len(aSYMALGORITHMS))
-	i := 0
-	for _, a := range aLGORITHMS {
-		algrs[i] = a.Name()
-		i++
-	}
-	for _, a := range aSYMALGORITHMS {
-		algrs[i] = a.Name()
-		i++
-	}
+	name, _ = Resolve(inp)
+	return
+}
 
-	indices, str, _ := utils.BestMatch(inp, algrs, true)
-	if len(indices) == 1 {
-		name = str
+// Resolve resolve the given algorithm name or alias, the error lists the candidates if the name is ambiguous.
+func Resolve(inp string) (name string, err error) {
+	name, cands := utils.Resolve(inp, List(0), aLIASES)
+	if name == "" && len(cands) > 0 {
+		err = fmt.Errorf("[ENCR] ambiguous encryption algorithm name '%v', candidates: %v", inp, strings.Join(cands, ", "))
+	} else if name == "" {
+		err = fmt.Errorf("[ENCR] invalid encryption algorithm name pattern '%v'", inp)
 	}
 	return
 }
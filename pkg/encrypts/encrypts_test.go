@@ -2,6 +2,7 @@ package encrypts
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"sea9.org/go/c9ryptool/pkg/utils"
@@ -76,3 +77,262 @@ func TestParse(t *testing.T) {
 		display(i, inp)
 	}
 }
+
+func TestResolve(t *testing.T) {
+	tests := map[string]string{
+		"A256GCM":            "AES-256-GCM",
+		"id-aes128-GCM":      "AES-128-GCM",
+		"RSA-OAEP-256":       "RSA-2048-OAEP-SHA256",
+		"RSA1_5":             "RSA-2048-PKCS1v15",
+		"chacha":             "ChaCha20-Poly1305",
+		"xchacha":            "XChaCha20-Poly1305",
+		"xchacha20-poly1305": "XChaCha20-Poly1305",
+		"aes-256":            "",
+		"rsa-oaep":           "",
+		"3DES-64-GCM":        "",
+	}
+	for inp, exp := range tests {
+		name, err := Resolve(inp)
+		if name != exp {
+			t.Fatalf("TestResolve() '%v' expecting '%v', got '%v'", inp, exp, name)
+		}
+		fmt.Printf("TestResolve() %-18v -> '%v' %v\n", inp, name, err)
+	}
+
+	_, err := Resolve("aes-256")
+	if err == nil || !strings.Contains(err.Error(), "AES-256-CBC, AES-256-GCM") {
+		t.Fatalf("TestResolve() expecting candidates in the error, got %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, n := range List(0) {
+		a0, a1 := New(n), New(n)
+		if a0 == nil || a1 == nil || a0.Name() != n {
+			t.Fatalf("TestNew() failed to create '%v'", n)
+		}
+		if a0 == a1 || a0 == Get(n) {
+			t.Fatalf("TestNew() '%v' instances are shared", n)
+		}
+	}
+	if New("AES-512-XYZ") != nil {
+		t.Fatal("TestNew() expecting nil for unknown algorithm")
+	}
+
+	k0, k1 := New("AES-256-GCM"), New("AES-256-GCM")
+	k0.PopulateKey(nil)
+	k1.PopulateKey(nil)
+	if string(k0.GetKey()) == string(k1.GetKey()) {
+		t.Fatal("TestNew() keys of different instances should not be shared")
+	}
+	fmt.Println("TestNew() test okay")
+}
+
+func TestV2(t *testing.T) {
+	plain := []byte("HelloHowAreYou?I'mFineThankYouVeryMuch!")
+	aad := []byte("header")
+	for _, n := range []string{"AES-128-GCM", "AES-192-GCM", "AES-256-GCM", "ChaCha20-Poly1305", "XChaCha20-Poly1305"} {
+		a := NewV2(n)
+		if err := a.PopulateKey(nil); err != nil {
+			t.Fatal(err)
+		}
+		nsize := 12
+		if n == "XChaCha20-Poly1305" {
+			nsize = 24
+		}
+		if !a.SupportsAAD() || a.NonceSize() != nsize || a.TagSize() != 16 {
+			t.Fatalf("TestV2() %v unexpected capabilities", n)
+		}
+
+		enc, err := a.Encrypt(EncryptRequest{Plaintext: plain, AAD: aad})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(enc.Nonce) != a.NonceSize() || len(enc.Tag) != a.TagSize() || len(enc.Ciphertext) != len(plain) {
+			t.Fatalf("TestV2() %v unexpected result sizes", n)
+		}
+
+		// combined output
+		dec, err := a.Decrypt(DecryptRequest{Ciphertext: enc.Output, AAD: aad})
+		if err != nil || string(dec.Plaintext) != string(plain) {
+			t.Fatalf("TestV2() %v decrypt output failed: %v", n, err)
+		}
+		// detached nonce and tag
+		dec, err = a.Decrypt(DecryptRequest{Ciphertext: enc.Ciphertext, Nonce: enc.Nonce, Tag: enc.Tag, AAD: aad})
+		if err != nil || string(dec.Plaintext) != string(plain) {
+			t.Fatalf("TestV2() %v decrypt detached failed: %v", n, err)
+		}
+		// wrong AAD
+		if _, err = a.Decrypt(DecryptRequest{Ciphertext: enc.Output}); err == nil {
+			t.Fatalf("TestV2() %v decrypt without AAD should fail", n)
+		}
+
+		// given nonce
+		nonce := enc.Nonce
+		enc, err = a.Encrypt(EncryptRequest{Plaintext: plain, Nonce: nonce, AAD: aad})
+		if err != nil || string(enc.Nonce) != string(nonce) {
+			t.Fatalf("TestV2() %v encrypt with nonce failed: %v", n, err)
+		}
+		if _, err = a.Encrypt(EncryptRequest{Plaintext: plain, Nonce: nonce[1:]}); err == nil {
+			t.Fatalf("TestV2() %v expecting invalid nonce size error", n)
+		}
+	}
+
+	a := NewV2("RSA-2048-OAEP-SHA256")
+	if err := a.PopulateKey(nil); err != nil {
+		t.Fatal(err)
+	}
+	if a.SupportsAAD() || a.NonceSize() != 0 || a.TagSize() != 0 {
+		t.Fatal("TestV2() RSA unexpected capabilities")
+	}
+	if _, err := a.Encrypt(EncryptRequest{Plaintext: plain, AAD: aad}); err == nil {
+		t.Fatal("TestV2() RSA expecting AAD not supported error")
+	}
+	enc, err := a.Encrypt(EncryptRequest{Plaintext: plain})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := a.Decrypt(DecryptRequest{Ciphertext: enc.Ciphertext})
+	if err != nil || string(dec.Plaintext) != string(plain) {
+		t.Fatalf("TestV2() RSA decrypt failed: %v", err)
+	}
+	fmt.Println("TestV2() test okay")
+}
+
+// xorAlgr trivial algorithm for testing registration
+type xorAlgr struct {
+	key []byte
+}
+
+func (a *xorAlgr) Name() string   { return "XOR-TEST" }
+func (a *xorAlgr) Type() bool     { return true }
+func (a *xorAlgr) KeyLength() int { return 8 }
+func (a *xorAlgr) GetKey() []byte { return a.key }
+func (a *xorAlgr) PopulateKey(key []byte) error {
+	if len(key) != a.KeyLength() {
+		return fmt.Errorf("invalid key length %v", len(key))
+	}
+	a.key = key
+	return nil
+}
+func (a *xorAlgr) Encrypt(input ...[]byte) ([][]byte, error) {
+	out := make([]byte, len(input[0]))
+	for i, b := range input[0] {
+		out[i] = b ^ a.key[i%len(a.key)]
+	}
+	return [][]byte{out}, nil
+}
+func (a *xorAlgr) Decrypt(input ...[]byte) ([][]byte, error) {
+	return a.Encrypt(input...)
+}
+
+// aeadAlgr trivial algorithm with AAD for testing the capabilities of registered algorithms
+type aeadAlgr struct {
+	xorAlgr
+}
+
+func (a *aeadAlgr) Name() string      { return "XOR-AEAD-TEST" }
+func (a *aeadAlgr) NonceSize() int    { return 0 }
+func (a *aeadAlgr) TagSize() int      { return 0 }
+func (a *aeadAlgr) SupportsAAD() bool { return true }
+func (a *aeadAlgr) Encrypt(input ...[]byte) ([][]byte, error) {
+	out, _ := a.xorAlgr.Encrypt(input[0])
+	if len(input) > 2 {
+		out[0] = append(out[0], input[2]...)
+	}
+	return out, nil
+}
+func (a *aeadAlgr) Decrypt(input ...[]byte) ([][]byte, error) {
+	out := input[0]
+	if len(input) > 3 {
+		if !strings.HasSuffix(string(out), string(input[3])) {
+			return nil, fmt.Errorf("AAD mismatched")
+		}
+		out = out[:len(out)-len(input[3])]
+	}
+	return a.xorAlgr.Decrypt(out)
+}
+
+func TestRegister(t *testing.T) {
+	if err := Register(func() Algorithm { return &xorAlgr{} }, "xortest", "XT"); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []string{"XOR-TEST", "xor-test", "xt", "XorTest"} {
+		if p := Parse(n); p != "XOR-TEST" {
+			t.Fatalf("TestRegister() parsing '%v' expecting 'XOR-TEST', got '%v'", n, p)
+		}
+	}
+	if _, err := Validate("xt", 1); err != nil {
+		t.Fatal(err)
+	}
+	if a := Aliases("XOR-TEST"); len(a) != 2 || a[0] != "xortest" || a[1] != "xt" {
+		t.Fatalf("TestRegister() unexpected aliases %v", a)
+	}
+
+	if err := Register(func() Algorithm { return &xorAlgr{} }); err == nil {
+		t.Fatal("TestRegister() expecting error registering duplicated name")
+	}
+	err := Register(func() Algorithm { return &xorAlgr{} }, "aes-256-gcm")
+	if err == nil {
+		t.Fatal("TestRegister() expecting error registering duplicated alias")
+	}
+
+	a := NewV2("XOR-TEST")
+	if err = a.PopulateKey([]byte("12345678")); err != nil {
+		t.Fatal(err)
+	}
+	enc, _ := a.Encrypt(EncryptRequest{Plaintext: []byte("HelloHowAreYou?")})
+	dec, _ := a.Decrypt(DecryptRequest{Ciphertext: enc.Output})
+	if string(dec.Plaintext) != "HelloHowAreYou?" {
+		t.Fatal("TestRegister() round trip failed")
+	}
+
+	if err = Register(func() Algorithm { return &aeadAlgr{} }); err != nil {
+		t.Fatal(err)
+	}
+	a = NewV2("XOR-AEAD-TEST")
+	if !a.SupportsAAD() || a.NonceSize() != 0 {
+		t.Fatal("TestRegister() capabilities of registered algorithm not reported")
+	}
+	_ = a.PopulateKey([]byte("12345678"))
+	enc, err = a.Encrypt(EncryptRequest{Plaintext: []byte("HelloHowAreYou?"), AAD: []byte("hdr")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.Decrypt(DecryptRequest{Ciphertext: enc.Output, AAD: []byte("rdh")}); err == nil {
+		t.Fatal("TestRegister() expecting error decrypting with a different AAD")
+	}
+	fmt.Printf("TestRegister() test okay: %v\n", err)
+}
+
+func TestSign(t *testing.T) {
+	dat := []byte("HelloHowAreYou?I'mFineThankYouVeryMuch!")
+	for _, n := range []string{"RSA-2048-PKCS1v15", "RSA-2048-OAEP-SHA256", "decred", "eciesgo"} {
+		prv := New(Parse(n))
+		if err := prv.PopulateKey(nil); err != nil {
+			t.Fatal(err)
+		}
+		sig, err := Sign(prv, dat)
+		if err != nil {
+			t.Fatalf("TestSign() %v: %v", n, err)
+		}
+
+		pub := New(Parse(n))
+		if err = pub.PopulateKey(prv.(AsymAlgorithm).GetPublicKey()); err != nil {
+			t.Fatal(err)
+		}
+		if err = Verify(pub, dat, sig); err != nil {
+			t.Fatalf("TestSign() %v: %v", n, err)
+		}
+		if err = Verify(pub, dat[1:], sig); err == nil {
+			t.Fatalf("TestSign() %v expecting error verifying altered data", n)
+		}
+		if _, err = Sign(pub, dat); err == nil {
+			t.Fatalf("TestSign() %v expecting error signing without private key", n)
+		}
+	}
+	if _, err := Sign(New("AES-256-GCM"), dat); err == nil {
+		t.Fatal("TestSign() expecting error signing with symmetric algorithm")
+	}
+	fmt.Println("TestSign() test okay")
+}
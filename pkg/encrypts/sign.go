@@ -0,0 +1,108 @@
+package encrypts
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+
+	"sea9.org/go/c9ryptool/pkg/encrypts/asym"
+)
+
+// Sign sign the SHA-256 digest of 'dat' using the private key populated in the given asymmetric algorithm. RSA keys
+// sign with RSA-PSS, except RSA-2048-PKCS1v15 signing with PKCS #1 v1.5, and secp256k1 keys sign with ECDSA (DER
+// encoded).
+func Sign(alg Algorithm, dat []byte) (sig []byte, err error) {
+	dgt := sha256.Sum256(dat)
+	switch a := alg.(type) {
+	case *asym.Rsa2048Pkcs1v15:
+		if a.PrivateKey == nil {
+			break
+		}
+		return rsa.SignPKCS1v15(rand.Reader, a.PrivateKey, crypto.SHA256, dgt[:])
+	case *asym.Rsa2048OaepSha256:
+		if a.PrivateKey == nil {
+			break
+		}
+		return rsa.SignPSS(rand.Reader, a.PrivateKey, crypto.SHA256, dgt[:], nil)
+	case *asym.Rsa2048OaepSha512:
+		if a.PrivateKey == nil {
+			break
+		}
+		return rsa.SignPSS(rand.Reader, a.PrivateKey, crypto.SHA256, dgt[:], nil)
+	case *asym.Rsa4096OaepSha512:
+		if a.N == nil || a.D == nil {
+			break
+		}
+		return rsa.SignPSS(rand.Reader, (*rsa.PrivateKey)(a), crypto.SHA256, dgt[:], nil)
+	case *asym.Secp256k1Decred:
+		if a.PrivateKey == nil {
+			break
+		}
+		return ecdsa.Sign(a.PrivateKey, dgt[:]).Serialize(), nil
+	case *asym.Secp256k1Eciesgo:
+		if a.PrivateKey == nil {
+			break
+		}
+		return ecdsa.Sign(secp256k1.PrivKeyFromBytes(a.PrivateKey.Bytes()), dgt[:]).Serialize(), nil
+	default:
+		return nil, fmt.Errorf("[SIGN] signing not supported by algorithm '%v'", alg.Name())
+	}
+	return nil, fmt.Errorf("[SIGN] private key of algorithm '%v' missing", alg.Name())
+}
+
+// Verify verify the signature created by Sign() using the public key populated in the given asymmetric algorithm.
+func Verify(alg Algorithm, dat, sig []byte) (err error) {
+	dgt := sha256.Sum256(dat)
+	err = fmt.Errorf("public key of algorithm '%v' missing", alg.Name())
+	switch a := alg.(type) {
+	case *asym.Rsa2048Pkcs1v15:
+		if a.PublicKey != nil {
+			err = rsa.VerifyPKCS1v15(a.PublicKey, crypto.SHA256, dgt[:], sig)
+		}
+	case *asym.Rsa2048OaepSha256:
+		if a.PublicKey != nil {
+			err = rsa.VerifyPSS(a.PublicKey, crypto.SHA256, dgt[:], sig, nil)
+		}
+	case *asym.Rsa2048OaepSha512:
+		if a.PublicKey != nil {
+			err = rsa.VerifyPSS(a.PublicKey, crypto.SHA256, dgt[:], sig, nil)
+		}
+	case *asym.Rsa4096OaepSha512:
+		if a.N != nil {
+			err = rsa.VerifyPSS(&a.PublicKey, crypto.SHA256, dgt[:], sig, nil)
+		}
+	case *asym.Secp256k1Decred:
+		if a.PublicKey != nil {
+			err = verifyEcdsa(a.PublicKey, dgt[:], sig)
+		}
+	case *asym.Secp256k1Eciesgo:
+		if a.PublicKey != nil {
+			var pub *secp256k1.PublicKey
+			if pub, err = secp256k1.ParsePubKey(a.PublicKey.Bytes(false)); err == nil {
+				err = verifyEcdsa(pub, dgt[:], sig)
+			}
+		}
+	default:
+		err = fmt.Errorf("signing not supported by algorithm '%v'", alg.Name())
+	}
+	if err != nil {
+		return fmt.Errorf("[SIGN] %v", err)
+	}
+	return
+}
+
+func verifyEcdsa(pub *secp256k1.PublicKey, dgt, sig []byte) error {
+	s, err := ecdsa.ParseDERSignature(sig)
+	if err != nil {
+		return err
+	}
+	if !s.Verify(dgt, pub) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
@@ -2,14 +2,17 @@ package sym
 
 import (
 	"bytes"
+	"crypto/cipher"
 	"fmt"
 
 	"golang.org/x/crypto/chacha20poly1305"
 )
 
+// encryptChacha20Poly1305 'aeadNew' is either chacha20poly1305.New or chacha20poly1305.NewX
 func encryptChacha20Poly1305(
 	key []byte,
 	inputs [][]byte,
+	aeadNew func([]byte) (cipher.AEAD, error),
 ) (
 	results [][]byte,
 	err error,
@@ -19,7 +22,7 @@ func encryptChacha20Poly1305(
 		return
 	}
 
-	aead, err := chacha20poly1305.New(key)
+	aead, err := aeadNew(key)
 	if err != nil {
 		return
 	}
@@ -29,6 +32,7 @@ func encryptChacha20Poly1305(
 	switch len(inputs) {
 	case 3:
 		aad = inputs[2]
+		fallthrough
 	case 2:
 		iv = inputs[1]
 	case 0:
@@ -57,12 +61,13 @@ func encryptChacha20Poly1305(
 func decryptChacha20Poly1305(
 	key []byte,
 	inputs [][]byte,
+	aeadNew func([]byte) (cipher.AEAD, error),
 ) ([][]byte, error) {
 	if len(key) <= 0 {
 		return nil, fmt.Errorf("[CHACHA] not ready")
 	}
 
-	aead, err := chacha20poly1305.New(key)
+	aead, err := aeadNew(key)
 	if err != nil {
 		return nil, err
 	}
@@ -132,9 +137,46 @@ func (a *ChaCha20Poly1305) PopulateKey(key []byte) (err error) {
 }
 
 func (a *ChaCha20Poly1305) Encrypt(input ...[]byte) ([][]byte, error) {
-	return encryptChacha20Poly1305(*a, input)
+	return encryptChacha20Poly1305(*a, input, chacha20poly1305.New)
 }
 
 func (a *ChaCha20Poly1305) Decrypt(input ...[]byte) ([][]byte, error) {
-	return decryptChacha20Poly1305(*a, input)
+	return decryptChacha20Poly1305(*a, input, chacha20poly1305.New)
+}
+
+// ////////////////// //
+// XChaCha20-Poly1305
+type XChaCha20Poly1305 []byte
+
+func (a *XChaCha20Poly1305) Name() string {
+	return "XChaCha20-Poly1305"
+}
+
+func (a *XChaCha20Poly1305) Type() bool {
+	return true
+}
+
+func (a *XChaCha20Poly1305) KeyLength() int {
+	return 256 / 8
+}
+
+func (a *XChaCha20Poly1305) GetKey() []byte {
+	return *a
+}
+
+func (a *XChaCha20Poly1305) PopulateKey(key []byte) (err error) {
+	if key == nil {
+		*a, err = Generate(a.KeyLength())
+	} else {
+		*a = key
+	}
+	return
+}
+
+func (a *XChaCha20Poly1305) Encrypt(input ...[]byte) ([][]byte, error) {
+	return encryptChacha20Poly1305(*a, input, chacha20poly1305.NewX)
+}
+
+func (a *XChaCha20Poly1305) Decrypt(input ...[]byte) ([][]byte, error) {
+	return decryptChacha20Poly1305(*a, input, chacha20poly1305.NewX)
 }
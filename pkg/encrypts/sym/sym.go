@@ -14,7 +14,8 @@ const P = 1
 
 // PopulateKeyFromPassword get a key of 'keyLen' bytes long from the given passpharse
 // using the scrypt method. The salt to use is stored at the end of the cipher text,
-// separated by a dot (`.`)
+// separated by a dot (`.`). All the characters of 'passwd' are used, the line break of
+// the interactive input is already removed by utils.Prompt().
 func PopulateKeyFromPassword(
 	passwd string,
 	input []byte,
@@ -35,7 +36,7 @@ func PopulateKeyFromPassword(
 		}
 	}
 
-	key, err := scrypt.Key([]byte(passwd[:len(passwd)-1]), salt, N, R, P, keyLen)
+	key, err := scrypt.Key([]byte(passwd), salt, N, R, P, keyLen)
 	if err != nil {
 		err = fmt.Errorf("[PASS] %v", err)
 		return
@@ -0,0 +1,214 @@
+package encrypts
+
+import (
+	"fmt"
+
+	"sea9.org/go/c9ryptool/pkg/encrypts/sym"
+)
+
+// EncryptRequest parameters of encryption
+type EncryptRequest struct {
+	Plaintext []byte
+	Nonce     []byte // IV / nonce, generated if nil
+	AAD       []byte // additional authenticated data
+}
+
+// EncryptResult result of encryption
+type EncryptResult struct {
+	Output     []byte // the complete output, i.e. nonce, ciphertext and tag concatenated if applicable
+	Nonce      []byte // nil if not applicable
+	Ciphertext []byte // the actual ciphertext
+	Tag        []byte // authentication tag, nil if not applicable
+}
+
+// DecryptRequest parameters of decryption
+type DecryptRequest struct {
+	Ciphertext []byte // begins with the nonce if 'Nonce' is nil, ends with the tag if 'Tag' is nil
+	Nonce      []byte
+	Tag        []byte
+	AAD        []byte
+}
+
+// DecryptResult result of decryption
+type DecryptResult struct {
+	Plaintext []byte
+}
+
+// AlgorithmV2 encryption algorithms with explicit encryption/decryption parameters
+type AlgorithmV2 interface {
+	// Name algorithm name.
+	Name() string
+
+	// Type type of encryption algorithms. 'true' is symmetric, false is asymmetric
+	Type() bool
+
+	// KeyLength may be in bytes or bits, depends on the algorithm.
+	KeyLength() int
+
+	// GetKey get key
+	GetKey() []byte
+
+	// PopulateKey populate key for the algorithm to use. If input byte slice is empty, a new key is generated.
+	PopulateKey([]byte) error
+
+	// NonceSize size of the IV / nonce in bytes, 0 if not applicable.
+	NonceSize() int
+
+	// TagSize size of the authentication tag in bytes, 0 if not applicable.
+	TagSize() int
+
+	// SupportsAAD 'true' if additional authenticated data is supported.
+	SupportsAAD() bool
+
+	// Encrypt encrypt as specified in the request.
+	Encrypt(EncryptRequest) (EncryptResult, error)
+
+	// Decrypt decrypt as specified in the request.
+	Decrypt(DecryptRequest) (DecryptResult, error)
+}
+
+// Capabilities optional interface of the algorithms added by Register(), reporting the nonce size, the tag size and
+// the AAD support to V2(). Algorithms not implementing it support neither nonce, tag nor AAD.
+type Capabilities interface {
+	NonceSize() int
+	TagSize() int
+	SupportsAAD() bool
+}
+
+// V2 adapt the given algorithm to the AlgorithmV2 interface, the key populated is shared with 'alg'.
+func V2(alg Algorithm) AlgorithmV2 {
+	if alg == nil {
+		return nil
+	}
+	a := &adapter{alg: alg}
+	switch c := alg.(type) {
+	case *sym.AesGcm128, *sym.AesGcm192, *sym.AesGcm256, *sym.ChaCha20Poly1305:
+		a.nonce, a.tag, a.aad = 12, 16, true
+	case *sym.XChaCha20Poly1305:
+		a.nonce, a.tag, a.aad = 24, 16, true
+	case *sym.AesCbc256:
+		a.nonce = 16
+	case Capabilities:
+		a.nonce, a.tag, a.aad = c.NonceSize(), c.TagSize(), c.SupportsAAD()
+	} // asymmetric algorithms support neither nonce, tag nor AAD
+	return a
+}
+
+// NewV2 create a new instance of the algorithm of the given name, nil if the algorithm is not found.
+func NewV2(inp string) AlgorithmV2 {
+	return V2(New(inp))
+}
+
+// adapter map the explicit parameters to the positional parameters of Algorithm:
+// - encrypt : input, iv, aad
+// - decrypt : input, iv, tag, aad
+// - results : the complete output, iv, ciphertext, tag; or only the complete output
+type adapter struct {
+	alg   Algorithm
+	nonce int
+	tag   int
+	aad   bool
+}
+
+func (a *adapter) Name() string {
+	return a.alg.Name()
+}
+
+func (a *adapter) Type() bool {
+	return a.alg.Type()
+}
+
+func (a *adapter) KeyLength() int {
+	return a.alg.KeyLength()
+}
+
+func (a *adapter) GetKey() []byte {
+	return a.alg.GetKey()
+}
+
+func (a *adapter) PopulateKey(key []byte) error {
+	return a.alg.PopulateKey(key)
+}
+
+func (a *adapter) NonceSize() int {
+	return a.nonce
+}
+
+func (a *adapter) TagSize() int {
+	return a.tag
+}
+
+func (a *adapter) SupportsAAD() bool {
+	return a.aad
+}
+
+func (a *adapter) Encrypt(req EncryptRequest) (rst EncryptResult, err error) {
+	if req.Nonce != nil && a.nonce <= 0 {
+		err = fmt.Errorf("[ENCR] nonce not supported by %v", a.Name())
+		return
+	} else if req.Nonce != nil && len(req.Nonce) != a.nonce {
+		err = fmt.Errorf("[ENCR] invalid nonce size %v for %v, expecting %v", len(req.Nonce), a.Name(), a.nonce)
+		return
+	} else if req.AAD != nil && !a.aad {
+		err = fmt.Errorf("[ENCR] additional authenticated data not supported by %v", a.Name())
+		return
+	}
+
+	inputs := [][]byte{req.Plaintext}
+	if req.Nonce != nil || req.AAD != nil {
+		inputs = append(inputs, req.Nonce)
+	}
+	if req.AAD != nil {
+		inputs = append(inputs, req.AAD)
+	}
+
+	results, err := a.alg.Encrypt(inputs...)
+	if err != nil {
+		return
+	} else if len(results) < 1 || results[0] == nil {
+		err = fmt.Errorf("[ENCR] result missing")
+		return
+	}
+
+	rst.Output = results[0]
+	if len(results) >= 4 {
+		rst.Nonce, rst.Ciphertext, rst.Tag = results[1], results[2], results[3]
+	} else {
+		rst.Ciphertext = results[0]
+	}
+	return
+}
+
+func (a *adapter) Decrypt(req DecryptRequest) (rst DecryptResult, err error) {
+	if req.Nonce != nil && a.nonce <= 0 {
+		err = fmt.Errorf("[ENCR] nonce not supported by %v", a.Name())
+		return
+	} else if req.Tag != nil && a.tag <= 0 {
+		err = fmt.Errorf("[ENCR] authentication tag not supported by %v", a.Name())
+		return
+	} else if req.AAD != nil && !a.aad {
+		err = fmt.Errorf("[ENCR] additional authenticated data not supported by %v", a.Name())
+		return
+	}
+
+	inputs := [][]byte{req.Ciphertext}
+	if req.Nonce != nil || req.Tag != nil || req.AAD != nil {
+		inputs = append(inputs, req.Nonce)
+	}
+	if req.Tag != nil || req.AAD != nil {
+		inputs = append(inputs, req.Tag)
+	}
+	if req.AAD != nil {
+		inputs = append(inputs, req.AAD)
+	}
+
+	results, err := a.alg.Decrypt(inputs...)
+	if err != nil {
+		return
+	} else if len(results) < 1 { // nil is valid for empty plaintexts
+		err = fmt.Errorf("[ENCR] result missing")
+		return
+	}
+	rst.Plaintext = results[0]
+	return
+}
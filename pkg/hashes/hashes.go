@@ -10,16 +10,52 @@ import (
 	"hash"
 	"hash/fnv"
 	"sort"
+	"strings"
 
 	"sea9.org/go/c9ryptool/pkg/utils"
 )
 
-var hASHINGS = map[string]hash.Hash{
-	"md5":    md5.New(),
-	"sha1":   sha1.New(),
-	"sha256": sha256.New(),
-	"fnv":    fnv.New128(),
-	"fnv32":  fnv.New32(),
+var hASHINGS = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"fnv":    fnv.New128,
+	"fnv32":  func() hash.Hash { return fnv.New32() },
+}
+
+// aLIASES alternative names of the hashing algorithms, in lower case
+var aLIASES = map[string]string{
+	"md-5":     "md5",
+	"sha-1":    "sha1",
+	"sha-256":  "sha256",
+	"sha2-256": "sha256",
+	"s256":     "sha256",
+	"fnv128":   "fnv",
+	"fnv-128":  "fnv",
+	"fnv-32":   "fnv32",
+}
+
+// Register register an additional hashing algorithm, 'ctor' creates new instances of the algorithm, and 'aliases'
+// are alternative names matched exactly (ignoring case) when parsing algorithm names. Register is expected to be
+// called during initialization, e.g. in init().
+func Register(name string, ctor func() hash.Hash, aliases ...string) (err error) {
+	if name == "" || ctor == nil {
+		return fmt.Errorf("[HASH] invalid hashing algorithm")
+	}
+	if err = utils.CheckNames(name, aliases, List(), aLIASES); err != nil {
+		return fmt.Errorf("[HASH]%v", err)
+	}
+
+	hASHINGS[name] = ctor
+	for _, a := range aliases {
+		aLIASES[strings.ToLower(a)] = name
+	}
+	return
+}
+
+// Aliases alternative names of the given hashing algorithm.
+func Aliases(name string) []string {
+	return utils.AliasesOf(name, aLIASES)
 }
 
 func Default() string {
@@ -35,31 +71,33 @@ func List() (list []string) {
 	return
 }
 
+// Get create a new instance of the given hashing algorithm, nil if not found.
 func Get(algr string) hash.Hash {
-	return hASHINGS[algr]
+	if ctor, ok := hASHINGS[algr]; ok {
+		return ctor()
+	}
+	return nil
 }
 
-// Validate validate the given algorithm name. TODO HERE!!! change to use parsing similar to encryption algorithm names
+// Validate validate the given algorithm name.
 func Validate(inp string) (err error) {
-	algr := Parse(inp)
-	if algr == "" {
-		err = fmt.Errorf("[HASH] unsupported hashing algorithm '%v'", inp)
-	}
+	_, err = Resolve(inp)
 	return
 }
 
 // Parse return the actual hashing algorithm name
 func Parse(inp string) (name string) {
-	algrs := make([]string, len(hASHINGS))
-	i := 0
-	for n := range hASHINGS {
-		algrs[i] = n
-		i++
-	}
+	name, _ = Resolve(inp)
+	return
+}
 
-	indices, str, _ := utils.BestMatch(inp, algrs, true)
-	if len(indices) == 1 {
-		name = str
+// Resolve resolve the given hashing algorithm name or alias, the error lists the candidates if the name is ambiguous.
+func Resolve(inp string) (name string, err error) {
+	name, cands := utils.Resolve(inp, List(), aLIASES)
+	if name == "" && len(cands) > 0 {
+		err = fmt.Errorf("[HASH] ambiguous hashing algorithm name '%v', candidates: %v", inp, strings.Join(cands, ", "))
+	} else if name == "" {
+		err = fmt.Errorf("[HASH] invalid hashing algorithm name pattern '%v'", inp)
 	}
 	return
 }
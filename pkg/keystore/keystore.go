@@ -0,0 +1,367 @@
+// Package keystore named keys kept in a local file encrypted with a master password, or with the public key of a
+// recipient, so keys are referenced by names instead of by paths of loose key files.
+package keystore
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"sea9.org/go/c9ryptool/pkg/c9crypt"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/encrypts/sym"
+	"sea9.org/go/c9ryptool/pkg/utils"
+)
+
+// KEYSTORE environment variable of the keystore path
+const KEYSTORE = "C9_KEYSTORE"
+
+// PASSWORD environment variable of the master password, for non-interactive use
+const PASSWORD = "C9_KEYSTORE_PASSWORD"
+
+// MAGIC leading bytes of keystore files
+const MAGIC = "c9s1"
+
+// CIPHER encryption algorithm of the keystore content
+const CIPHER = "AES-256-GCM"
+
+// locking modes
+const (
+	MODE_PASSWORD  = 1
+	MODE_RECIPIENT = 2
+)
+
+// key rotation states
+const (
+	STATE_ACTIVE  = "active"  // used for both encryption and decryption
+	STATE_RETIRED = "retired" // used for decryption only
+)
+
+// rECIPIENTS asymmetric algorithms tried, in order, when detecting the algorithm of a recipient public key
+var rECIPIENTS = []string{
+	"ECIES-SECP256K1-DECRED",
+	"RSA-2048-OAEP-SHA256",
+}
+
+// Key a named key
+type Key struct {
+	Name      string            `json:"name"`
+	Algorithm string            `json:"algorithm"`
+	Key       []byte            `json:"key"` // raw symmetric key, or PEM encoded asymmetric private key
+	Created   time.Time         `json:"created"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	State     string            `json:"state,omitempty"` // rotation state, empty means active
+}
+
+// Active 'true' if the key can be used for encryption.
+func (k *Key) Active() bool {
+	return k.State == "" || k.State == STATE_ACTIVE
+}
+
+// New create a new instance of the key's algorithm with the key populated.
+func (k *Key) New() (alg encrypts.Algorithm, err error) {
+	if alg = encrypts.New(k.Algorithm); alg == nil {
+		return nil, fmt.Errorf("[KEYS] unsupported algorithm '%v' of key '%v'", k.Algorithm, k.Name)
+	}
+	if err = alg.PopulateKey(k.Key); err != nil {
+		return nil, fmt.Errorf("[KEYS][%v]%v", k.Name, err)
+	}
+	return
+}
+
+// Lock how the keystore file is encrypted, either with a master password, or with the public key of a recipient.
+// Recipients are similar to age / HPKE recipients: a new file key, encrypting the content, is encrypted with the
+// public key (ECIES secp256k1 or RSA-OAEP) and stored in the header, and is decrypted with the private key.
+type Lock struct {
+	Password  string // master password, all the characters are used to generate the key
+	Algorithm string // asymmetric algorithm of the recipient, detected from the key if empty
+	Recipient []byte // PEM encoded public key of the recipient, for creating keystores
+	Identity  []byte // PEM encoded private key of the recipient, for opening (and saving) keystores
+}
+
+// Keystore keys kept in an encrypted file
+type Keystore struct {
+	Keys map[string]*Key `json:"keys"`
+	path string
+	lock Lock
+}
+
+// DefaultPath path of the keystore, from the environment variable C9_KEYSTORE if set
+func DefaultPath() string {
+	if path := os.Getenv(KEYSTORE); path != "" {
+		return path
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "c9ryptool", "keystore.c9s")
+	}
+	return "keystore.c9s"
+}
+
+// Exists 'true' if the keystore file exists.
+func Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Create create a new empty keystore, the file is written by Save().
+func Create(path string, lock Lock) (ks *Keystore, err error) {
+	if Exists(path) {
+		return nil, fmt.Errorf("[KEYS] keystore '%v' already exists", path)
+	}
+	if lock.Password == "" && lock.Recipient == nil {
+		return nil, fmt.Errorf("[KEYS] either a master password or a recipient is required")
+	} else if lock.Password != "" && lock.Recipient != nil {
+		return nil, fmt.Errorf("[KEYS] master password and recipient are mutually exclusive")
+	}
+	if lock.Recipient != nil {
+		if lock.Algorithm, err = detect(lock.Algorithm, lock.Recipient); err != nil {
+			return
+		}
+	}
+	return &Keystore{Keys: make(map[string]*Key), path: path, lock: lock}, nil
+}
+
+// Open read and decrypt the keystore file, using the master password or the identity of the recipient in 'lock'.
+func Open(path string, lock Lock) (ks *Keystore, err error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("[KEYS] %v", err)
+	}
+	dat, lock, err := unseal(buf, lock)
+	if err != nil {
+		return nil, fmt.Errorf("[KEYS][%v]%v", path, err)
+	}
+
+	ks = &Keystore{path: path, lock: lock}
+	if err = json.Unmarshal(dat, ks); err != nil {
+		return nil, fmt.Errorf("[KEYS] invalid keystore '%v': %v", path, err)
+	}
+	if ks.Keys == nil {
+		ks.Keys = make(map[string]*Key)
+	}
+	for name, k := range ks.Keys {
+		k.Name = name
+		if k.Algorithm, err = encrypts.Resolve(k.Algorithm); err != nil {
+			return nil, fmt.Errorf("[KEYS][%v]%v", name, err)
+		}
+	}
+	return
+}
+
+// Save encrypt and write the keystore file, replacing the existing file atomically.
+func (ks *Keystore) Save() (err error) {
+	dat, err := json.Marshal(ks)
+	if err != nil {
+		return fmt.Errorf("[KEYS] %v", err)
+	}
+	buf, err := seal(dat, ks.lock)
+	if err != nil {
+		return fmt.Errorf("[KEYS]%v", err)
+	}
+
+	if Exists(ks.path) {
+		return utils.Replace(ks.path, "", buf)
+	}
+	if err = os.MkdirAll(filepath.Dir(ks.path), 0700); err != nil {
+		return fmt.Errorf("[KEYS] %v", err)
+	}
+	f, err := os.OpenFile(ks.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("[KEYS] %v", err)
+	}
+	if _, err = f.Write(buf); err != nil {
+		f.Close()
+		return fmt.Errorf("[KEYS] %v", err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("[KEYS] %v", err)
+	}
+	return
+}
+
+// Get get the key of the given name.
+func (ks *Keystore) Get(name string) (*Key, error) {
+	if k, ok := ks.Keys[name]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("[KEYS] key '%v' not found", name)
+}
+
+// Add add a new key, a new key is generated if 'Key' is empty, otherwise the key is validated against its algorithm.
+// 'Created' is set if empty.
+func (ks *Keystore) Add(k *Key) (err error) {
+	if k.Name == "" {
+		return fmt.Errorf("[KEYS] key name missing")
+	} else if _, ok := ks.Keys[k.Name]; ok {
+		return fmt.Errorf("[KEYS] key '%v' already exists", k.Name)
+	}
+	if k.State != "" && k.State != STATE_ACTIVE && k.State != STATE_RETIRED {
+		return fmt.Errorf("[KEYS] invalid state '%v', expecting '%v' or '%v'", k.State, STATE_ACTIVE, STATE_RETIRED)
+	}
+	if k.Algorithm, err = encrypts.Resolve(k.Algorithm); err != nil {
+		return fmt.Errorf("[KEYS]%v", err)
+	}
+	if k.Key == nil {
+		alg := encrypts.New(k.Algorithm)
+		if err = alg.PopulateKey(nil); err != nil {
+			return fmt.Errorf("[KEYS][GEN]%v", err)
+		}
+		k.Key = alg.GetKey()
+	}
+	alg, err := k.New()
+	if err != nil {
+		return
+	} else if alg.Type() && len(k.Key) != alg.KeyLength() {
+		return fmt.Errorf("[KEYS] invalid key length %v of '%v', expecting %v", len(k.Key), k.Algorithm, alg.KeyLength())
+	}
+	if k.Created.IsZero() {
+		k.Created = time.Now().UTC().Truncate(time.Second)
+	}
+	ks.Keys[k.Name] = k
+	return
+}
+
+// Remove remove the key of the given name.
+func (ks *Keystore) Remove(name string) error {
+	if _, ok := ks.Keys[name]; !ok {
+		return fmt.Errorf("[KEYS] key '%v' not found", name)
+	}
+	delete(ks.Keys, name)
+	return nil
+}
+
+// List list the keys ordered by names.
+func (ks *Keystore) List() (list []*Key) {
+	list = make([]*Key, 0, len(ks.Keys))
+	for _, k := range ks.Keys {
+		list = append(list, k)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return
+}
+
+// detect detect the algorithm of the recipient public key if 'algr' is empty
+func detect(algr string, pub []byte) (name string, err error) {
+	if algr != "" {
+		if name, err = encrypts.Resolve(algr); err != nil {
+			return
+		} else if a := encrypts.Get(name); a == nil || a.Type() {
+			return "", fmt.Errorf("[KEYS] recipient algorithm '%v' is not asymmetric", name)
+		}
+		if err = encrypts.New(name).PopulateKey(pub); err != nil {
+			err = fmt.Errorf("[KEYS][RCPT]%v", err)
+		}
+		return
+	}
+	for _, n := range rECIPIENTS {
+		if encrypts.New(n).PopulateKey(pub) == nil {
+			return n, nil
+		}
+	}
+	return "", fmt.Errorf("[KEYS] unsupported recipient public key")
+}
+
+// seal encrypt the keystore content, the file is:
+// MAGIC | mode (1) | [algorithm len (1) | algorithm | wrapped file key len (2) | wrapped file key] | ciphertext
+// the ciphertext is the output of c9crypt using CIPHER, with the preceding header as the AAD. The key of the
+// password mode is generated from the entire master password.
+func seal(dat []byte, lock Lock) (buf []byte, err error) {
+	hdr := []byte(MAGIC)
+	opts := c9crypt.Options{Algorithm: CIPHER}
+	if lock.Password != "" {
+		hdr = append(hdr, MODE_PASSWORD)
+		opts.Password = lock.Password
+	} else {
+		pub := lock.Recipient
+		if pub == nil {
+			alg := encrypts.New(lock.Algorithm)
+			if alg == nil || alg.PopulateKey(lock.Identity) != nil {
+				return nil, fmt.Errorf("[SEAL] invalid recipient identity")
+			}
+			pub = alg.(encrypts.AsymAlgorithm).GetPublicKey()
+		}
+		if opts.Key, err = sym.Generate(32); err != nil {
+			return nil, fmt.Errorf("[SEAL] %v", err)
+		}
+		var wrapped bytes.Buffer
+		err = c9crypt.Encrypt(context.Background(), bytes.NewReader(opts.Key), &wrapped, c9crypt.Options{Algorithm: lock.Algorithm, Key: pub})
+		if err != nil {
+			return nil, fmt.Errorf("[SEAL]%v", err)
+		} else if len(lock.Algorithm) > 255 || wrapped.Len() > 65535 {
+			return nil, fmt.Errorf("[SEAL] recipient too long")
+		}
+		hdr = append(hdr, MODE_RECIPIENT, byte(len(lock.Algorithm)))
+		hdr = append(hdr, lock.Algorithm...)
+		hdr = binary.BigEndian.AppendUint16(hdr, uint16(wrapped.Len()))
+		hdr = append(hdr, wrapped.Bytes()...)
+	}
+	opts.AAD = hdr
+
+	var out bytes.Buffer
+	if err = c9crypt.Encrypt(context.Background(), bytes.NewReader(dat), &out, opts); err != nil {
+		return nil, fmt.Errorf("[SEAL]%v", err)
+	}
+	return append(hdr, out.Bytes()...), nil
+}
+
+// unseal decrypt the keystore content, and return the lock completed with the recipient algorithm if any
+func unseal(buf []byte, lock Lock) (dat []byte, _ Lock, err error) {
+	errShort := errors.New("[UNSEAL] invalid keystore file, too short")
+	if len(buf) < len(MAGIC)+1 || string(buf[:len(MAGIC)]) != MAGIC {
+		return nil, lock, fmt.Errorf("[UNSEAL] not a keystore file")
+	}
+	opts := c9crypt.Options{Algorithm: CIPHER}
+	pos := len(MAGIC) + 1
+	switch buf[len(MAGIC)] {
+	case MODE_PASSWORD:
+		if lock.Password == "" {
+			return nil, lock, fmt.Errorf("[UNSEAL] keystore locked with a master password, password missing")
+		}
+		opts.Password = lock.Password
+	case MODE_RECIPIENT:
+		if lock.Identity == nil {
+			return nil, lock, fmt.Errorf("[UNSEAL] keystore locked with a recipient, identity missing")
+		}
+		if len(buf) < pos+1 {
+			return nil, lock, errShort
+		}
+		l := int(buf[pos])
+		if len(buf) < pos+1+l+2 {
+			return nil, lock, errShort
+		}
+		lock.Algorithm = string(buf[pos+1 : pos+1+l])
+		pos += 1 + l
+		w := int(binary.BigEndian.Uint16(buf[pos:]))
+		if len(buf) < pos+2+w {
+			return nil, lock, errShort
+		}
+		var key bytes.Buffer
+		err = c9crypt.Decrypt(context.Background(), bytes.NewReader(buf[pos+2:pos+2+w]), &key, c9crypt.Options{Algorithm: lock.Algorithm, Key: lock.Identity})
+		if err != nil {
+			return nil, lock, fmt.Errorf("[UNSEAL] identity mismatched: %v", err)
+		}
+		opts.Key = key.Bytes()
+		pos += 2 + w
+	default:
+		return nil, lock, fmt.Errorf("[UNSEAL] unsupported locking mode %v", buf[len(MAGIC)])
+	}
+	opts.AAD = buf[:pos]
+
+	var out bytes.Buffer
+	if err = c9crypt.Decrypt(context.Background(), bytes.NewReader(buf[pos:]), &out, opts); err != nil {
+		if lock.Password != "" {
+			return nil, lock, fmt.Errorf("[UNSEAL] incorrect master password or corrupted keystore")
+		}
+		return nil, lock, fmt.Errorf("[UNSEAL]%v", err)
+	}
+	return out.Bytes(), lock, nil
+}
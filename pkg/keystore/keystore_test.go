@@ -0,0 +1,117 @@
+package keystore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/encrypts/sym"
+)
+
+func TestPassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "keys.c9s")
+	ks, err := Create(path, Lock{Password: "abcd1234"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	k0, _ := sym.Generate(32)
+	if err = ks.Add(&Key{Name: "k1", Algorithm: "a256gcm", Key: k0, Labels: map[string]string{"env": "test"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = ks.Add(&Key{Name: "k0", Algorithm: "chacha", State: STATE_RETIRED}); err != nil {
+		t.Fatal(err)
+	}
+	if err = ks.Add(&Key{Name: "k2", Algorithm: "AES-128-GCM", Key: k0}); err == nil {
+		t.Fatal("TestPassword() expecting error adding key of invalid length")
+	}
+	if err = ks.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Fatalf("TestPassword() unexpected file mode %v", info.Mode())
+	}
+
+	if _, err = Open(path, Lock{Password: "abcd123"}); err == nil {
+		t.Fatal("TestPassword() expecting error opening with wrong password")
+	}
+	if _, err = Open(path, Lock{Password: "abcd1235"}); err == nil {
+		t.Fatal("TestPassword() expecting error opening with wrong last password character")
+	}
+	short := filepath.Join(t.TempDir(), "short.c9s")
+	if ks, err = Create(short, Lock{Password: "a"}); err != nil {
+		t.Fatal(err)
+	} else if err = ks.Save(); err != nil {
+		t.Fatal(err)
+	} else if _, err = Open(short, Lock{Password: "b"}); err == nil {
+		t.Fatal("TestPassword() expecting error opening with wrong single character password")
+	}
+	ks, err = Open(path, Lock{Password: "abcd1234"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := ks.List()
+	if len(list) != 2 || list[0].Name != "k0" || list[1].Algorithm != "AES-256-GCM" || list[0].Active() || !list[1].Active() ||
+		list[1].Labels["env"] != "test" || list[1].Created.IsZero() {
+		t.Fatalf("TestPassword() unexpected keys %v", list)
+	}
+
+	if err = ks.Remove("k0"); err != nil {
+		t.Fatal(err)
+	}
+	if err = ks.Save(); err != nil {
+		t.Fatal(err)
+	}
+	ks, _ = Open(path, Lock{Password: "abcd1234"})
+	if _, err = ks.Get("k0"); err == nil {
+		t.Fatal("TestPassword() expecting error getting removed key")
+	}
+	fmt.Printf("TestPassword() test okay: %v\n", err)
+}
+
+func TestRecipient(t *testing.T) {
+	for _, n := range []string{"ECIES-SECP256K1-DECRED", "RSA-2048-OAEP-SHA256"} {
+		id := encrypts.New(n)
+		id.PopulateKey(nil)
+		pub := id.(encrypts.AsymAlgorithm).GetPublicKey()
+
+		path := filepath.Join(t.TempDir(), "keys.c9s")
+		ks, err := Create(path, Lock{Recipient: pub})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ks.lock.Algorithm != n {
+			t.Fatalf("TestRecipient() detected '%v' expecting '%v'", ks.lock.Algorithm, n)
+		}
+		if err = ks.Add(&Key{Name: "asym", Algorithm: n, Key: id.GetKey()}); err != nil {
+			t.Fatal(err)
+		}
+		if err = ks.Save(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = Open(path, Lock{Password: "abcd1234"}); err == nil {
+			t.Fatalf("TestRecipient() %v expecting error opening without identity", n)
+		}
+
+		// saving with the identity only
+		ks, err = Open(path, Lock{Identity: id.GetKey()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ks.Remove("asym")
+		if err = ks.Save(); err != nil {
+			t.Fatal(err)
+		}
+		if ks, err = Open(path, Lock{Identity: id.GetKey()}); err != nil || len(ks.Keys) != 0 {
+			t.Fatalf("TestRecipient() %v reopening failed: %v", n, err)
+		}
+
+		other := encrypts.New(n)
+		other.PopulateKey(nil)
+		if _, err = Open(path, Lock{Identity: other.GetKey()}); err == nil {
+			t.Fatalf("TestRecipient() %v expecting error opening with another identity", n)
+		}
+	}
+	fmt.Println("TestRecipient() test okay")
+}
@@ -0,0 +1,110 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+)
+
+// ////////// //
+// Local file
+// key encryption key stored in a local file, either 32 raw bytes or encoded in one of the supported encoding schemes
+type File struct {
+	path string
+	kek  []byte
+	kid  string
+}
+
+// openFile 'file:///abs/path' or 'file:rel/path'
+func openFile(u *url.URL) (Provider, error) {
+	path := u.Path
+	if u.Opaque != "" {
+		path = u.Opaque
+	} else if u.Host != "" {
+		path = filepath.Join(u.Host, u.Path)
+	}
+	if path == "" {
+		return nil, fmt.Errorf("[FILE] key file path missing")
+	}
+	return NewFile(path)
+}
+
+// NewFile create a provider using the key encryption key in the given file.
+func NewFile(path string) (*File, error) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("[FILE] %v", err)
+	}
+
+	kek := dat
+	if len(kek) != 32 {
+		kek = nil
+		txt := string(bytes.TrimSpace(dat))
+		for _, n := range encodes.List() {
+			if k, err := encodes.Get(n).DecodeString(txt); err == nil && len(k) == 32 {
+				kek = k
+				break
+			}
+		}
+		if kek == nil {
+			return nil, fmt.Errorf("[FILE] invalid key encryption key in '%v', expecting 32 bytes", path)
+		}
+	}
+
+	hsh := sha256.Sum256(kek)
+	return &File{
+		path: path,
+		kek:  kek,
+		kid:  "file:" + hex.EncodeToString(hsh[:8]),
+	}, nil
+}
+
+func (p *File) Name() string {
+	return "file"
+}
+
+// KeyID fingerprint of the key encryption key, since the same key may be stored at different paths
+func (p *File) KeyID() string {
+	return p.kid
+}
+
+func (p *File) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	alg := encrypts.NewV2("AES-256-GCM")
+	if err := alg.PopulateKey(p.kek); err != nil {
+		return nil, fmt.Errorf("[FILE]%v", err)
+	}
+	rst, err := alg.Encrypt(encrypts.EncryptRequest{Plaintext: key, AAD: []byte(p.kid)})
+	if err != nil {
+		return nil, fmt.Errorf("[FILE]%v", err)
+	}
+	return rst.Output, nil
+}
+
+func (p *File) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if keyID != p.kid {
+		return nil, fmt.Errorf("[FILE] data key wrapped by '%v', but '%v' is '%v'", keyID, p.path, p.kid)
+	}
+	alg := encrypts.NewV2("AES-256-GCM")
+	if err := alg.PopulateKey(p.kek); err != nil {
+		return nil, fmt.Errorf("[FILE]%v", err)
+	}
+	rst, err := alg.Decrypt(encrypts.DecryptRequest{Ciphertext: wrapped, AAD: []byte(keyID)})
+	if err != nil {
+		return nil, fmt.Errorf("[FILE]%v", err)
+	}
+	return rst.Plaintext, nil
+}
@@ -0,0 +1,150 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// VAULT_TOKEN environment variable of the Vault token
+const VAULT_TOKEN = "VAULT_TOKEN"
+
+// ///////////////////// //
+// Vault Transit engine
+// key encryption key kept in a Vault transit secrets engine (or a compatible service), accessed via its HTTP API:
+// - POST /v1/{mount}/encrypt/{key} {"plaintext": base64} -> {"data": {"ciphertext": "vault:v1:..."}}
+// - POST /v1/{mount}/decrypt/{key} {"ciphertext": "vault:v1:..."} -> {"data": {"plaintext": base64}}
+type Vault struct {
+	addr   string // e.g. https://vault.example.com:8200
+	mount  string // e.g. transit
+	key    string // name of the key encryption key
+	token  string
+	client *http.Client
+}
+
+// openVault 'vault+https://host:port/{mount}/{key}', the token is read from the environment variable VAULT_TOKEN
+func openVault(u *url.URL) (Provider, error) {
+	scheme := strings.TrimPrefix(strings.ToLower(u.Scheme), "vault+")
+	mount, key := path.Split(strings.Trim(u.Path, "/"))
+	mount = strings.Trim(mount, "/")
+	if u.Host == "" || mount == "" || key == "" {
+		return nil, fmt.Errorf("[VAULT] invalid URI '%v', expecting 'vault+%v://host:port/mount/key'", u.Redacted(), scheme)
+	}
+	return NewVault(fmt.Sprintf("%v://%v", scheme, u.Host), mount, key, os.Getenv(VAULT_TOKEN), nil), nil
+}
+
+// NewVault create a provider using the transit engine mounted at 'mount' of the given Vault server, the default
+// HTTP client is used if 'client' is nil.
+func NewVault(addr, mount, key, token string, client *http.Client) *Vault {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Vault{
+		addr:   strings.TrimSuffix(addr, "/"),
+		mount:  mount,
+		key:    key,
+		token:  token,
+		client: client,
+	}
+}
+
+func (p *Vault) Name() string {
+	return "vault"
+}
+
+// Address address of the Vault server, e.g. 'https://vault.example.com:8200'
+func (p *Vault) Address() string {
+	return p.addr
+}
+
+// KeyID mount path and name of the key encryption key, the key version is kept in the wrapped key by Vault
+func (p *Vault) KeyID() string {
+	return p.mount + "/" + p.key
+}
+
+func (p *Vault) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	var rsp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	err := p.call(ctx, "encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(key)}, &rsp)
+	if err != nil {
+		return nil, err
+	} else if rsp.Data.Ciphertext == "" {
+		return nil, fmt.Errorf("[VAULT] ciphertext missing in response")
+	}
+	return []byte(rsp.Data.Ciphertext), nil
+}
+
+func (p *Vault) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	if keyID != p.KeyID() {
+		return nil, fmt.Errorf("[VAULT] data key wrapped by '%v', not '%v'", keyID, p.KeyID())
+	}
+	var rsp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	err := p.call(ctx, "decrypt", map[string]string{"ciphertext": string(wrapped)}, &rsp)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(rsp.Data.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("[VAULT] invalid plaintext in response: %v", err)
+	}
+	return key, nil
+}
+
+// call POST 'req' to the given transit operation, and decode the response into 'rsp'
+func (p *Vault) call(ctx context.Context, op string, req, rsp interface{}) (err error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("[VAULT] %v", err)
+	}
+	hrq, err := http.NewRequestWithContext(
+		ctx, http.MethodPost,
+		fmt.Sprintf("%v/v1/%v/%v/%v", p.addr, p.mount, op, url.PathEscape(p.key)),
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return fmt.Errorf("[VAULT] %v", err)
+	}
+	hrq.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		hrq.Header.Set("X-Vault-Token", p.token)
+	}
+
+	hrs, err := p.client.Do(hrq)
+	if err != nil {
+		return fmt.Errorf("[VAULT] %v", err)
+	}
+	defer hrs.Body.Close()
+	dat, err := io.ReadAll(io.LimitReader(hrs.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("[VAULT] %v", err)
+	}
+	if hrs.StatusCode != http.StatusOK {
+		var msg struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(dat, &msg) == nil && len(msg.Errors) > 0 {
+			return fmt.Errorf("[VAULT] %v %v: %v", op, hrs.Status, strings.Join(msg.Errors, "; "))
+		}
+		return fmt.Errorf("[VAULT] %v %v", op, hrs.Status)
+	}
+	if err = json.Unmarshal(dat, rsp); err != nil {
+		return fmt.Errorf("[VAULT] invalid response: %v", err)
+	}
+	return
+}
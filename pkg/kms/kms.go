@@ -0,0 +1,166 @@
+// Package kms key management services for envelope encryption. The data keys are generated locally, and wrapped
+// (encrypted) by a key encryption key held by the KMS, the wrapped data keys then travel with the ciphertexts.
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Provider key management service wrapping and unwrapping data keys
+type Provider interface {
+	// Name provider name, which is also the scheme of the provider URIs.
+	Name() string
+
+	// KeyID identifier of the key encryption key used for wrapping.
+	KeyID() string
+
+	// WrapKey wrap the given data key using the key encryption key.
+	WrapKey(context.Context, []byte) ([]byte, error)
+
+	// UnwrapKey unwrap the given wrapped data key, using the key encryption key of the given identifier.
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// Opener create a provider from the parsed provider URI
+type Opener func(*url.URL) (Provider, error)
+
+var pROVIDERS = map[string]Opener{
+	"file":        openFile,
+	"vault+http":  openVault,
+	"vault+https": openVault,
+}
+
+// Register register an additional provider for the given URI scheme. Register is expected to be called during
+// initialization, e.g. in init().
+func Register(scheme string, open Opener) (err error) {
+	scheme = strings.ToLower(scheme)
+	if scheme == "" || open == nil {
+		return fmt.Errorf("[KMS] invalid provider")
+	} else if _, ok := pROVIDERS[scheme]; ok {
+		return fmt.Errorf("[KMS] provider '%v' already registered", scheme)
+	}
+	pROVIDERS[scheme] = open
+	return
+}
+
+// List list the URI schemes of the available providers.
+func List() (list []string) {
+	list = make([]string, 0)
+	for k := range pROVIDERS {
+		list = append(list, k)
+	}
+	sort.Strings(list)
+	return
+}
+
+// Open create the provider of the given URI, e.g.:
+// - file:///path/to/kek
+// - vault+https://vault.example.com:8200/transit/my-key
+func Open(uri string) (prv Provider, err error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("[KMS] invalid URI: %v", err)
+	}
+	open, ok := pROVIDERS[strings.ToLower(u.Scheme)]
+	if !ok {
+		return nil, fmt.Errorf("[KMS] unsupported provider '%v'", u.Scheme)
+	}
+	if prv, err = open(u); err != nil {
+		err = fmt.Errorf("[KMS]%v", err)
+	}
+	return
+}
+
+// MAGIC beginning of the envelope headers
+const MAGIC = "c9k1"
+
+// Envelope header prepended to the ciphertexts, carrying the wrapped data key:
+// MAGIC | len(Provider) (1 byte) | Provider | len(KeyID) (2 bytes) | KeyID | len(Wrapped) (2 bytes) | Wrapped
+type Envelope struct {
+	Provider string // name of the provider wrapped the data key
+	KeyID    string // identifier of the key encryption key
+	Wrapped  []byte // the wrapped data key
+}
+
+// Marshal serialize the envelope header.
+func (e *Envelope) Marshal() (dat []byte, err error) {
+	if len(e.Provider) > 0xff || len(e.KeyID) > 0xffff || len(e.Wrapped) > 0xffff {
+		return nil, fmt.Errorf("[KMS][ENV] envelope field too long")
+	}
+	var buf bytes.Buffer
+	buf.WriteString(MAGIC)
+	buf.WriteByte(byte(len(e.Provider)))
+	buf.WriteString(e.Provider)
+	buf.Write(binary.BigEndian.AppendUint16(nil, uint16(len(e.KeyID))))
+	buf.WriteString(e.KeyID)
+	buf.Write(binary.BigEndian.AppendUint16(nil, uint16(len(e.Wrapped))))
+	buf.Write(e.Wrapped)
+	return buf.Bytes(), nil
+}
+
+// ParseEnvelope parse the envelope header at the beginning of 'dat', returns the remaining data.
+func ParseEnvelope(dat []byte) (env *Envelope, rest []byte, err error) {
+	if !bytes.HasPrefix(dat, []byte(MAGIC)) {
+		return nil, nil, fmt.Errorf("[KMS][ENV] envelope header not found")
+	}
+	rest = dat[len(MAGIC):]
+
+	field := func(size int) (val []byte) {
+		if err != nil {
+			return
+		}
+		var l int
+		if len(rest) >= size {
+			if size == 1 {
+				l = int(rest[0])
+			} else {
+				l = int(binary.BigEndian.Uint16(rest))
+			}
+			rest = rest[size:]
+			if len(rest) >= l {
+				val, rest = rest[:l], rest[l:]
+				return
+			}
+		}
+		err = fmt.Errorf("[KMS][ENV] envelope header truncated")
+		return
+	}
+	prv, kid, wrp := field(1), field(2), field(2)
+	if err != nil {
+		return nil, nil, err
+	}
+	env = &Envelope{Provider: string(prv), KeyID: string(kid), Wrapped: wrp}
+	return
+}
+
+// Seal wrap the data key using 'prv', and return the serialized envelope header.
+func Seal(ctx context.Context, prv Provider, key []byte) (hdr []byte, err error) {
+	wrp, err := prv.WrapKey(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("[KMS][WRAP]%v", err)
+	}
+	env := &Envelope{Provider: prv.Name(), KeyID: prv.KeyID(), Wrapped: wrp}
+	return env.Marshal()
+}
+
+// Unseal parse the envelope header at the beginning of 'dat' and unwrap the data key using 'prv', returns the
+// data key and the remaining data.
+func Unseal(ctx context.Context, prv Provider, dat []byte) (key, rest []byte, err error) {
+	env, rest, err := ParseEnvelope(dat)
+	if err != nil {
+		return
+	}
+	if env.Provider != prv.Name() {
+		return nil, nil, fmt.Errorf("[KMS] data key wrapped by provider '%v', not '%v'", env.Provider, prv.Name())
+	}
+	if key, err = prv.UnwrapKey(ctx, env.KeyID, env.Wrapped); err != nil {
+		err = fmt.Errorf("[KMS][UNWRAP]%v", err)
+	}
+	return
+}
@@ -0,0 +1,136 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sea9.org/go/c9ryptool/pkg/encrypts/sym"
+)
+
+func TestEnvelope(t *testing.T) {
+	env := &Envelope{Provider: "file", KeyID: "file:0123456789abcdef", Wrapped: []byte("wrapped-key")}
+	hdr, err := env.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dat := append(hdr, []byte("ciphertext")...)
+
+	e, rest, err := ParseEnvelope(dat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Provider != env.Provider || e.KeyID != env.KeyID || !bytes.Equal(e.Wrapped, env.Wrapped) || string(rest) != "ciphertext" {
+		t.Fatalf("TestEnvelope() mismatched %v / '%s'", e, rest)
+	}
+	for _, l := range []int{0, 3, len(MAGIC) + 3, len(hdr) - 1} {
+		if _, _, err = ParseEnvelope(dat[:l]); err == nil {
+			t.Fatalf("TestEnvelope() expecting error parsing %v bytes", l)
+		}
+	}
+	fmt.Printf("TestEnvelope() test okay: %v\n", err)
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	k0, _ := sym.Generate(32)
+	k1, _ := sym.Generate(32)
+	p0, p1 := filepath.Join(dir, "kek0"), filepath.Join(dir, "kek1.txt")
+	os.WriteFile(p0, k0, 0600)
+	os.WriteFile(p1, []byte(base64.RawURLEncoding.EncodeToString(k1)+"\n"), 0600)
+
+	ctx := context.Background()
+	prv0, err := Open("file://" + p0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prv1, err := Open("file:" + p1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := sym.Generate(32)
+	hdr, err := Seal(ctx, prv0, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unw, rest, err := Unseal(ctx, prv0, append(hdr, 'x'))
+	if err != nil || !bytes.Equal(unw, key) || string(rest) != "x" {
+		t.Fatalf("TestFile() unseal failed: %v", err)
+	}
+	if _, _, err = Unseal(ctx, prv1, hdr); err == nil {
+		t.Fatal("TestFile() expecting error unwrapping with a different key encryption key")
+	}
+	fmt.Printf("TestFile() test okay: %v\n", err)
+}
+
+// transit stand-in of the Vault transit engine, "wrapping" the keys by reversing the bytes
+func transit(token string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		reverse := func(s string) string {
+			b := []byte(s)
+			for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+				b[i], b[j] = b[j], b[i]
+			}
+			return string(b)
+		}
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/my-key":
+			fmt.Fprintf(w, `{"data":{"ciphertext":"vault:v1:%v"}}`, reverse(req["plaintext"]))
+		case "/v1/transit/decrypt/my-key":
+			if !strings.HasPrefix(req["ciphertext"], "vault:v1:") {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(w, `{"data":{"plaintext":"%v"}}`, reverse(req["ciphertext"][9:]))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestVault(t *testing.T) {
+	srv := transit("s3cret")
+	defer srv.Close()
+	t.Setenv(VAULT_TOKEN, "s3cret")
+
+	ctx := context.Background()
+	prv, err := Open(strings.Replace(srv.URL, "http://", "vault+http://", 1) + "/transit/my-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prv.KeyID() != "transit/my-key" {
+		t.Fatalf("TestVault() unexpected key ID '%v'", prv.KeyID())
+	}
+
+	key, _ := sym.Generate(32)
+	hdr, err := Seal(ctx, prv, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unw, _, err := Unseal(ctx, prv, hdr)
+	if err != nil || !bytes.Equal(unw, key) {
+		t.Fatalf("TestVault() unseal failed: %v", err)
+	}
+
+	bad := NewVault(srv.URL, "transit", "my-key", "wrong", nil)
+	if _, err = bad.WrapKey(ctx, key); err == nil {
+		t.Fatal("TestVault() expecting error with wrong token")
+	}
+	fmt.Printf("TestVault() test okay: %v\n", err)
+}
@@ -0,0 +1,266 @@
+// Package server encryption as a service, exposing the encryption, hashing, encoding and signing functions as
+// JSON/HTTP endpoints, the keys are referenced by names from a keystore so they never leave the server.
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+
+	"sea9.org/go/c9ryptool/pkg/c9crypt"
+	"sea9.org/go/c9ryptool/pkg/encodes"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/hashes"
+	"sea9.org/go/c9ryptool/pkg/keystore"
+)
+
+// MAX_REQUEST default maximum size of a request body in bytes
+const MAX_REQUEST = 1 << 20
+
+// Request request body of the endpoints, binary fields are base64 encoded in JSON
+type Request struct {
+	Key       string `json:"key,omitempty"`       // name of the key in the keystore
+	Algorithm string `json:"algorithm,omitempty"` // hashing algorithm of '/v1/hash' and '/v1/mac'
+	Encoding  string `json:"encoding,omitempty"`  // encoding scheme of '/v1/encode' and '/v1/decode'
+	Data      []byte `json:"data,omitempty"`      // input data
+	Text      string `json:"text,omitempty"`      // encoded input of '/v1/decode'
+	AAD       []byte `json:"aad,omitempty"`       // additional authenticated data of '/v1/encrypt' and '/v1/decrypt'
+	Signature []byte `json:"signature,omitempty"` // signature to verify by '/v1/verify'
+}
+
+// Response response body of the endpoints
+type Response struct {
+	Error string `json:"error,omitempty"`
+	Data  []byte `json:"data,omitempty"`
+	Text  string `json:"text,omitempty"`  // encoded output of '/v1/encode', hex encoded output of '/v1/hash'
+	Valid *bool  `json:"valid,omitempty"` // result of '/v1/verify'
+}
+
+// statusError error with the HTTP status code to respond
+type statusError struct {
+	code int
+	err  error
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+// Server the HTTP handler of the endpoints
+type Server struct {
+	keys    *keystore.Keystore
+	maxSize int64
+	mux     *http.ServeMux
+}
+
+// New create a new server using keys from the given keystore, request bodies larger than 'maxSize' bytes are
+// rejected, MAX_REQUEST is used if 'maxSize' is not positive.
+func New(keys *keystore.Keystore, maxSize int64) *Server {
+	if maxSize <= 0 {
+		maxSize = MAX_REQUEST
+	}
+	s := &Server{keys: keys, maxSize: maxSize, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"status":"ok"}`)
+	})
+	s.mux.Handle("POST /v1/encrypt", s.handle(s.encrypt))
+	s.mux.Handle("POST /v1/decrypt", s.handle(s.decrypt))
+	s.mux.Handle("POST /v1/hash", s.handle(s.hash))
+	s.mux.Handle("POST /v1/encode", s.handle(s.encode))
+	s.mux.Handle("POST /v1/decode", s.handle(s.decode))
+	s.mux.Handle("POST /v1/sign", s.handle(s.sign))
+	s.mux.Handle("POST /v1/verify", s.handle(s.verify))
+	s.mux.Handle("POST /v1/mac", s.handle(s.mac))
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handle decode the request, and encode the response or the error
+func (s *Server) handle(fn func(context.Context, *Request) (*Response, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		var rsp *Response
+		var err error
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.maxSize))
+		dec.DisallowUnknownFields()
+		if err = dec.Decode(&req); err != nil {
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				err = &statusError{http.StatusRequestEntityTooLarge, fmt.Errorf("[SERV] request larger than %v bytes", mbe.Limit)}
+			} else {
+				err = fmt.Errorf("[SERV] invalid request: %v", err)
+			}
+		} else {
+			rsp, err = fn(r.Context(), &req)
+		}
+
+		code := http.StatusOK
+		if err != nil {
+			code = http.StatusBadRequest
+			var se *statusError
+			if errors.As(err, &se) {
+				code = se.code
+			}
+			rsp = &Response{Error: err.Error()}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(rsp)
+	})
+}
+
+// key get the named key from the keystore
+func (s *Server) key(name string) (*keystore.Key, error) {
+	if name == "" {
+		return nil, fmt.Errorf("[SERV] key name missing")
+	}
+	if s.keys == nil {
+		return nil, &statusError{http.StatusNotFound, fmt.Errorf("[SERV] keystore not available")}
+	}
+	k, err := s.keys.Get(name)
+	if err != nil {
+		return nil, &statusError{http.StatusNotFound, fmt.Errorf("[SERV]%v", err)}
+	}
+	return k, nil
+}
+
+// encrypt the output is the same as the 'encrypt' command
+func (s *Server) encrypt(ctx context.Context, req *Request) (*Response, error) {
+	k, err := s.key(req.Key)
+	if err != nil {
+		return nil, err
+	} else if !k.Active() {
+		return nil, fmt.Errorf("[SERV] key '%v' is %v, decryption only", k.Name, k.State)
+	}
+	var out bytes.Buffer
+	err = c9crypt.Encrypt(ctx, bytes.NewReader(req.Data), &out, c9crypt.Options{Algorithm: k.Algorithm, Key: k.Key, AAD: req.AAD})
+	if err != nil {
+		return nil, fmt.Errorf("[SERV]%v", err)
+	}
+	return &Response{Data: out.Bytes()}, nil
+}
+
+func (s *Server) decrypt(ctx context.Context, req *Request) (*Response, error) {
+	k, err := s.key(req.Key)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	err = c9crypt.Decrypt(ctx, bytes.NewReader(req.Data), &out, c9crypt.Options{Algorithm: k.Algorithm, Key: k.Key, AAD: req.AAD})
+	if err != nil {
+		return nil, fmt.Errorf("[SERV]%v", err)
+	}
+	return &Response{Data: out.Bytes()}, nil
+}
+
+// hash the digest is returned both in raw and in hex, the same as the 'hash' command
+func (s *Server) hash(_ context.Context, req *Request) (*Response, error) {
+	if req.Algorithm == "" {
+		req.Algorithm = hashes.Default()
+	}
+	algr, err := hashes.Resolve(req.Algorithm)
+	if err != nil {
+		return nil, fmt.Errorf("[SERV]%v", err)
+	}
+	h := hashes.Get(algr)
+	h.Write(req.Data)
+	dgt := h.Sum(nil)
+	return &Response{Data: dgt, Text: hex.EncodeToString(dgt)}, nil
+}
+
+func (s *Server) encode(_ context.Context, req *Request) (*Response, error) {
+	enc, err := s.encoding(req.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Text: enc.EncodeToString(req.Data)}, nil
+}
+
+func (s *Server) decode(_ context.Context, req *Request) (*Response, error) {
+	enc, err := s.encoding(req.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	dat, err := enc.DecodeString(req.Text)
+	if err != nil {
+		return nil, fmt.Errorf("[SERV][ENCD] %v", err)
+	}
+	return &Response{Data: dat}, nil
+}
+
+func (s *Server) encoding(name string) (encodes.Encoding, error) {
+	if name == "" {
+		name = encodes.Default()
+	}
+	scheme, err := encodes.Resolve(name)
+	if err != nil {
+		return nil, fmt.Errorf("[SERV]%v", err)
+	}
+	return encodes.Get(scheme), nil
+}
+
+// sign sign using the private key of an asymmetric key, see encrypts.Sign()
+func (s *Server) sign(_ context.Context, req *Request) (*Response, error) {
+	alg, err := s.asymmetric(req.Key)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := encrypts.Sign(alg, req.Data)
+	if err != nil {
+		return nil, fmt.Errorf("[SERV]%v", err)
+	}
+	return &Response{Data: sig}, nil
+}
+
+func (s *Server) verify(_ context.Context, req *Request) (*Response, error) {
+	alg, err := s.asymmetric(req.Key)
+	if err != nil {
+		return nil, err
+	}
+	valid := encrypts.Verify(alg, req.Data, req.Signature) == nil
+	return &Response{Valid: &valid}, nil
+}
+
+func (s *Server) asymmetric(name string) (encrypts.Algorithm, error) {
+	k, err := s.key(name)
+	if err != nil {
+		return nil, err
+	}
+	alg, err := k.New()
+	if err != nil {
+		return nil, fmt.Errorf("[SERV]%v", err)
+	} else if alg.Type() {
+		return nil, fmt.Errorf("[SERV] signing requires an asymmetric key, use '/v1/mac' for symmetric key '%v'", name)
+	}
+	return alg, nil
+}
+
+// mac HMAC using a symmetric key, the hashing algorithm defaults to hashes.Default()
+func (s *Server) mac(_ context.Context, req *Request) (*Response, error) {
+	k, err := s.key(req.Key)
+	if err != nil {
+		return nil, err
+	} else if alg := encrypts.Get(k.Algorithm); alg == nil || !alg.Type() {
+		return nil, fmt.Errorf("[SERV] MAC requires a symmetric key, use '/v1/sign' for asymmetric key '%v'", k.Name)
+	}
+	if req.Algorithm == "" {
+		req.Algorithm = hashes.Default()
+	}
+	algr, err := hashes.Resolve(req.Algorithm)
+	if err != nil {
+		return nil, fmt.Errorf("[SERV]%v", err)
+	}
+	m := hmac.New(func() hash.Hash { return hashes.Get(algr) }, k.Key)
+	m.Write(req.Data)
+	return &Response{Data: m.Sum(nil)}, nil
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"sea9.org/go/c9ryptool/pkg/c9crypt"
+	"sea9.org/go/c9ryptool/pkg/encrypts"
+	"sea9.org/go/c9ryptool/pkg/encrypts/sym"
+	"sea9.org/go/c9ryptool/pkg/keystore"
+)
+
+func call(t *testing.T, url string, req any) (code int, rsp Response) {
+	buf, _ := json.Marshal(req)
+	r, err := http.Post(url, "application/json", bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+	if err = json.NewDecoder(r.Body).Decode(&rsp); err != nil {
+		t.Fatal(err)
+	}
+	return r.StatusCode, rsp
+}
+
+func TestServer(t *testing.T) {
+	sym0, _ := sym.Generate(32)
+	asym0 := encrypts.New("ECIES-SECP256K1-DECRED")
+	asym0.PopulateKey(nil)
+	keys, err := keystore.Create(filepath.Join(t.TempDir(), "keys.c9s"), keystore.Lock{Password: "abcd1234"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = keys.Add(&keystore.Key{Name: "sym0", Algorithm: "AES-256-GCM", Key: sym0}); err != nil {
+		t.Fatal(err)
+	}
+	if err = keys.Add(&keystore.Key{Name: "asym0", Algorithm: "decred", Key: asym0.GetKey()}); err != nil {
+		t.Fatal(err)
+	}
+
+	svr := httptest.NewServer(New(keys, 1024))
+	defer svr.Close()
+	plain := []byte("HelloHowAreYou?I'mFineThankYouVeryMuch!")
+
+	r, err := http.Get(svr.URL + "/healthz")
+	if err != nil || r.StatusCode != http.StatusOK {
+		t.Fatalf("TestServer() healthz failed: %v", err)
+	}
+
+	// outputs compatible with c9crypt, i.e. the 'encrypt' / 'decrypt' commands
+	code, rsp := call(t, svr.URL+"/v1/encrypt", &Request{Key: "sym0", Data: plain, AAD: []byte("hdr")})
+	if code != http.StatusOK {
+		t.Fatalf("TestServer() encrypt failed: %v", rsp.Error)
+	}
+	var out bytes.Buffer
+	err = c9crypt.Decrypt(context.Background(), bytes.NewReader(rsp.Data), &out, c9crypt.Options{Algorithm: "AES-256-GCM", Key: sym0, AAD: []byte("hdr")})
+	if err != nil || !bytes.Equal(out.Bytes(), plain) {
+		t.Fatalf("TestServer() decrypt with c9crypt failed: %v", err)
+	}
+	code, rsp = call(t, svr.URL+"/v1/decrypt", &Request{Key: "sym0", Data: rsp.Data, AAD: []byte("hdr")})
+	if code != http.StatusOK || !bytes.Equal(rsp.Data, plain) {
+		t.Fatalf("TestServer() decrypt failed: %v", rsp.Error)
+	}
+
+	code, rsp = call(t, svr.URL+"/v1/hash", &Request{Algorithm: "sha-256", Data: []byte("abc")})
+	if code != http.StatusOK || rsp.Text != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Fatalf("TestServer() hash failed: %v %v", rsp.Text, rsp.Error)
+	}
+	code, rsp = call(t, svr.URL+"/v1/encode", &Request{Encoding: "hex", Data: []byte("abc")})
+	if code != http.StatusOK || rsp.Text != "616263" {
+		t.Fatalf("TestServer() encode failed: %v %v", rsp.Text, rsp.Error)
+	}
+	code, rsp = call(t, svr.URL+"/v1/decode", &Request{Encoding: "hex", Text: "616263"})
+	if code != http.StatusOK || string(rsp.Data) != "abc" {
+		t.Fatalf("TestServer() decode failed: %v", rsp.Error)
+	}
+
+	code, rsp = call(t, svr.URL+"/v1/sign", &Request{Key: "asym0", Data: plain})
+	if code != http.StatusOK {
+		t.Fatalf("TestServer() sign failed: %v", rsp.Error)
+	}
+	code, rsp = call(t, svr.URL+"/v1/verify", &Request{Key: "asym0", Data: plain, Signature: rsp.Data})
+	if code != http.StatusOK || rsp.Valid == nil || !*rsp.Valid {
+		t.Fatalf("TestServer() verify failed: %v", rsp.Error)
+	}
+	code, rsp = call(t, svr.URL+"/v1/mac", &Request{Key: "sym0", Data: plain})
+	if code != http.StatusOK || len(rsp.Data) != 32 {
+		t.Fatalf("TestServer() mac failed: %v", rsp.Error)
+	}
+
+	// errors
+	if code, rsp = call(t, svr.URL+"/v1/encrypt", &Request{Key: "sym1", Data: plain}); code != http.StatusNotFound {
+		t.Fatalf("TestServer() expecting 404 for missing key, got %v", code)
+	}
+	if code, rsp = call(t, svr.URL+"/v1/mac", &Request{Key: "asym0", Data: plain}); code != http.StatusBadRequest {
+		t.Fatalf("TestServer() expecting 400 for MAC with asymmetric key, got %v", code)
+	}
+	if code, rsp = call(t, svr.URL+"/v1/hash", &Request{Data: make([]byte, 1024)}); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("TestServer() expecting 413 for large request, got %v", code)
+	}
+	fmt.Printf("TestServer() test okay: %v\n", rsp.Error)
+}
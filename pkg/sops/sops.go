@@ -0,0 +1,414 @@
+// Package sops read and write documents in the encrypted format of SOPS (https://github.com/getsops/sops). Each value
+// is encrypted by AES-256-GCM using a data key, with the key path as the additional authenticated data, while the
+// data key is wrapped by the key groups kept in the 'sops' metadata, along with the MAC of all the values.
+package sops
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// METADATA key of the metadata in the top level map
+const METADATA = "sops"
+
+// VERSION SOPS version written to the metadata
+const VERSION = "3.9.0"
+
+// UNENCRYPTED_SUFFIX default suffix of the keys whose values are not encrypted
+const UNENCRYPTED_SUFFIX = "_unencrypted"
+
+// KEY_SIZE size of the data keys
+const KEY_SIZE = 32
+
+const iV_SIZE = 32
+const tAG_SIZE = 16
+
+var eNCRYPTED = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+// Metadata the 'sops' metadata. Key groups other than 'hc_vault' are kept only to report which keys the data key
+// is wrapped by.
+type Metadata struct {
+	KMS               []map[string]interface{} `yaml:"kms,omitempty"`
+	GcpKMS            []map[string]interface{} `yaml:"gcp_kms,omitempty"`
+	AzureKV           []map[string]interface{} `yaml:"azure_kv,omitempty"`
+	HcVault           []VaultKey               `yaml:"hc_vault,omitempty"`
+	Age               []map[string]interface{} `yaml:"age,omitempty"`
+	PGP               []map[string]interface{} `yaml:"pgp,omitempty"`
+	C9ryptool         []Key                    `yaml:"c9ryptool,omitempty"` // ignored by SOPS
+	LastModified      string                   `yaml:"lastmodified"`
+	Mac               string                   `yaml:"mac"`
+	UnencryptedSuffix string                   `yaml:"unencrypted_suffix,omitempty"`
+	EncryptedSuffix   string                   `yaml:"encrypted_suffix,omitempty"`
+	UnencryptedRegex  string                   `yaml:"unencrypted_regex,omitempty"`
+	EncryptedRegex    string                   `yaml:"encrypted_regex,omitempty"`
+	MacOnlyEncrypted  bool                     `yaml:"mac_only_encrypted,omitempty"`
+	Version           string                   `yaml:"version"`
+}
+
+// VaultKey data key wrapped by a Vault transit engine, readable by SOPS
+type VaultKey struct {
+	VaultAddress string `yaml:"vault_address"`
+	EnginePath   string `yaml:"engine_path"`
+	KeyName      string `yaml:"key_name"`
+	CreatedAt    string `yaml:"created_at"`
+	Enc          string `yaml:"enc"`
+}
+
+// Key data key wrapped by an encryption key or password of c9ryptool, or by a KMS provider other than Vault
+type Key struct {
+	Algorithm string `yaml:"algorithm,omitempty"` // algorithm of the encryption key
+	Salt      string `yaml:"salt,omitempty"`      // salt of the key generated from a password, base64 encoded
+	Kms       string `yaml:"kms,omitempty"`       // name of the KMS provider
+	KeyID     string `yaml:"key_id,omitempty"`    // identifier of the key encryption key of the KMS provider
+	CreatedAt string `yaml:"created_at"`
+	Enc       string `yaml:"enc"` // the wrapped data key, base64 encoded
+}
+
+// Groups names of the key groups the data key is wrapped by, c9ryptool keys are named by their algorithms or KMS.
+func (m *Metadata) Groups() (list []string) {
+	list = make([]string, 0)
+	for _, g := range []struct {
+		name string
+		size int
+	}{
+		{"kms", len(m.KMS)}, {"gcp_kms", len(m.GcpKMS)}, {"azure_kv", len(m.AzureKV)}, {"hc_vault", len(m.HcVault)},
+		{"age", len(m.Age)}, {"pgp", len(m.PGP)},
+	} {
+		if g.size > 0 {
+			list = append(list, g.name)
+		}
+	}
+	for _, k := range m.C9ryptool {
+		if k.Kms != "" {
+			list = append(list, "c9ryptool:"+k.Kms)
+		} else {
+			list = append(list, "c9ryptool:"+k.Algorithm)
+		}
+	}
+	return
+}
+
+// Selector the selector of the values to encrypt, by the key paths, following the suffixes and regular
+// expressions in the metadata as SOPS does.
+func (m *Metadata) Selector() (sel func([]string) bool, err error) {
+	var urgx, ergx *regexp.Regexp
+	if m.UnencryptedRegex != "" {
+		if urgx, err = regexp.Compile(m.UnencryptedRegex); err != nil {
+			return nil, fmt.Errorf("[SOPS] invalid unencrypted_regex '%v'", m.UnencryptedRegex)
+		}
+	}
+	if m.EncryptedRegex != "" {
+		if ergx, err = regexp.Compile(m.EncryptedRegex); err != nil {
+			return nil, fmt.Errorf("[SOPS] invalid encrypted_regex '%v'", m.EncryptedRegex)
+		}
+	}
+	anyKey := func(path []string, match func(string) bool) bool {
+		for _, k := range path {
+			if match(k) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(path []string) bool {
+		encrypted := true
+		if m.UnencryptedSuffix != "" && anyKey(path, func(k string) bool { return strings.HasSuffix(k, m.UnencryptedSuffix) }) {
+			encrypted = false
+		}
+		if m.EncryptedSuffix != "" {
+			encrypted = anyKey(path, func(k string) bool { return strings.HasSuffix(k, m.EncryptedSuffix) })
+		}
+		if urgx != nil && anyKey(path, urgx.MatchString) {
+			encrypted = false
+		}
+		if ergx != nil {
+			encrypted = anyKey(path, ergx.MatchString)
+		}
+		return encrypted
+	}, nil
+}
+
+// AAD the additional authenticated data of the value of the given key path, e.g. 'db:password:'.
+func AAD(path []string) string {
+	return strings.Join(path, ":") + ":"
+}
+
+// IsEncrypted check if the value is encrypted in the SOPS format.
+func IsEncrypted(val string) bool {
+	return eNCRYPTED.MatchString(val)
+}
+
+// NewKey generate a new data key.
+func NewKey() (key []byte, err error) {
+	key = make([]byte, KEY_SIZE)
+	if _, err = rand.Read(key); err != nil {
+		return nil, fmt.Errorf("[SOPS][KEY]%v", err)
+	}
+	return
+}
+
+// Encrypt encrypt a value of type string, int, float64 or bool, into 'ENC[AES256_GCM,data:...,iv:...,tag:...,type:...]'.
+// Empty strings are not encrypted.
+func Encrypt(key []byte, val interface{}, aad string) (enc string, err error) {
+	var txt, typ string
+	switch v := val.(type) {
+	case string:
+		if v == "" {
+			return "", nil
+		}
+		txt, typ = v, "str"
+	case int:
+		txt, typ = strconv.Itoa(v), "int"
+	case float64:
+		txt, typ = strconv.FormatFloat(v, 'f', -1, 64), "float"
+	case bool:
+		txt, typ = strconv.FormatBool(v), "bool"
+	default:
+		return "", fmt.Errorf("[SOPS] unsupported value of type %T", val)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return
+	}
+	iv := make([]byte, iV_SIZE)
+	if _, err = rand.Read(iv); err != nil {
+		return "", fmt.Errorf("[SOPS][IV]%v", err)
+	}
+	out := gcm.Seal(nil, iv, []byte(txt), []byte(aad))
+	n := len(out) - tAG_SIZE
+	return fmt.Sprintf(
+		"ENC[AES256_GCM,data:%v,iv:%v,tag:%v,type:%v]",
+		base64.StdEncoding.EncodeToString(out[:n]),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(out[n:]),
+		typ,
+	), nil
+}
+
+// Decrypt decrypt a value encrypted in the SOPS format, restored as the original type.
+func Decrypt(key []byte, enc string, aad string) (val interface{}, err error) {
+	if enc == "" {
+		return "", nil
+	}
+	mth := eNCRYPTED.FindStringSubmatch(enc)
+	if mth == nil {
+		return nil, fmt.Errorf("[SOPS] invalid encrypted value")
+	}
+	var dat [3][]byte
+	for i := range dat {
+		if dat[i], err = base64.StdEncoding.DecodeString(mth[i+1]); err != nil {
+			return nil, fmt.Errorf("[SOPS] invalid encrypted value: %v", err)
+		}
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return
+	}
+	if len(dat[1]) != iV_SIZE {
+		return nil, fmt.Errorf("[SOPS] invalid IV size %v", len(dat[1]))
+	}
+	txt, err := gcm.Open(nil, dat[1], append(dat[0], dat[2]...), []byte(aad))
+	if err != nil {
+		return nil, fmt.Errorf("[SOPS] %v", err)
+	}
+
+	str := string(txt)
+	switch mth[4] {
+	case "str", "comment":
+		return str, nil
+	case "int":
+		val, err = strconv.Atoi(str)
+	case "float":
+		val, err = strconv.ParseFloat(str, 64)
+	case "bool":
+		val, err = strconv.ParseBool(str)
+	default:
+		return nil, fmt.Errorf("[SOPS] unsupported type '%v'", mth[4])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("[SOPS] invalid %v value", mth[4])
+	}
+	return
+}
+
+func newGCM(key []byte) (gcm cipher.AEAD, err error) {
+	blk, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("[SOPS] %v", err)
+	}
+	if gcm, err = cipher.NewGCMWithNonceSize(blk, iV_SIZE); err != nil {
+		err = fmt.Errorf("[SOPS] %v", err)
+	}
+	return
+}
+
+// Mac message authentication code of all the values of a document, in the order of the document.
+type Mac struct {
+	hash hash.Hash
+}
+
+func NewMac() *Mac {
+	return &Mac{hash: sha512.New()}
+}
+
+// Add add a plaintext value of type string, int, float64 or bool to the MAC.
+func (m *Mac) Add(val interface{}) (err error) {
+	var txt string
+	switch v := val.(type) {
+	case string:
+		txt = v
+	case int:
+		txt = strconv.Itoa(v)
+	case float64:
+		txt = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		txt = "False"
+		if v {
+			txt = "True"
+		}
+	default:
+		return fmt.Errorf("[SOPS][MAC] unsupported value of type %T", val)
+	}
+	m.hash.Write([]byte(txt))
+	return
+}
+
+// Sum the MAC as upper case hex string.
+func (m *Mac) Sum() string {
+	return fmt.Sprintf("%X", m.hash.Sum(nil))
+}
+
+// Value the value of a scalar node as string, int, float64, bool, or nil for nulls. Timestamps are strings as
+// in SOPS.
+func Value(node *yaml.Node) (val interface{}, err error) {
+	if err = node.Decode(&val); err != nil {
+		return nil, fmt.Errorf("[SOPS] %v", err)
+	}
+	switch val.(type) {
+	case nil, string, int, float64, bool:
+		return
+	}
+	return node.Value, nil
+}
+
+// SetValue set a scalar node to the given value.
+func SetValue(node *yaml.Node, val interface{}) {
+	node.Style &^= yaml.TaggedStyle | yaml.SingleQuotedStyle | yaml.DoubleQuotedStyle
+	switch v := val.(type) {
+	case string:
+		node.Tag, node.Value = "!!str", v
+	case int:
+		node.Tag, node.Value = "!!int", strconv.Itoa(v)
+	case float64:
+		node.Tag, node.Value = "!!float", strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		node.Tag, node.Value = "!!bool", strconv.FormatBool(v)
+	case nil:
+		node.Tag, node.Value = "!!null", "null"
+	}
+}
+
+// Walk walk the scalar values of a node in the order of the document, with the key paths as SOPS does, i.e. the
+// items of lists have the same key path as the lists. Aliases are expected to be expanded by Expand() already.
+func Walk(node *yaml.Node, action func([]string, *yaml.Node) error) error {
+	return walk(nil, node, action)
+}
+
+func walk(path []string, node *yaml.Node, action func([]string, *yaml.Node) error) (err error) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, n := range node.Content {
+			if err = walk(path, n, action); err != nil {
+				return
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if err = walk(append(path[:len(path):len(path)], key), node.Content[i+1], action); err != nil {
+				return fmt.Errorf("[%v]%v", key, err)
+			}
+		}
+	case yaml.ScalarNode:
+		err = action(path, node)
+	case yaml.AliasNode:
+		err = fmt.Errorf("[SOPS] unexpected alias '%v'", node.Value)
+	}
+	return
+}
+
+// Expand replace the aliases in the node with copies of the anchored nodes, and remove the anchors, since SOPS
+// expands the aliases and the copies are encrypted with their own key paths.
+func Expand(node *yaml.Node) {
+	for i, n := range node.Content {
+		if n.Kind == yaml.AliasNode {
+			node.Content[i] = clone(n.Alias)
+		}
+		Expand(node.Content[i])
+	}
+	node.Anchor = ""
+}
+
+func clone(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.AliasNode {
+		return clone(node.Alias)
+	}
+	cpy := *node
+	cpy.Anchor = ""
+	cpy.Content = make([]*yaml.Node, len(node.Content))
+	for i, n := range node.Content {
+		cpy.Content[i] = clone(n)
+	}
+	return &cpy
+}
+
+// DecryptComments decrypt the comments encrypted by SOPS, e.g. '#ENC[AES256_GCM,data:...,type:comment]', comments
+// that fail to decrypt are kept as is, the same as SOPS does.
+func DecryptComments(key []byte, node *yaml.Node) {
+	decryptComments(nil, key, node)
+}
+
+func decryptComments(path []string, key []byte, node *yaml.Node) {
+	aad := AAD(path)
+	for _, c := range []*string{&node.HeadComment, &node.LineComment, &node.FootComment} {
+		if *c == "" {
+			continue
+		}
+		lines := strings.Split(*c, "\n")
+		for i, l := range lines {
+			enc, ok := strings.CutPrefix(strings.TrimSpace(l), "#")
+			if !ok || !IsEncrypted(enc) {
+				continue
+			}
+			if val, err := Decrypt(key, enc, aad); err == nil {
+				lines[i] = fmt.Sprintf("#%v", val)
+			}
+		}
+		*c = strings.Join(lines, "\n")
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			decryptComments(path, key, node.Content[i])
+			decryptComments(append(path[:len(path):len(path)], node.Content[i].Value), key, node.Content[i+1])
+		}
+	default:
+		for _, n := range node.Content {
+			decryptComments(path, key, n)
+		}
+	}
+}
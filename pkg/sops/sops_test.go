@@ -0,0 +1,124 @@
+package sops
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestValue(t *testing.T) {
+	key, err := NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, val := range []interface{}{"Hello, World!", 5432, 1.5, true, false} {
+		enc, err := Encrypt(key, val, AAD([]string{"db", "password"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsEncrypted(enc) {
+			t.Fatalf("TestValue() '%v' not encrypted: '%v'", val, enc)
+		}
+		dec, err := Decrypt(key, enc, "db:password:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dec != val {
+			t.Fatalf("TestValue() expecting %v (%T), got %v (%T)", val, val, dec, dec)
+		}
+		if _, err = Decrypt(key, enc, "db:user:"); err == nil {
+			t.Fatalf("TestValue() expecting error decrypting '%v' with another key path", val)
+		}
+	}
+
+	if enc, err := Encrypt(key, "", "db:"); err != nil || enc != "" {
+		t.Fatalf("TestValue() empty strings are not encrypted: '%v' %v", enc, err)
+	}
+	if _, err = Decrypt(key, "ENC[AES256_GCM,data:x]", "db:"); err == nil {
+		t.Fatalf("TestValue() expecting error decrypting invalid value")
+	}
+	fmt.Println("TestValue() test okay")
+}
+
+func TestMac(t *testing.T) {
+	m1, m2 := NewMac(), NewMac()
+	for _, v := range []interface{}{"admin", 5432, 1.5, true} {
+		if err := m1.Add(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, v := range []string{"admin", "5432", "1.5", "True"} {
+		m2.Add(v)
+	}
+	if m1.Sum() != m2.Sum() || m1.Sum() != strings.ToUpper(m1.Sum()) || len(m1.Sum()) != 128 {
+		t.Fatalf("TestMac() mismatched '%v' / '%v'", m1.Sum(), m2.Sum())
+	}
+	if err := m1.Add([]string{"x"}); err == nil {
+		t.Fatalf("TestMac() expecting error adding unsupported value")
+	}
+	fmt.Println("TestMac() test okay")
+}
+
+func TestSelector(t *testing.T) {
+	for _, c := range []struct {
+		meta Metadata
+		path []string
+		exp  bool
+	}{
+		{Metadata{UnencryptedSuffix: UNENCRYPTED_SUFFIX}, []string{"db", "password"}, true},
+		{Metadata{UnencryptedSuffix: UNENCRYPTED_SUFFIX}, []string{"db_unencrypted", "password"}, false},
+		{Metadata{EncryptedSuffix: "_secret"}, []string{"db", "password"}, false},
+		{Metadata{EncryptedSuffix: "_secret"}, []string{"db", "password_secret"}, true},
+		{Metadata{EncryptedRegex: "^pass"}, []string{"db", "password"}, true},
+		{Metadata{EncryptedRegex: "^pass"}, []string{"db", "user"}, false},
+		{Metadata{UnencryptedRegex: "^us"}, []string{"db", "user"}, false},
+	} {
+		sel, err := c.meta.Selector()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sel(c.path) != c.exp {
+			t.Fatalf("TestSelector() %v expecting %v", c.path, c.exp)
+		}
+	}
+	if _, err := (&Metadata{EncryptedRegex: "("}).Selector(); err == nil {
+		t.Fatalf("TestSelector() expecting error of invalid regex")
+	}
+	fmt.Println("TestSelector() test okay")
+}
+
+func TestWalk(t *testing.T) {
+	doc := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte("base: &b\n  x: 1\nref: *b\nhosts:\n  - a\n  - [b, c]\nnothing: null\n"), doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := Walk(doc, func([]string, *yaml.Node) error { return nil }); err == nil {
+		t.Fatalf("TestWalk() expecting error of unexpanded alias")
+	}
+
+	Expand(doc)
+	paths, vals := make([]string, 0), make([]interface{}, 0)
+	err := Walk(doc, func(path []string, n *yaml.Node) error {
+		val, err := Value(n)
+		paths, vals = append(paths, strings.Join(path, ".")), append(vals, val)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "[base.x ref.x hosts hosts hosts nothing] [1 1 a b c <nil>]"
+	if str := fmt.Sprintf("%v %v", paths, vals); str != exp {
+		t.Fatalf("TestWalk() expecting '%v', got '%v'", exp, str)
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "&b") || strings.Contains(string(out), "*b") {
+		t.Fatalf("TestWalk() aliases not expanded:\n%s", out)
+	}
+	fmt.Println("TestWalk() test okay")
+}
@@ -3,9 +3,11 @@ package utils
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 )
 
 type Encoder interface {
@@ -120,6 +122,80 @@ func pipedDecode(in io.Reader, out io.Writer, decoders ...Decoder) (err error) {
 	return
 }
 
+// modes of the end-of-input sentinel when reading from stdin
+const (
+	SENTINEL_AUTO = iota // apply the sentinel only if stdin is a terminal
+	SENTINEL_ON          // always apply the sentinel
+	SENTINEL_OFF         // never apply the sentinel, read stdin until EOF
+)
+
+var sENTINEL = SENTINEL_AUTO
+
+// SetSentinel set the mode of the end-of-input sentinel when reading from stdin
+func SetSentinel(mode int) {
+	sENTINEL = mode
+}
+
+// IsTerminal check if the given file is a terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Stdin return a reader of stdin. If getting input from stdin interactively, pressing <enter> would signify the
+// end of an input line, and an entire line with a single period ('.') means the end of input. Piped input is
+// read until EOF.
+func Stdin() io.Reader {
+	if sENTINEL == SENTINEL_ON || (sENTINEL == SENTINEL_AUTO && IsTerminal(os.Stdin)) {
+		return &sentinelReader{
+			rdr: bufio.NewReader(os.Stdin),
+		}
+	}
+	return os.Stdin
+}
+
+// sentinelReader read line by line, until a line with a single period ('.') is encountered
+type sentinelReader struct {
+	rdr  *bufio.Reader
+	line []byte // unread part of the current line
+	cont bool   // 'true' if the current line is longer than the read buffer
+	done bool
+}
+
+func (s *sentinelReader) Read(p []byte) (n int, err error) {
+	if len(s.line) <= 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		var line []byte
+		line, err = s.rdr.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			err = nil
+		} else if err == io.EOF {
+			s.done = true
+			err = nil
+		} else if err != nil {
+			return
+		}
+		// ASCII code 10: line feed (LF)
+		// ASCII code 13: carriage return (CR)
+		// ASCII code 46: period ('.')
+		if !s.cont && (bytes.Equal(line, []byte{46, 10}) || bytes.Equal(line, []byte{46, 13, 10})) {
+			s.done = true
+			return 0, io.EOF
+		}
+		s.cont = len(line) > 0 && line[len(line)-1] != 10
+		s.line = line
+		if len(s.line) <= 0 {
+			return 0, io.EOF
+		}
+	}
+	n = copy(p, s.line)
+	s.line = s.line[n:]
+	return
+}
+
+// BufferedRead read everything from 'rdr' until EOF, calling 'action' for each chunk read
 func BufferedRead(
 	rdr *bufio.Reader,
 	size int,
@@ -135,18 +211,6 @@ func BufferedRead(
 		// it is because the returned error could have been EOF
 		cnt, err = rdr.Read(buf[:cap(buf)])
 
-		// If getting input from stdin interactively, pressing <enter> would signify the end of an input line.
-		// An entire line with a signle period ('.') means the end of input.
-		if (cnt == 2 && buf[:cnt][1] == 10) || (cnt == 3 && (buf[:cnt][1] == 13 && buf[:cnt][2] == 10 || buf[:cnt][1] == 10 && buf[:cnt][2] == 13)) {
-			// ASCII code 10: line feed (LF)
-			// ASCII code 13: carriage return (CR)
-			// ASCII code 46: period ('.')
-			if buf[:cnt][0] == 46 {
-				cnt = 0
-				err = io.EOF
-			}
-		}
-
 		if cnt > 0 {
 			err = action(cnt, buf[:cnt])
 			if err != nil {
@@ -169,14 +233,16 @@ func Read(
 	dat []byte,
 	err error,
 ) {
-	inp := os.Stdin
+	inp := Stdin()
 	if path != "" {
-		inp, err = os.Open(path)
+		var f *os.File
+		f, err = os.Open(path)
 		if err != nil {
 			err = fmt.Errorf("[READ] %v", err)
 			return
 		}
-		defer inp.Close()
+		defer f.Close()
+		inp = f
 	}
 	rdr := bufio.NewReaderSize(inp, buffer)
 
@@ -220,18 +286,135 @@ func Write(
 	dat []byte,
 	encr ...Encoder,
 ) (err error) {
-	var out *os.File
-	var wtr *bufio.Writer
+	out := os.Stdout
 	if path != "" {
 		out, err = os.Create(path)
 		if err != nil {
 			err = fmt.Errorf("[WRITE] %v", err)
 			return
 		}
-		wtr = bufio.NewWriter(out)
 		defer out.Close()
 	}
+	err = write(bufio.NewWriter(out), dat, encr)
+	return
+}
+
+// Replace atomically replace the content of the existing file 'path' with 'dat'. The output is written to a
+// temporary file in the same directory, synced to disk, then renamed over the original file, keeping the file
+// mode of the original. The original file is kept as 'backup' unless it is empty.
+func Replace(
+	path, backup string,
+	dat []byte,
+	encr ...Encoder,
+) (err error) {
+	stg, err := Stage(path, dat, encr...)
+	if err != nil {
+		return
+	}
+	return stg.Commit(backup)
+}
+
+// Staged the replacement of a file written by Stage(), to be committed or discarded, so that a batch of files can
+// be replaced only after all of them are written successfully
+type Staged struct {
+	path string // the file to replace, symbolic links resolved
+	tmp  string // the temporary file holding the new content
+}
+
+// Stage write the new content of the existing file 'path' to a temporary file in the same directory, synced to
+// disk, keeping the file mode of the original. The original file is untouched until Commit().
+func Stage(
+	path string,
+	dat []byte,
+	encr ...Encoder,
+) (stg *Staged, err error) {
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		err = fmt.Errorf("[REPLACE] %v", err)
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		err = fmt.Errorf("[REPLACE] %v", err)
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), fmt.Sprintf(".%v.*.tmp", filepath.Base(path)))
+	if err != nil {
+		err = fmt.Errorf("[REPLACE] %v", err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
 
+	if err = write(bufio.NewWriter(tmp), dat, encr); err != nil {
+		return
+	}
+	if err = tmp.Chmod(info.Mode().Perm()); err != nil {
+		err = fmt.Errorf("[REPLACE] %v", err)
+		return
+	}
+	if err = tmp.Sync(); err != nil {
+		err = fmt.Errorf("[REPLACE] %v", err)
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		err = fmt.Errorf("[REPLACE] %v", err)
+		return
+	}
+	return &Staged{path: path, tmp: tmp.Name()}, nil
+}
+
+// Commit rename the temporary file over the original file, which is kept as 'backup' if given
+func (s *Staged) Commit(backup string) (err error) {
+	defer func() {
+		if err != nil {
+			s.Discard()
+		}
+	}()
+	if backup != "" {
+		if err = os.Remove(backup); err != nil && !errors.Is(err, os.ErrNotExist) {
+			err = fmt.Errorf("[BACKUP] %v", err)
+			return
+		}
+		if err = os.Link(s.path, backup); err != nil {
+			// hard link not supported, copy instead
+			var org []byte
+			var info os.FileInfo
+			if info, err = os.Stat(s.path); err == nil {
+				if org, err = os.ReadFile(s.path); err == nil {
+					err = os.WriteFile(backup, org, info.Mode().Perm())
+				}
+			}
+			if err != nil {
+				err = fmt.Errorf("[BACKUP] %v", err)
+				return
+			}
+		}
+	}
+
+	if err = os.Rename(s.tmp, s.path); err != nil {
+		err = fmt.Errorf("[REPLACE] %v", err)
+		return
+	}
+	if d, e := os.Open(filepath.Dir(s.path)); e == nil { // persist the rename, not supported on all platforms
+		d.Sync()
+		d.Close()
+	}
+	return
+}
+
+// Discard remove the temporary file, the original file is kept as is
+func (s *Staged) Discard() {
+	os.Remove(s.tmp)
+}
+
+// write write 'dat' to 'wtr', encoded by the given encoders if any
+func write(wtr *bufio.Writer, dat []byte, encr []Encoder) (err error) {
 	enc := make([]Encoder, 0)
 	for _, n := range encr {
 		if n != nil {
@@ -239,17 +422,12 @@ func Write(
 		}
 	}
 	if len(enc) <= 0 {
-		if wtr == nil {
-			fmt.Printf("%s", dat)
-		} else {
-			fmt.Fprintf(wtr, "%s", dat)
-			wtr.Flush()
+		_, err = wtr.Write(dat)
+		if err != nil {
+			return
 		}
 	} else {
 		rdr := bytes.NewReader(dat)
-		if wtr == nil {
-			wtr = bufio.NewWriter(os.Stdout)
-		}
 		if len(enc) <= 1 {
 			err = enc[0].Encode(rdr, wtr)
 			if err != nil {
@@ -261,8 +439,8 @@ func Write(
 				return
 			}
 		}
-		err = wtr.Flush()
 	}
+	err = wtr.Flush()
 	return
 }
 
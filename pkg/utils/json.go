@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// jsonFrame an object or an array being traversed
+type jsonFrame struct {
+	obj     bool
+	key     string // the key of the current value of objects
+	idx     int    // the index of the current value of arrays
+	wantKey bool
+}
+
+// TraverseJson traverse a JSON document while preserving order and formatting, 'action' is called with the key path
+// and the raw literal of each scalar, e.g. '"a\tb"' with the quotes, which may be changed in place to another valid
+// JSON literal. Items of arrays are keyed by their indices.
+func TraverseJson(
+	input []byte,
+	action func([]string, *string) error,
+) (
+	output []byte,
+	err error,
+) {
+	dec := json.NewDecoder(bytes.NewReader(input))
+	dec.UseNumber()
+	var buf bytes.Buffer
+	stack := make([]*jsonFrame, 0)
+	pos, done := 0, false
+	for {
+		prev := int(dec.InputOffset())
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("[JSON] offset %v: %v", prev, err)
+		} else if done {
+			return nil, fmt.Errorf("[JSON] offset %v: multiple values", prev)
+		}
+
+		var top *jsonFrame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		switch t := tok.(type) {
+		case json.Delim:
+			if t == '{' || t == '[' {
+				stack = append(stack, &jsonFrame{obj: t == '{', wantKey: t == '{'})
+				continue
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if top != nil && top.obj && top.wantKey {
+				top.key, top.wantKey = t.(string), false
+				continue
+			}
+			path := make([]string, 0, len(stack))
+			for _, f := range stack {
+				if f.obj {
+					path = append(path, f.key)
+				} else {
+					path = append(path, strconv.Itoa(f.idx))
+				}
+			}
+			start, end := prev+len(input[prev:])-len(bytes.TrimLeft(input[prev:], " \t\r\n:,")), int(dec.InputOffset())
+			raw := string(input[start:end])
+			if err = action(path, &raw); err != nil {
+				return nil, fmt.Errorf("[%v]%v", strings.Join(path, "]["), err)
+			}
+			if raw != string(input[start:end]) {
+				buf.Write(input[pos:start])
+				buf.WriteString(raw)
+				pos = end
+			}
+		}
+
+		// the value of the parent is done
+		if len(stack) <= 0 {
+			done = true
+		} else if top = stack[len(stack)-1]; top.obj {
+			top.wantKey = true
+		} else {
+			top.idx++
+		}
+	}
+	if !done {
+		return nil, fmt.Errorf("[JSON] offset %v: unexpected end of input", len(input))
+	}
+	buf.Write(input[pos:])
+	return buf.Bytes(), nil
+}
+
+// JsonType the type of a raw JSON literal, i.e. 'str', 'int', 'float', 'bool' or 'null'
+func JsonType(raw string) string {
+	switch {
+	case raw == "":
+		return ""
+	case raw[0] == '"':
+		return "str"
+	case raw == "true" || raw == "false":
+		return "bool"
+	case raw == "null":
+		return "null"
+	case strings.ContainsAny(raw, ".eE"):
+		return "float"
+	}
+	return "int"
+}
+
+// WriteJson serialize a YAML document node, e.g. of a JSON document read by ReadNodes(), as JSON indented by tabs
+// while keeping the key order. Scalars are written by their tags, strings for the tags other than 'int', 'float',
+// 'bool' and 'null', or the values not valid in JSON.
+func WriteJson(doc *yaml.Node) (output []byte, err error) {
+	var buf, out bytes.Buffer
+	if err = writeJson(&buf, doc); err != nil {
+		return
+	}
+	if err = json.Indent(&out, buf.Bytes(), "", "\t"); err != nil {
+		return nil, fmt.Errorf("[JSON] %v", err)
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+func writeJson(buf *bytes.Buffer, node *yaml.Node) (err error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) <= 0 {
+			buf.WriteString("null")
+			return
+		}
+		return writeJson(buf, node.Content[0])
+	case yaml.AliasNode:
+		return writeJson(buf, node.Alias)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			jsonString(buf, node.Content[i].Value)
+			buf.WriteByte(':')
+			if err = writeJson(buf, node.Content[i+1]); err != nil {
+				return fmt.Errorf("[%v]%v", node.Content[i].Value, err)
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, n := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err = writeJson(buf, n); err != nil {
+				return fmt.Errorf("[%v]%v", i, err)
+			}
+		}
+		buf.WriteByte(']')
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!null":
+			buf.WriteString("null")
+		case "!!int", "!!float", "!!bool":
+			if json.Valid([]byte(node.Value)) {
+				buf.WriteString(node.Value)
+			} else {
+				jsonString(buf, node.Value)
+			}
+		default:
+			jsonString(buf, node.Value)
+		}
+	default:
+		return fmt.Errorf("[JSON] unsupported node kind %v", node.Kind)
+	}
+	return
+}
+
+// jsonString write a JSON string literal, without escaping the HTML characters
+func jsonString(buf *bytes.Buffer, str string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(str)
+	buf.Truncate(buf.Len() - 1) // the line break added by Encode()
+}
@@ -0,0 +1,301 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Lines a line based configuration file, i.e. '.env', INI or Java '.properties', parsed into entries while the other
+// lines, e.g. comments, blank lines and sections, are kept as is. The values are the raw text as written, without
+// the quotes but with the escapes, so writing them back keeps the quoting and escaping of the file.
+type Lines struct {
+	items []*lineItem
+	sep   string // separator of the added entries, e.g. '=' or ' = '
+	ini   bool   // entries are added before the first section
+}
+
+// lineItem an entry, or the text of the other lines if 'path' is nil; each item ends with its line break, if any
+type lineItem struct {
+	path  []string
+	head  string // the text before the value, including the key, the separator and the opening quote
+	value string
+	tail  string // the text after the value, including the closing quote, comments and the line break
+	sect  bool   // INI section header
+}
+
+func (i *lineItem) String() string {
+	return i.head + i.value + i.tail
+}
+
+var dOTENV = regexp.MustCompile(`^(\s*(?:export\s+)?)([A-Za-z_][A-Za-z0-9_.\-]*)(\s*=[ \t]*)`)
+
+// ReadDotenv parse a '.env' file, i.e. 'KEY=VALUE' lines with optional 'export ' prefixes, the values may be single
+// or double quoted spanning multiple lines, and unquoted values may be followed by ' # comments'.
+func ReadDotenv(input []byte) (*Lines, error) {
+	lns := &Lines{sep: "="}
+	lines := splitLines(string(input))
+	for i := 0; i < len(lines); i++ {
+		str := strings.TrimSpace(lines[i])
+		mth := dOTENV.FindStringSubmatch(lines[i])
+		if str == "" || strings.HasPrefix(str, "#") || mth == nil {
+			lns.items = append(lns.items, &lineItem{head: lines[i]})
+			continue
+		}
+		item, n, err := quotedValue(mth[0], lines[i:], "#")
+		if err != nil {
+			return nil, fmt.Errorf("[DOTENV] line %v: %v", i+1, err)
+		}
+		item.path = []string{mth[2]}
+		lns.items = append(lns.items, item)
+		i += n
+	}
+	return lns, nil
+}
+
+// ReadIni parse an INI file, i.e. '[section]' headers and 'key = value' or 'key: value' lines, comments start with
+// ';' or '#'. The key paths of the entries are the section and the key, or the key only before the first section.
+func ReadIni(input []byte) (*Lines, error) {
+	lns := &Lines{sep: " = ", ini: true}
+	sect := ""
+	for i, line := range splitLines(string(input)) {
+		str := strings.TrimSpace(line)
+		if str == "" || strings.HasPrefix(str, ";") || strings.HasPrefix(str, "#") {
+			lns.items = append(lns.items, &lineItem{head: line})
+			continue
+		}
+		if strings.HasPrefix(str, "[") {
+			end := strings.Index(str, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("[INI] line %v: ']' missing in section header", i+1)
+			}
+			sect = strings.TrimSpace(str[1:end])
+			lns.items = append(lns.items, &lineItem{head: line, sect: true})
+			continue
+		}
+		idx := strings.IndexAny(line, "=:")
+		if idx < 0 { // e.g. keys without values, kept as is
+			lns.items = append(lns.items, &lineItem{head: line})
+			continue
+		}
+		key := strings.TrimSpace(line[:idx])
+		hlen := idx + 1 + len(line[idx+1:]) - len(strings.TrimLeft(line[idx+1:], " \t"))
+		item, _, err := quotedValue(line[:hlen], []string{line}, ";#")
+		if err != nil {
+			return nil, fmt.Errorf("[INI] line %v: %v", i+1, err)
+		}
+		if sect == "" {
+			item.path = []string{key}
+		} else {
+			item.path = []string{sect, key}
+		}
+		lns.items = append(lns.items, item)
+	}
+	return lns, nil
+}
+
+// ReadProperties parse a Java '.properties' file, i.e. 'key=value', 'key: value' or 'key value' lines, comments start
+// with '#' or '!', and lines ending with an odd number of '\' are continued. The key paths are the unescaped keys.
+func ReadProperties(input []byte) (*Lines, error) {
+	lns := &Lines{sep: "="}
+	lines := splitLines(string(input))
+	for i := 0; i < len(lines); i++ {
+		str := strings.TrimLeft(lines[i], " \t\f")
+		if strings.TrimSpace(str) == "" || strings.HasPrefix(str, "#") || strings.HasPrefix(str, "!") {
+			lns.items = append(lns.items, &lineItem{head: lines[i]})
+			continue
+		}
+		// the logical line, with the continued lines
+		line := lines[i]
+		for ; i+1 < len(lines) && continued(line); i++ {
+			line += lines[i+1]
+		}
+		body, eol := cutEOL(line)
+		kbeg := len(body) - len(strings.TrimLeft(body, " \t\f"))
+		kend := kbeg
+		for kend < len(body) && !strings.ContainsRune("=: \t\f", rune(body[kend])) {
+			if body[kend] == '\\' {
+				kend++
+			}
+			kend++
+		}
+		kend = min(kend, len(body))
+		vbeg := kend + len(body[kend:]) - len(strings.TrimLeft(body[kend:], " \t\f"))
+		if vbeg < len(body) && (body[vbeg] == '=' || body[vbeg] == ':') {
+			vbeg++
+			vbeg += len(body[vbeg:]) - len(strings.TrimLeft(body[vbeg:], " \t\f"))
+		}
+		lns.items = append(lns.items, &lineItem{
+			path:  []string{unescapeProperty(body[kbeg:kend])},
+			head:  body[:vbeg],
+			value: body[vbeg:],
+			tail:  eol,
+		})
+	}
+	return lns, nil
+}
+
+// Traverse call 'action' with the key path and the value of each entry in order, the value may be changed in place.
+func (l *Lines) Traverse(action func([]string, *string) error) (err error) {
+	for _, item := range l.items {
+		if item.path == nil {
+			continue
+		}
+		if err = action(append([]string{}, item.path...), &item.value); err != nil {
+			return fmt.Errorf("[%v]%v", strings.Join(item.path, "]["), err)
+		}
+	}
+	return
+}
+
+// Put add an entry of the key 'name' at the end, or before the first section of INI files.
+func (l *Lines) Put(name, value string) {
+	pos := len(l.items)
+	if l.ini {
+		for i, item := range l.items {
+			if item.sect {
+				pos = i
+				break
+			}
+		}
+	}
+	item := &lineItem{path: []string{name}, head: name + l.sep, value: value, tail: "\n"}
+	if pos > 0 {
+		// keep the file not ending with a line break, which is restored by Take()
+		if prev := l.items[pos-1]; !strings.HasSuffix(prev.String(), "\n") {
+			prev.tail += "\n"
+			if pos == len(l.items) {
+				item.tail = ""
+			}
+		}
+	}
+	l.items = append(l.items[:pos], append([]*lineItem{item}, l.items[pos:]...)...)
+}
+
+// Take remove the entry of the key 'name' added by Put(), and return its value.
+func (l *Lines) Take(name string) (value string) {
+	for i, item := range l.items {
+		if len(item.path) == 1 && item.path[0] == name {
+			l.items = append(l.items[:i], l.items[i+1:]...)
+			// the line break added by Put() if the file does not end with one
+			if i == len(l.items) && i > 0 && !strings.HasSuffix(item.String(), "\n") {
+				if prev := l.items[i-1]; strings.HasSuffix(prev.tail, "\n") {
+					prev.tail = prev.tail[:len(prev.tail)-1]
+				} else if prev.tail == "" {
+					prev.head = strings.TrimSuffix(prev.head, "\n")
+				}
+			}
+			return item.value
+		}
+	}
+	return ""
+}
+
+// Bytes the content of the file.
+func (l *Lines) Bytes() []byte {
+	var buf strings.Builder
+	for _, item := range l.items {
+		buf.WriteString(item.String())
+	}
+	return []byte(buf.String())
+}
+
+// splitLines split the text into lines, each keeping its line break
+func splitLines(txt string) (lines []string) {
+	for txt != "" {
+		idx := strings.IndexByte(txt, '\n')
+		if idx < 0 {
+			return append(lines, txt)
+		}
+		lines, txt = append(lines, txt[:idx+1]), txt[idx+1:]
+	}
+	return
+}
+
+// cutEOL split the line break from a line
+func cutEOL(line string) (body, eol string) {
+	body = strings.TrimRight(line, "\r\n")
+	return body, line[len(body):]
+}
+
+// quotedValue parse the value following 'head' in lines[0], which may be quoted and continued in the following
+// lines, unquoted values end before any whitespace followed by one of the comment characters 'cmts'. 'n' is the
+// number of the following lines consumed.
+func quotedValue(head string, lines []string, cmts string) (item *lineItem, n int, err error) {
+	item = &lineItem{head: head}
+	rest := lines[0][len(head):]
+	if rest == "" || (rest[0] != '"' && rest[0] != '\'') {
+		body, eol := cutEOL(rest)
+		end := len(body)
+		for i := 1; i < len(body); i++ {
+			if strings.IndexByte(cmts, body[i]) >= 0 && (body[i-1] == ' ' || body[i-1] == '\t') {
+				end = i
+				break
+			}
+		}
+		item.value = strings.TrimRight(body[:end], " \t")
+		item.tail = body[len(item.value):] + eol
+		return
+	}
+
+	quote := rest[0]
+	item.head += rest[:1]
+	rest = rest[1:]
+	for {
+		for i := 0; i < len(rest); i++ {
+			if rest[i] == '\\' && quote == '"' {
+				i++
+			} else if rest[i] == quote {
+				item.value += rest[:i]
+				item.tail = rest[i:]
+				return
+			}
+		}
+		if n+1 >= len(lines) {
+			return nil, 0, fmt.Errorf("closing quote %c missing", quote)
+		}
+		item.value += rest
+		n++
+		rest = lines[n]
+	}
+}
+
+// continued 'true' if the line ends with an odd number of '\', i.e. continued in the next line
+func continued(line string) bool {
+	body, _ := cutEOL(line)
+	cnt := len(body) - len(strings.TrimRight(body, "\\"))
+	return cnt%2 == 1
+}
+
+// unescapeProperty unescape a key of '.properties' files
+func unescapeProperty(str string) string {
+	var buf strings.Builder
+	for i := 0; i < len(str); i++ {
+		if str[i] != '\\' || i+1 >= len(str) {
+			buf.WriteByte(str[i])
+			continue
+		}
+		i++
+		switch str[i] {
+		case 't':
+			buf.WriteByte('\t')
+		case 'n':
+			buf.WriteByte('\n')
+		case 'r':
+			buf.WriteByte('\r')
+		case 'f':
+			buf.WriteByte('\f')
+		case 'u':
+			if r, err := strconv.ParseUint(str[i+1:min(i+5, len(str))], 16, 32); err == nil && i+5 <= len(str) {
+				buf.WriteRune(rune(r))
+				i += 4
+			} else {
+				buf.WriteByte('u')
+			}
+		default:
+			buf.WriteByte(str[i])
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReadNodes parse all the documents of a YAML stream into nodes, keeping comments, anchors and styles.
+func ReadNodes(input []byte) (docs []*yaml.Node, err error) {
+	dec := yaml.NewDecoder(bytes.NewReader(input))
+	for {
+		doc := &yaml.Node{}
+		if err = dec.Decode(doc); errors.Is(err, io.EOF) {
+			return docs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// WriteNodes serialize the documents back into a YAML stream, separated by '---'.
+func WriteNodes(docs []*yaml.Node) (output []byte, err error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, doc := range docs {
+		untagMerge(doc)
+		if err = enc.Encode(doc); err != nil {
+			return
+		}
+	}
+	if err = enc.Close(); err != nil {
+		return
+	}
+	return buf.Bytes(), nil
+}
+
+// untagMerge clear the tags of the merge keys, otherwise yaml.v3 writes them as '!!merge <<'
+func untagMerge(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if k := node.Content[i]; k.Tag == "!!merge" && k.Value == "<<" {
+				k.Tag = ""
+			}
+		}
+	}
+	for _, n := range node.Content {
+		untagMerge(n)
+	}
+}
+
+// TraverseNode traverse a YAML document node in order, 'action' is called with the key path and the node of each
+// scalar value, which may be modified in place. Items of lists are keyed by their indices. Aliases are not
+// followed, since the anchored nodes are traversed already, and the keys of maps are left untouched.
+func TraverseNode(
+	node *yaml.Node,
+	action func([]string, *yaml.Node) error,
+) (err error) {
+	return traverseNode(nil, node, false, action)
+}
+
+// traverseNode traverse 'node', aliases and merge keys ('<<') are followed if 'follow' is 'true'.
+func traverseNode(
+	path []string,
+	node *yaml.Node,
+	follow bool,
+	action func([]string, *yaml.Node) error,
+) (err error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, n := range node.Content {
+			if err = traverseNode(path, n, follow, action); err != nil {
+				return
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if follow && key == "<<" && node.Content[i].ShortTag() == "!!merge" {
+				if err = traverseNode(path, node.Content[i+1], follow, action); err != nil {
+					return
+				}
+				continue
+			}
+			if err = traverseNode(append(path[:len(path):len(path)], key), node.Content[i+1], follow, action); err != nil {
+				return fmt.Errorf("[%v]%v", key, err)
+			}
+		}
+	case yaml.SequenceNode:
+		for i, n := range node.Content {
+			if err = traverseNode(append(path[:len(path):len(path)], fmt.Sprintf("%v", i)), n, follow, action); err != nil {
+				return fmt.Errorf("[%v]%v", i, err)
+			}
+		}
+	case yaml.AliasNode:
+		if follow {
+			err = traverseNode(path, node.Alias, follow, action)
+		}
+	case yaml.ScalarNode:
+		err = action(path, node)
+	}
+	return
+}
+
+// FlattenNode flatten a YAML document node into the key paths and the scalar values, while preserving order. Items
+// of lists are keyed by their indices, nulls are empty strings, and aliases and merge keys are resolved.
+func FlattenNode(
+	node *yaml.Node,
+) (
+	paths [][]string,
+	vals []string,
+) {
+	traverseNode(nil, node, true, func(path []string, n *yaml.Node) error {
+		paths = append(paths, path)
+		if n.ShortTag() == "!!null" {
+			vals = append(vals, "")
+		} else {
+			vals = append(vals, n.Value)
+		}
+		return nil
+	})
+	return
+}
@@ -2,7 +2,9 @@ package utils
 
 import (
 	"fmt"
+	"path"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -74,3 +76,143 @@ func BestMatch(
 	}
 	return
 }
+
+// Resolve resolve 'inp' to one of 'names' deterministically: an exact match of the names (ignoring case) first, then
+// an exact match of the 'aliases' (keyed in lower case), and finally the best match of the names if it is unique.
+// 'candidates' are the sorted names matched if 'inp' is ambiguous.
+func Resolve(inp string, names []string, aliases map[string]string) (name string, candidates []string) {
+	for _, n := range names {
+		if strings.EqualFold(inp, n) {
+			return n, nil
+		}
+	}
+	if n, ok := aliases[strings.ToLower(inp)]; ok {
+		return n, nil
+	}
+
+	indices, str, _ := BestMatch(inp, names, true)
+	if len(indices) == 1 {
+		return str, nil
+	}
+	candidates = make([]string, 0, len(indices))
+	for _, i := range indices {
+		candidates = append(candidates, names[i])
+	}
+	sort.Strings(candidates)
+	return
+}
+
+// MatchGlob match the slash-separated path 'name' against the glob pattern 'pttn'. Patterns without any slash
+// are matched against the last element of 'name' only, while '**' in a pattern matches zero or more path elements.
+func MatchGlob(pttn, name string) bool {
+	if !strings.Contains(pttn, "/") {
+		ok, _ := path.Match(pttn, path.Base(name))
+		return ok
+	}
+	return matchElements(strings.Split(pttn, "/"), strings.Split(name, "/"))
+}
+
+// ValidateGlob check if the given glob pattern is well-formed.
+func ValidateGlob(pttn string) (err error) {
+	for _, p := range strings.Split(pttn, "/") {
+		if _, err = path.Match(p, ""); err != nil {
+			return fmt.Errorf("[GLOB] invalid pattern '%v'", pttn)
+		}
+	}
+	return
+}
+
+// ParsePath split a path expression (JSONPath / yq style, e.g. '$.db.password', '.hosts[0]', '**.secret*') into its
+// elements. The leading '$' and '.' are optional, '..' is the same as '.**.', and list indices are elements of
+// their own.
+func ParsePath(expr string) (elms []string, err error) {
+	str := strings.TrimPrefix(expr, "$")
+	str = strings.ReplaceAll(str, "..", ".**.")
+	str = strings.ReplaceAll(str, "[", ".[")
+	str = strings.TrimPrefix(str, ".")
+	if str == "" {
+		return nil, fmt.Errorf("[PATH] empty path expression '%v'", expr)
+	}
+
+	elms = strings.Split(str, ".")
+	for i, e := range elms {
+		if strings.HasPrefix(e, "[") {
+			if !strings.HasSuffix(e, "]") || len(e) <= 2 {
+				return nil, fmt.Errorf("[PATH] invalid index '%v' in '%v'", e, expr)
+			}
+			e = e[1 : len(e)-1]
+			elms[i] = e
+		}
+		if e == "" {
+			return nil, fmt.Errorf("[PATH] empty element in '%v'", expr)
+		} else if _, err = path.Match(e, ""); err != nil {
+			return nil, fmt.Errorf("[PATH] invalid pattern '%v' in '%v'", e, expr)
+		}
+	}
+	return
+}
+
+// MatchPath match the key path 'keys' against the elements of a path expression parsed by ParsePath(). Each
+// element is a glob pattern of a key or a list index, '**' matches zero or more keys, and an expression matching
+// a map or a list matches all the values in it.
+func MatchPath(elms, keys []string) bool {
+	return matchElements(append(elms[:len(elms):len(elms)], "**"), keys)
+}
+
+func matchElements(pttns, names []string) bool {
+	for len(pttns) > 0 {
+		if pttns[0] == "**" {
+			for i := 0; i <= len(names); i++ {
+				if matchElements(pttns[1:], names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(names) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pttns[0], names[0]); !ok {
+			return false
+		}
+		pttns, names = pttns[1:], names[1:]
+	}
+	return len(names) == 0
+}
+
+// CheckNames check if the name and aliases to be registered collide with the existing names and aliases (ignoring case).
+func CheckNames(name string, aliases []string, names []string, existing map[string]string) error {
+	used := make(map[string]bool)
+	for _, n := range names {
+		used[strings.ToLower(n)] = true
+	}
+	for a := range existing {
+		used[a] = true
+	}
+
+	if used[strings.ToLower(name)] {
+		return fmt.Errorf(" '%v' already registered", name)
+	}
+	used[strings.ToLower(name)] = true
+	for _, a := range aliases {
+		if a == "" {
+			return fmt.Errorf(" empty alias of '%v'", name)
+		} else if used[strings.ToLower(a)] {
+			return fmt.Errorf(" alias '%v' of '%v' already registered", a, name)
+		}
+		used[strings.ToLower(a)] = true
+	}
+	return nil
+}
+
+// AliasesOf list the aliases of 'name' in 'aliases', sorted.
+func AliasesOf(name string, aliases map[string]string) (list []string) {
+	list = make([]string, 0)
+	for a, n := range aliases {
+		if n == name {
+			list = append(list, a)
+		}
+	}
+	sort.Strings(list)
+	return
+}
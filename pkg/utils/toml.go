@@ -0,0 +1,422 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Toml a TOML file parsed into the leaf values, i.e. strings, numbers, booleans and date-times in tables, arrays
+// and inline tables, while the rest of the file, e.g. comments, whitespace and key order, is kept as is. The values
+// are the raw TOML literals, e.g. '"a\tb"' with the quotes, so writing them back keeps the quoting and escaping.
+type Toml struct {
+	text   string
+	leaves []*tomlLeaf
+	first  int // offset of the first table header, where the root keys are added
+}
+
+type tomlLeaf struct {
+	path       []string
+	start, end int // the span of the literal in 'text'
+	raw        string
+	line       [2]int // the span of the line of a root key, for removing it
+}
+
+var tOMLBARE = regexp.MustCompile(`^[A-Za-z0-9_\-]+`)
+var tOMLDATE = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+var tOMLTIME = regexp.MustCompile(`^ \d{2}:`)
+
+// ReadToml parse a TOML file. The key paths of the values are the keys of the tables and the dotted keys, the items
+// of arrays and arrays of tables are keyed by their indices, e.g. 'products.1.name' of the second '[[products]]'.
+func ReadToml(input []byte) (*Toml, error) {
+	t := &Toml{text: string(input)}
+	if err := t.parse(); err != nil {
+		return nil, fmt.Errorf("[TOML]%v", err)
+	}
+	return t, nil
+}
+
+// TomlType the type of a raw TOML literal, i.e. 'str', 'int', 'float', 'bool' or 'timestamp'
+func TomlType(raw string) string {
+	switch {
+	case raw == "":
+		return ""
+	case raw[0] == '"' || raw[0] == '\'':
+		return "str"
+	case raw == "true" || raw == "false":
+		return "bool"
+	case len(raw) >= 10 && tOMLDATE.MatchString(raw[:10]), len(raw) >= 3 && raw[2] == ':':
+		return "timestamp"
+	case strings.HasPrefix(raw, "0x"), strings.HasPrefix(raw, "0o"), strings.HasPrefix(raw, "0b"):
+		return "int"
+	case strings.ContainsAny(raw, ".eE"), strings.HasSuffix(raw, "inf"), strings.HasSuffix(raw, "nan"):
+		return "float"
+	}
+	return "int"
+}
+
+// Traverse call 'action' with the key path and the raw literal of each leaf value in order, the literal may be
+// changed in place, and must be a valid TOML literal.
+func (t *Toml) Traverse(action func([]string, *string) error) (err error) {
+	for _, leaf := range t.leaves {
+		if err = action(append([]string{}, leaf.path...), &leaf.raw); err != nil {
+			return fmt.Errorf("[%v]%v", strings.Join(leaf.path, "]["), err)
+		}
+	}
+	return
+}
+
+// Put add a root key 'name' of the string 'value' before the first table, 'value' is not escaped.
+func (t *Toml) Put(name, value string) {
+	t.flush()
+	txt, pos := t.text, t.first
+	line := fmt.Sprintf("%v = \"%v\"\n", name, value)
+	if pos > 0 && txt[pos-1] != '\n' {
+		// keep the file not ending with a line break, which is restored by Take()
+		txt += "\n"
+		pos++
+		if pos >= len(txt) {
+			line = strings.TrimSuffix(line, "\n")
+		}
+	}
+	t.text = txt[:pos] + line + txt[pos:]
+	t.parse()
+}
+
+// Take remove the root key 'name' added by Put(), and return its value.
+func (t *Toml) Take(name string) (value string) {
+	t.flush()
+	for _, leaf := range t.leaves {
+		if len(leaf.path) != 1 || leaf.path[0] != name || leaf.line[1] <= 0 {
+			continue
+		}
+		value = strings.Trim(leaf.raw, "\"")
+		txt := t.text
+		beg, end := leaf.line[0], leaf.line[1]
+		if end >= len(txt) && beg > 0 && !strings.HasSuffix(txt, "\n") {
+			beg--
+		}
+		t.text = txt[:beg] + txt[end:]
+		t.parse()
+		return
+	}
+	return ""
+}
+
+// Bytes the content of the file.
+func (t *Toml) Bytes() []byte {
+	var buf strings.Builder
+	pos := 0
+	for _, leaf := range t.leaves {
+		buf.WriteString(t.text[pos:leaf.start])
+		buf.WriteString(leaf.raw)
+		pos = leaf.end
+	}
+	buf.WriteString(t.text[pos:])
+	return []byte(buf.String())
+}
+
+// flush apply the changed values to the text
+func (t *Toml) flush() {
+	t.text = string(t.Bytes())
+	t.parse()
+}
+
+// tomlParser the state of parsing
+type tomlParser struct {
+	*Toml
+	pos    int
+	arrays map[string]int // the current indices of the arrays of tables
+}
+
+func (t *Toml) parse() (err error) {
+	t.leaves, t.first = nil, -1
+	p := &tomlParser{Toml: t, arrays: make(map[string]int)}
+	table := []string{}
+	for p.skip(false); p.pos < len(t.text); p.skip(false) {
+		beg, root := p.pos, (*tomlLeaf)(nil)
+		if t.text[p.pos] == '[' {
+			if t.first < 0 {
+				t.first = strings.LastIndex(t.text[:beg], "\n") + 1
+			}
+			if table, err = p.header(); err != nil {
+				return
+			}
+		} else {
+			keys, err := p.keys()
+			if err != nil {
+				return err
+			}
+			if p.skipSpace(); !p.peek("=") {
+				return p.errorf("'=' expected")
+			}
+			p.pos++
+			p.skipSpace()
+			cnt := len(t.leaves)
+			if err = p.value(append(append([]string{}, table...), keys...)); err != nil {
+				return err
+			}
+			if t.first < 0 && len(keys) == 1 && len(t.leaves) == cnt+1 {
+				root = t.leaves[cnt]
+			}
+		}
+		if p.skipSpace(); p.pos < len(t.text) && t.text[p.pos] == '#' {
+			p.skipComment()
+		}
+		if p.pos < len(t.text) && !p.newline() {
+			return p.errorf("line break expected")
+		}
+		if root != nil {
+			root.line = [2]int{strings.LastIndex(t.text[:beg], "\n") + 1, p.pos}
+		}
+	}
+	if t.first < 0 {
+		t.first = len(t.text)
+	}
+	return
+}
+
+// header parse a table header '[a.b]' or '[[a.b]]', and return the key path of the table
+func (p *tomlParser) header() (path []string, err error) {
+	isArray := p.peek("[[")
+	if isArray {
+		p.pos += 2
+	} else {
+		p.pos++
+	}
+	p.skipSpace()
+	keys, err := p.keys()
+	if err != nil {
+		return
+	}
+	p.skipSpace()
+	if isArray && !p.peek("]]") || !isArray && !p.peek("]") {
+		return nil, p.errorf("']' expected")
+	}
+	if p.pos++; isArray {
+		p.pos++
+	}
+
+	// the keys of the arrays of tables are followed by their current indices
+	for i, k := range keys {
+		path = append(path, k)
+		id := strings.Join(keys[:i+1], "\x00")
+		if i == len(keys)-1 && isArray {
+			idx, ok := p.arrays[id]
+			if ok {
+				idx++
+			}
+			p.arrays[id] = idx
+			for a := range p.arrays {
+				if strings.HasPrefix(a, id+"\x00") {
+					delete(p.arrays, a)
+				}
+			}
+			path = append(path, strconv.Itoa(idx))
+		} else if idx, ok := p.arrays[id]; ok {
+			path = append(path, strconv.Itoa(idx))
+		}
+	}
+	return
+}
+
+// keys parse a dotted key
+func (p *tomlParser) keys() (keys []string, err error) {
+	for {
+		p.skipSpace()
+		var key string
+		switch {
+		case p.peek("\""):
+			beg := p.pos
+			if err = p.basicString(); err != nil {
+				return
+			}
+			if key, err = strconv.Unquote(p.text[beg:p.pos]); err != nil {
+				key = p.text[beg+1 : p.pos-1]
+			}
+		case p.peek("'"):
+			beg := p.pos
+			if err = p.literalString(); err != nil {
+				return
+			}
+			key = p.text[beg+1 : p.pos-1]
+		default:
+			if key = tOMLBARE.FindString(p.text[p.pos:]); key == "" {
+				return nil, p.errorf("key expected")
+			}
+			p.pos += len(key)
+		}
+		keys = append(keys, key)
+		if p.skipSpace(); !p.peek(".") {
+			return
+		}
+		p.pos++
+	}
+}
+
+// value parse a value, the leaves are added with their key paths
+func (p *tomlParser) value(path []string) (err error) {
+	if p.pos >= len(p.text) {
+		return p.errorf("value expected")
+	}
+	beg := p.pos
+	switch {
+	case p.peek("["):
+		p.pos++
+		for idx := 0; ; idx++ {
+			p.skip(true)
+			if p.peek("]") {
+				break
+			}
+			if err = p.value(append(path[:len(path):len(path)], strconv.Itoa(idx))); err != nil {
+				return
+			}
+			if p.skip(true); p.peek(",") {
+				p.pos++
+			} else if !p.peek("]") {
+				return p.errorf("',' or ']' expected")
+			}
+		}
+		p.pos++
+		return
+	case p.peek("{"):
+		p.pos++
+		for first := true; ; first = false {
+			p.skipSpace()
+			if p.peek("}") && first {
+				break
+			}
+			keys, err := p.keys()
+			if err != nil {
+				return err
+			}
+			if p.skipSpace(); !p.peek("=") {
+				return p.errorf("'=' expected")
+			}
+			p.pos++
+			p.skipSpace()
+			if err = p.value(append(path[:len(path):len(path)], keys...)); err != nil {
+				return err
+			}
+			if p.skipSpace(); p.peek(",") {
+				p.pos++
+			} else if p.peek("}") {
+				break
+			} else {
+				return p.errorf("',' or '}' expected")
+			}
+		}
+		p.pos++
+		return
+	case p.peek("\"\"\""), p.peek("'''"):
+		err = p.multilineString()
+	case p.peek("\""):
+		err = p.basicString()
+	case p.peek("'"):
+		err = p.literalString()
+	default:
+		for p.pos < len(p.text) && !strings.ContainsRune(" \t\r\n,]}#", rune(p.text[p.pos])) {
+			p.pos++
+		}
+		// date-times may be delimited by a space, e.g. '1979-05-27 07:32:00Z'
+		if tOMLDATE.MatchString(p.text[beg:p.pos]) && tOMLTIME.MatchString(p.text[p.pos:]) {
+			for p.pos++; p.pos < len(p.text) && !strings.ContainsRune(" \t\r\n,]}#", rune(p.text[p.pos])); p.pos++ {
+			}
+		}
+		if p.pos == beg {
+			return p.errorf("value expected")
+		}
+	}
+	if err == nil {
+		p.leaves = append(p.leaves, &tomlLeaf{path: path, start: beg, end: p.pos, raw: p.text[beg:p.pos]})
+	}
+	return
+}
+
+func (p *tomlParser) basicString() error {
+	for i := p.pos + 1; i < len(p.text) && p.text[i] != '\n'; i++ {
+		if p.text[i] == '\\' {
+			i++
+		} else if p.text[i] == '"' {
+			p.pos = i + 1
+			return nil
+		}
+	}
+	return p.errorf("closing '\"' missing")
+}
+
+func (p *tomlParser) literalString() error {
+	end := strings.IndexAny(p.text[p.pos+1:], "'\n")
+	if end < 0 || p.text[p.pos+1+end] != '\'' {
+		return p.errorf("closing \"'\" missing")
+	}
+	p.pos += end + 2
+	return nil
+}
+
+func (p *tomlParser) multilineString() error {
+	quote := p.text[p.pos : p.pos+3]
+	for i := p.pos + 3; i+3 <= len(p.text); i++ {
+		if p.text[i] == '\\' && quote == "\"\"\"" {
+			i++
+		} else if p.text[i:i+3] == quote {
+			// up to 2 quotes adjacent to the delimiter belong to the string
+			end := i + 3
+			for n := 0; n < 2 && end < len(p.text) && p.text[end] == quote[0]; n++ {
+				end++
+			}
+			p.pos = end
+			return nil
+		}
+	}
+	return p.errorf("closing %v missing", quote)
+}
+
+func (p *tomlParser) peek(str string) bool {
+	return strings.HasPrefix(p.text[p.pos:], str)
+}
+
+func (p *tomlParser) skipSpace() {
+	for p.pos < len(p.text) && (p.text[p.pos] == ' ' || p.text[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *tomlParser) skipComment() {
+	if end := strings.IndexByte(p.text[p.pos:], '\n'); end >= 0 {
+		p.pos += end
+	} else {
+		p.pos = len(p.text)
+	}
+	if p.pos > 0 && p.text[p.pos-1] == '\r' {
+		p.pos--
+	}
+}
+
+func (p *tomlParser) newline() bool {
+	if p.peek("\r\n") {
+		p.pos += 2
+	} else if p.peek("\n") {
+		p.pos++
+	} else {
+		return false
+	}
+	return true
+}
+
+// skip skip the whitespace, comments and line breaks, until a statement if 'inner' is false, or a value of arrays
+func (p *tomlParser) skip(inner bool) {
+	for {
+		if p.skipSpace(); p.peek("#") {
+			p.skipComment()
+		}
+		if !p.newline() {
+			return
+		}
+	}
+}
+
+func (p *tomlParser) errorf(frmt string, args ...interface{}) error {
+	return fmt.Errorf(" line %v: %v", strings.Count(p.text[:min(p.pos, len(p.text))], "\n")+1, fmt.Sprintf(frmt, args...))
+}
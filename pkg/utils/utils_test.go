@@ -7,11 +7,15 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/crypto/scrypt"
+	"gopkg.in/yaml.v3"
 )
 
 func TestRandom(t *testing.T) {
@@ -89,6 +93,41 @@ func TestWrite(t *testing.T) {
 	fmt.Println("\nTestWrite() test okay")
 }
 
+func TestReplace(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets.yaml")
+	if err := os.WriteFile(path, []byte("original"), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Replace(path, path+".bak", []byte("replaced"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dat, _ := os.ReadFile(path)
+	bak, _ := os.ReadFile(path + ".bak")
+	info, _ := os.Stat(path)
+	if string(dat) != "replaced" || string(bak) != "original" || info.Mode().Perm() != 0640 {
+		t.Fatalf("TestReplace() unexpected result '%s', backup '%s', mode %v", dat, bak, info.Mode().Perm())
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Fatalf("TestReplace() expecting 2 files, found %v", len(entries))
+	}
+
+	stg, err := Stage(path, []byte("discarded"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stg.Discard()
+	dat, _ = os.ReadFile(path)
+	if entries, _ = os.ReadDir(dir); string(dat) != "replaced" || len(entries) != 2 {
+		t.Fatalf("TestReplace() unexpected result '%s' of %v files after discarding", dat, len(entries))
+	}
+	fmt.Println("TestReplace() test okay")
+}
+
 func TestPipeUsage(t *testing.T) {
 	defer func() {
 		if r := recover(); r == nil {
@@ -142,3 +181,295 @@ func TestVarArgs(t *testing.T) {
 	}
 	fmt.Println("TestVarArgs() test okay")
 }
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pttn, name string
+		expected   bool
+	}{
+		{"*.yaml", "secrets.yaml", true},
+		{"*.yaml", "prod/db/secrets.yaml", true},
+		{"*.yaml", "prod/db/secrets.json", false},
+		{"prod/*.yaml", "prod/secrets.yaml", true},
+		{"prod/*.yaml", "prod/db/secrets.yaml", false},
+		{"prod/**/*.yaml", "prod/secrets.yaml", true},
+		{"prod/**/*.yaml", "prod/db/x/secrets.yaml", true},
+		{"prod/**", "prod/db/secrets.yaml", true},
+		{"**/db/*", "prod/db/secrets.yaml", true},
+		{"**/db/*", "prod/dbx/secrets.yaml", false},
+	}
+	for i, tst := range tests {
+		if rst := MatchGlob(tst.pttn, tst.name); rst != tst.expected {
+			t.Fatalf("TestMatchGlob() %v - '%v' matching '%v' expected %v, got %v", i, tst.pttn, tst.name, tst.expected, rst)
+		}
+	}
+	if err := ValidateGlob("prod/[a-/*.yaml"); err == nil {
+		t.Fatal("TestMatchGlob() expecting invalid pattern error")
+	}
+	fmt.Println("TestMatchGlob() test okay")
+}
+
+func TestSentinel(t *testing.T) {
+	inp := "line 1\n.\r\nline 2\n"
+	rdr := bufio.NewReaderSize(&sentinelReader{rdr: bufio.NewReaderSize(bytes.NewReader([]byte(inp)), 16)}, 16)
+	dat, err := io.ReadAll(rdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(dat) != "line 1\n" {
+		t.Fatalf("TestSentinel() expecting 'line 1', got '%s'", dat)
+	}
+
+	inp = "0123456789abcdef.\n.\n"
+	rdr = bufio.NewReaderSize(&sentinelReader{rdr: bufio.NewReaderSize(bytes.NewReader([]byte(inp)), 16)}, 16)
+	dat, _ = io.ReadAll(rdr)
+	if string(dat) != "0123456789abcdef.\n" {
+		t.Fatalf("TestSentinel() long line expecting '0123456789abcdef.', got '%s'", dat)
+	}
+
+	var out []byte
+	err = BufferedRead(bufio.NewReaderSize(bytes.NewReader([]byte(".\n")), 16), 16, func(cnt int, buf []byte) error {
+		out = append(out, buf...)
+		return nil
+	})
+	if err != nil || string(out) != ".\n" {
+		t.Fatalf("TestSentinel() sentinel should not apply to non-interactive input, got '%s'", out)
+	}
+	fmt.Println("TestSentinel() test okay")
+}
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		expr  string
+		keys  string
+		match bool
+	}{
+		{"$.db.password", "db.password", true},
+		{"$.db.password", "db.user", false},
+		{".db", "db.password", true},
+		{"db", "dbs.password", false},
+		{"**.secret*", "api.secret_key", true},
+		{"**.secret*", "secret", true},
+		{"$..token", "a.b.token", true},
+		{".hosts[1]", "hosts.1", true},
+		{".hosts[1]", "hosts.0", false},
+		{"hosts[*].name", "hosts.3.name", true},
+	}
+	for i, tt := range tests {
+		elms, err := ParsePath(tt.expr)
+		if err != nil {
+			t.Fatalf("TestPath() %v - %v", i, err)
+		}
+		if rst := MatchPath(elms, strings.Split(tt.keys, ".")); rst != tt.match {
+			t.Fatalf("TestPath() %v - '%v' matching '%v' expecting %v, got %v", i, tt.expr, tt.keys, tt.match, rst)
+		}
+	}
+	for _, expr := range []string{"", "$", "a..", "a[", "a[]", "a.[b"} {
+		if _, err := ParsePath(expr); err == nil {
+			t.Fatalf("TestPath() expecting error parsing '%v'", expr)
+		}
+	}
+	fmt.Println("TestPath() test okay")
+}
+
+func TestNode(t *testing.T) {
+	inp := "# head\nbase: &b\n  host: localhost # host\ndb:\n  <<: *b\n  port: 5432\n---\n- a\n- 'b'\n"
+	docs, err := ReadNodes([]byte(inp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("TestNode() expecting 2 documents, got %v", len(docs))
+	}
+
+	paths, vals := FlattenNode(docs[0])
+	expected := []string{"base.host=localhost", "db.host=localhost", "db.port=5432"}
+	if len(paths) != len(expected) {
+		t.Fatalf("TestNode() expecting %v values, got %v", len(expected), len(paths))
+	}
+	for i, p := range paths {
+		if rst := fmt.Sprintf("%v=%v", strings.Join(p, "."), vals[i]); rst != expected[i] {
+			t.Fatalf("TestNode() %v - expecting '%v', got '%v'", i, expected[i], rst)
+		}
+	}
+
+	for _, doc := range docs {
+		err = TraverseNode(doc, func(path []string, n *yaml.Node) error {
+			n.Value = strings.ToUpper(n.Value)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err := WriteNodes(docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expout := "# head\nbase: &b\n  host: LOCALHOST # host\ndb:\n  <<: *b\n  port: 5432\n---\n- A\n- 'B'\n"
+	if string(out) != expout {
+		t.Fatalf("TestNode() expecting\n%v\ngot\n%v", expout, string(out))
+	}
+	fmt.Println("TestNode() test okay")
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		read     func([]byte) (*Lines, error)
+		inp      string
+		expected []string
+	}{
+		{ReadDotenv, "# head\nexport A=1 # one\nB=\"x\\\"y\"\nC='multi\nline'\nD=\n", []string{"A=1", "B=x\\\"y", "C=multi\nline", "D="}},
+		{ReadIni, "; head\nname = app\n[db]\nuser=admin\npass: \"p;w\" ; comment\nflag\n", []string{"name=app", "db.user=admin", "db.pass=p;w"}},
+		{ReadProperties, "# head\na.b=1\nc : two\\\n  lines\nd\\ e\\=f = 3\ng", []string{"a.b=1", "c=two\\\n  lines", "d e=f=3", "g="}},
+	}
+	for i, tt := range tests {
+		lns, err := tt.read([]byte(tt.inp))
+		if err != nil {
+			t.Fatalf("TestLines() %v - %v", i, err)
+		}
+		rsts, vals := make([]string, 0), make([]string, 0)
+		lns.Traverse(func(path []string, val *string) error {
+			rsts, vals = append(rsts, fmt.Sprintf("%v=%v", strings.Join(path, "."), *val)), append(vals, *val)
+			*val = "X"
+			return nil
+		})
+		if fmt.Sprintf("%q", rsts) != fmt.Sprintf("%q", tt.expected) {
+			t.Fatalf("TestLines() %v - expecting %q, got %q", i, tt.expected, rsts)
+		}
+		lns.Traverse(func(path []string, val *string) error {
+			*val, vals = vals[0], vals[1:]
+			return nil
+		})
+
+		lns.Put("salt", "SALT")
+		lns.Put("mac", "MAC")
+		if !strings.Contains(string(lns.Bytes()), "mac") {
+			t.Fatalf("TestLines() %v - entry not added:\n%s", i, lns.Bytes())
+		}
+		if lns.Take("salt") != "SALT" || lns.Take("mac") != "MAC" || lns.Take("mac") != "" {
+			t.Fatalf("TestLines() %v - entries not taken", i)
+		}
+		if out := string(lns.Bytes()); out != tt.inp {
+			t.Fatalf("TestLines() %v - expecting\n%v\ngot\n%v", i, tt.inp, out)
+		}
+	}
+	if _, err := ReadDotenv([]byte("A=\"open\n")); err == nil {
+		t.Fatalf("TestLines() expecting error of missing quote")
+	}
+	fmt.Println("TestLines() test okay")
+}
+
+func TestToml(t *testing.T) {
+	inp := "# head\ntitle = \"TOML\" # comment\n\"a b\".c = 'lit'\n\n[owner]\ndob = 1979-05-27 07:32:00-08:00\n" +
+		"[db]\nports = [ 8000,\n  8001 ] # ports\nconn = { max = 5000, on = true }\npass = \"\"\"x\n\"y\"\"\"\"\n\n" +
+		"[[products]]\nname = \"Hammer\"\n[[products]]\nname = 'Nail'\n[products.size]\npi = 3.14"
+	expected := []string{
+		"title=\"TOML\":str", "a b.c='lit':str", "owner.dob=1979-05-27 07:32:00-08:00:timestamp",
+		"db.ports.0=8000:int", "db.ports.1=8001:int", "db.conn.max=5000:int", "db.conn.on=true:bool",
+		"db.pass=\"\"\"x\n\"y\"\"\"\":str", "products.0.name=\"Hammer\":str", "products.1.name='Nail':str",
+		"products.1.size.pi=3.14:float",
+	}
+	tml, err := ReadToml([]byte(inp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsts, vals := make([]string, 0), make([]string, 0)
+	tml.Traverse(func(path []string, val *string) error {
+		rsts = append(rsts, fmt.Sprintf("%v=%v:%v", strings.Join(path, "."), *val, TomlType(*val)))
+		*val, vals = "\"X\"", append(vals, *val)
+		return nil
+	})
+	if fmt.Sprintf("%q", rsts) != fmt.Sprintf("%q", expected) {
+		t.Fatalf("TestToml() expecting %q, got %q", expected, rsts)
+	}
+	if _, err = ReadToml(tml.Bytes()); err != nil {
+		t.Fatalf("TestToml() invalid output - %v\n%s", err, tml.Bytes())
+	}
+
+	tml.Put("salt", "SALT")
+	tml.Put("mac", "MAC")
+	if out := string(tml.Bytes()); !strings.Contains(out, "mac = \"MAC\"\n[owner]") {
+		t.Fatalf("TestToml() keys not added before the first table:\n%v", out)
+	}
+	tml.Traverse(func(path []string, val *string) error {
+		if path[0] != "salt" && path[0] != "mac" {
+			*val, vals = vals[0], vals[1:]
+		}
+		return nil
+	})
+	if tml.Take("salt") != "SALT" || tml.Take("mac") != "MAC" || tml.Take("mac") != "" {
+		t.Fatalf("TestToml() keys not taken")
+	}
+	if out := string(tml.Bytes()); out != inp {
+		t.Fatalf("TestToml() expecting\n%v\ngot\n%v", inp, out)
+	}
+
+	tml, _ = ReadToml([]byte("a = 1"))
+	tml.Put("mac", "MAC")
+	if out := string(tml.Bytes()); out != "a = 1\nmac = \"MAC\"" || tml.Take("mac") != "MAC" || string(tml.Bytes()) != "a = 1" {
+		t.Fatalf("TestToml() missing final line break not kept: %q", out)
+	}
+	for _, bad := range []string{"a = \"open\n", "a = [1, 2\n", "[tbl\n", "a = 1 b = 2\n"} {
+		if _, err = ReadToml([]byte(bad)); err == nil {
+			t.Fatalf("TestToml() expecting error of '%v'", bad)
+		}
+	}
+	fmt.Println("TestToml() test okay")
+}
+
+func TestTraverseJson(t *testing.T) {
+	inp := `{"user": {"email": "a\"b@x.io", "age": 42}, "tags": ["x", 1.5e3, [true, null]], "ok":false }`
+	expected := []string{
+		`user.email="a\"b@x.io":str`, "user.age=42:int", `tags.0="x":str`, "tags.1=1.5e3:float",
+		"tags.2.0=true:bool", "tags.2.1=null:null", "ok=false:bool",
+	}
+	rsts := make([]string, 0)
+	out, err := TraverseJson([]byte(inp), func(path []string, val *string) error {
+		rsts = append(rsts, fmt.Sprintf("%v=%v:%v", strings.Join(path, "."), *val, JsonType(*val)))
+		if path[0] == "user" {
+			*val = `"X"`
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%q", rsts) != fmt.Sprintf("%q", expected) {
+		t.Fatalf("TestTraverseJson() expecting %q, got %q", expected, rsts)
+	}
+	if exp := `{"user": {"email": "X", "age": "X"}, "tags": ["x", 1.5e3, [true, null]], "ok":false }`; string(out) != exp {
+		t.Fatalf("TestTraverseJson() expecting '%v', got '%s'", exp, out)
+	}
+
+	if out, err = TraverseJson([]byte(" 12 "), func(path []string, val *string) error {
+		*val = "13"
+		return nil
+	}); err != nil || string(out) != " 13 " {
+		t.Fatalf("TestTraverseJson() expecting ' 13 ', got '%s' %v", out, err)
+	}
+	for _, bad := range []string{`{"a": 1`, `{"a": }`, `{} {}`, ``} {
+		if _, err = TraverseJson([]byte(bad), func([]string, *string) error { return nil }); err == nil {
+			t.Fatalf("TestTraverseJson() expecting error of '%v'", bad)
+		}
+	}
+	fmt.Println("TestTraverseJson() test okay")
+}
+
+func TestWriteJson(t *testing.T) {
+	inp := `{"b": {"s": "a<b>&\"c", "n": 1.5e3, "q": "12"}, "a": [true, null, {}], "t": 0x1F}`
+	docs, err := ReadNodes([]byte(inp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := WriteJson(docs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "{\n\t\"b\": {\n\t\t\"s\": \"a<b>&\\\"c\",\n\t\t\"n\": 1.5e3,\n\t\t\"q\": \"12\"\n\t},\n" +
+		"\t\"a\": [\n\t\ttrue,\n\t\tnull,\n\t\t{}\n\t],\n\t\"t\": \"0x1F\"\n}\n"
+	if string(out) != exp {
+		t.Fatalf("TestWriteJson() expecting '%v', got '%s'", exp, out)
+	}
+	fmt.Println("TestWriteJson() test okay")
+}